@@ -0,0 +1,164 @@
+// Command pushmock stands in for FCM and APNs during development and tests. It
+// accepts every message except those to tokens starting with "invalid", which
+// it rejects the way the providers reject stale tokens. GET /messages lists the
+// accepted messages.
+//
+// Point the server at it with:
+//
+//	FCM_ENDPOINT=http://localhost:8091 FCM_PROJECT_ID=warden FCM_ACCESS_TOKEN=mock
+//	APNS_ENDPOINT=http://localhost:8091 APNS_TOPIC=ru.warden APNS_AUTH_TOKEN=mock
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	received struct {
+		Provider   string          `json:"provider"`
+		Token      string          `json:"token"`
+		Payload    json.RawMessage `json:"payload"`
+		ReceivedAt time.Time       `json:"received_at"`
+	}
+
+	mock struct {
+		mu       sync.Mutex
+		messages []received
+	}
+)
+
+func main() {
+
+	addr := flag.String("addr", "localhost:8091", "listen address")
+	flag.Parse()
+
+	server := &http.Server{Addr: *addr, Handler: newMux(&mock{}), Protocols: protocols(), ReadHeaderTimeout: 3 * time.Second}
+
+	log.Printf("push mock listening on %s", *addr)
+	log.Fatal(server.ListenAndServe())
+}
+
+func newMux(m *mock) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", m.token)
+	mux.HandleFunc("POST /v1/projects/{project}/messages:send", m.fcm)
+	mux.HandleFunc("POST /3/device/{token}", m.apns)
+	mux.HandleFunc("GET /messages", m.list)
+	return mux
+}
+
+// protocols are the ones the mock serves: APNs clients speak HTTP/2 only, and
+// plain-text HTTP/2 is enough for a mock.
+func protocols() *http.Protocols {
+	var p http.Protocols
+	p.SetHTTP1(true)
+	p.SetUnencryptedHTTP2(true)
+	return &p
+}
+
+// token is the OAuth token endpoint for FCM service account keys.
+func (m *mock) token(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": "mock",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+	})
+}
+
+func (m *mock) fcm(w http.ResponseWriter, r *http.Request) {
+
+	if !strings.HasPrefix(strings.ToLower(r.Header.Get("Authorization")), "bearer ") {
+		writeJSON(w, http.StatusUnauthorized, fcmError(http.StatusUnauthorized, "UNAUTHENTICATED", ""))
+		return
+	}
+
+	var req struct {
+		Message struct {
+			Token string `json:"token"`
+		} `json:"message"`
+	}
+	payload, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(payload, &req)
+	}
+	if err != nil || req.Message.Token == "" {
+		writeJSON(w, http.StatusBadRequest, fcmError(http.StatusBadRequest, "INVALID_ARGUMENT", "INVALID_ARGUMENT"))
+		return
+	}
+
+	if strings.HasPrefix(req.Message.Token, "invalid") {
+		writeJSON(w, http.StatusNotFound, fcmError(http.StatusNotFound, "NOT_FOUND", "UNREGISTERED"))
+		return
+	}
+
+	m.add("fcm", req.Message.Token, payload)
+	writeJSON(w, http.StatusOK, map[string]string{
+		"name": fmt.Sprintf("projects/%s/messages/%d", r.PathValue("project"), time.Now().UnixNano()),
+	})
+}
+
+func (m *mock) apns(w http.ResponseWriter, r *http.Request) {
+
+	if r.Header.Get("apns-topic") == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"reason": "MissingTopic"})
+		return
+	}
+	if r.Header.Get("Authorization") == "" {
+		writeJSON(w, http.StatusForbidden, map[string]string{"reason": "MissingProviderToken"})
+		return
+	}
+
+	token := r.PathValue("token")
+	if strings.HasPrefix(token, "invalid") {
+		writeJSON(w, http.StatusGone, map[string]any{"reason": "Unregistered", "timestamp": time.Now().UnixMilli()})
+		return
+	}
+
+	payload, err := io.ReadAll(r.Body)
+	if err != nil || !json.Valid(payload) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"reason": "PayloadEmpty"})
+		return
+	}
+
+	m.add("apns", token, payload)
+	w.Header().Set("apns-id", fmt.Sprintf("%d", time.Now().UnixNano()))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (m *mock) list(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	writeJSON(w, http.StatusOK, m.messages)
+}
+
+func (m *mock) add(provider, token string, payload []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, received{Provider: provider, Token: token, Payload: payload, ReceivedAt: time.Now()})
+	log.Printf("%s: message to %s", provider, token)
+}
+
+func fcmError(code int, status string, errorCode string) map[string]any {
+	e := map[string]any{"code": code, "status": status, "message": strings.ToLower(status)}
+	if errorCode != "" {
+		e["details"] = []map[string]string{{
+			"@type":     "type.googleapis.com/google.firebase.fcm.v1.FcmError",
+			"errorCode": errorCode,
+		}}
+	}
+	return map[string]any{"error": e}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/inzarubin80/Server/internal/model"
+	"github.com/inzarubin80/Server/internal/push"
+	"github.com/inzarubin80/Server/internal/service"
+)
+
+// TestSendPush runs the real FCM and APNs senders against the mock through the
+// push outbox handler: accepted messages are delivered and devices with
+// rejected tokens are deleted.
+func TestSendPush(t *testing.T) {
+
+	m := &mock{}
+	server := httptest.NewUnstartedServer(newMux(m))
+	server.Config.Protocols = protocols()
+	server.Start()
+	t.Cleanup(server.Close)
+
+	fcm, err := push.NewFCMSender(push.FCMConfig{Endpoint: server.URL, ProjectID: "warden", AccessToken: "mock"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	apns, err := push.NewAPNsSender(push.APNsConfig{Endpoint: server.URL, Topic: "ru.warden", AuthToken: "mock"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := push.NewRouter()
+	router.Register(model.PlatformAndroid, fcm)
+	router.Register(model.PlatformIOS, apns)
+
+	repo := &deviceRepo{}
+	svc := service.NewPokerService(repo, nil, nil, nil, nil, nil, nil, nil, nil, router, nil, service.Config{})
+
+	tests := []struct {
+		name      string
+		platform  string
+		token     string
+		delivered bool
+	}{
+		{name: "fcm delivered", platform: model.PlatformAndroid, token: "android-token", delivered: true},
+		{name: "fcm unregistered", platform: model.PlatformAndroid, token: "invalid-android-token"},
+		{name: "apns delivered", platform: model.PlatformIOS, token: "ios-token", delivered: true},
+		{name: "apns unregistered", platform: model.PlatformIOS, token: "invalid-ios-token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			payload, err := json.Marshal(&model.PushMessage{UserID: 1, Platform: tt.platform, Token: tt.token, Title: "title", Body: "body"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := svc.SendPush(context.Background(), payload); err != nil {
+				t.Fatalf("SendPush: %v", err)
+			}
+
+			if got := m.received(tt.token); got != tt.delivered {
+				t.Errorf("delivered = %v, want %v", got, tt.delivered)
+			}
+			if got := repo.deleted(tt.token); got == tt.delivered {
+				t.Errorf("device deleted = %v, want %v", got, !tt.delivered)
+			}
+		})
+	}
+}
+
+func (m *mock) received(token string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, msg := range m.messages {
+		if msg.Token == token {
+			return true
+		}
+	}
+	return false
+}
+
+// deviceRepo records deleted devices. Other methods panic through the nil
+// embedded Repository.
+type deviceRepo struct {
+	service.Repository
+
+	mu     sync.Mutex
+	tokens []string
+}
+
+func (r *deviceRepo) DeleteDeviceByToken(ctx context.Context, token string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokens = append(r.tokens, token)
+	return nil
+}
+
+func (r *deviceRepo) deleted(token string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range r.tokens {
+		if t == token {
+			return true
+		}
+	}
+	return false
+}
@@ -13,9 +13,16 @@ import (
 	appHttp "github.com/inzarubin80/Server/internal/app/http"
 	middleware "github.com/inzarubin80/Server/internal/app/http/middleware"
 	tokenservice "github.com/inzarubin80/Server/internal/app/token_service"
+	"github.com/inzarubin80/Server/internal/app/uhttp"
 	ws "github.com/inzarubin80/Server/internal/app/ws"
+	"github.com/inzarubin80/Server/internal/imageproc"
 	"github.com/inzarubin80/Server/internal/model"
+	"github.com/inzarubin80/Server/internal/moderation"
+	"github.com/inzarubin80/Server/internal/objectstorage"
+	"github.com/inzarubin80/Server/internal/outbox"
+	"github.com/inzarubin80/Server/internal/push"
 	"github.com/inzarubin80/Server/internal/repository"
+	"github.com/inzarubin80/Server/internal/rules"
 	service "github.com/inzarubin80/Server/internal/service"
 
 	//"github.com/rs/cors"
@@ -45,29 +52,86 @@ type (
 		oauthConfig   *oauth2.Config
 		store         *sessions.CookieStore
 		provadersConf authinterface.MapProviderOauthConf
+		// localStorage is set when photos are stored on local disk; it also serves uploads.
+		localStorage *objectstorage.LocalStorage
+		imagePool    *imageproc.Pool
+		relay        *outbox.Relay
 	}
 )
 
-// hubAdapter provides no-op implementations to satisfy the service.Hub interface.
-type hubAdapter struct{ *ws.Hub }
+// sweepPendingPhotos schedules the photos still waiting for processing on start
+// and then every imageSweepInterval, so that none is left behind by a restart or
+// a full queue.
+func (a *App) sweepPendingPhotos(ctx context.Context) {
 
-func (h *hubAdapter) AddMessage(pokerID model.PokerID, payload any) error { return nil }
-func (h *hubAdapter) AddMessageForUser(pokerID model.PokerID, userID model.UserID, payload any) error {
-	return nil
-}
-func (h *hubAdapter) GetActiveUsersID(pokerID model.PokerID) ([]model.UserID, error) {
-	return []model.UserID{}, nil
+	ticker := time.NewTicker(a.config.imageSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := a.pokerService.RequeuePendingPhotos(ctx); err != nil {
+			fmt.Println("requeue pending photos:", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
 }
 
 func (a *App) ListenAndServe() error {
 	go a.hub.Run()
+	go a.imagePool.Run(context.Background())
+	go a.sweepPendingPhotos(context.Background())
+	go a.relay.Run(context.Background())
 
 	a.mux.Handle(a.config.path.ping, appHttp.NewPingHandlerHandler(a.config.path.ping))
 	a.mux.Handle(a.config.path.session, appHttp.NewGetSessionHandler(a.store, a.config.path.session))
 	a.mux.Handle(a.config.path.getProviders, appHttp.NewProvadersHandler(a.provadersConf, a.config.path.getProviders))
 	a.mux.Handle(a.config.path.login, appHttp.NewLoginHandler(a.provadersConf, a.config.path.login, a.store))
 	a.mux.Handle(a.config.path.exchange, appHttp.NewExchangeHandler(a.store, a.config.path.exchange, a.pokerService))
+	a.mux.Handle(a.config.path.refreshToken, appHttp.NewRefreshTokenHandler(a.pokerService, a.config.path.refreshToken, a.store))
+	a.mux.Handle(a.config.path.logOut, appHttp.NewLogOutHandlerHandler(a.config.path.logOut, a.store, a.pokerService))
+	a.mux.Handle(a.config.path.listSessions, middleware.NewAuthMiddleware(appHttp.NewListSessionsHandler(a.pokerService, a.config.path.listSessions), a.store, a.pokerService))
+	a.mux.Handle(a.config.path.revokeSession, middleware.NewAuthMiddleware(appHttp.NewRevokeSessionHandler(a.pokerService, a.config.path.revokeSession), a.store, a.pokerService))
+	a.mux.Handle(a.config.path.revokeAllSessions, middleware.NewAuthMiddleware(appHttp.NewRevokeAllSessionsHandler(a.pokerService, a.config.path.revokeAllSessions), a.store, a.pokerService))
+	a.mux.Handle(a.config.path.registerDevice, middleware.NewAuthMiddleware(appHttp.NewRegisterDeviceHandler(a.pokerService, a.config.path.registerDevice), a.store, a.pokerService))
+	a.mux.Handle(a.config.path.unregisterDevice, middleware.NewAuthMiddleware(appHttp.NewUnregisterDeviceHandler(a.pokerService, a.config.path.unregisterDevice), a.store, a.pokerService))
+	a.mux.Handle(a.config.path.listNotifications, middleware.NewAuthMiddleware(appHttp.NewListNotificationsHandler(a.pokerService, a.config.path.listNotifications), a.store, a.pokerService))
+	a.mux.Handle(a.config.path.unreadNotifications, middleware.NewAuthMiddleware(appHttp.NewUnreadNotificationsHandler(a.pokerService, a.config.path.unreadNotifications), a.store, a.pokerService))
+	a.mux.Handle(a.config.path.readNotification, middleware.NewAuthMiddleware(appHttp.NewReadNotificationHandler(a.pokerService, a.config.path.readNotification), a.store, a.pokerService))
+	a.mux.Handle(a.config.path.readAllNotifications, middleware.NewAuthMiddleware(appHttp.NewReadAllNotificationsHandler(a.pokerService, a.config.path.readAllNotifications), a.store, a.pokerService))
 	a.mux.Handle(a.config.path.createViolation, middleware.NewAuthMiddleware(appHttp.NewCreateViolationHandler(a.store, a.config.path.createViolation, a.pokerService), a.store, a.pokerService))
+	a.mux.Handle(a.config.path.listViolations, appHttp.NewListViolationsHandler(a.pokerService, a.config.path.listViolations))
+	a.mux.Handle(a.config.path.clusterViolations, appHttp.NewClusterViolationsHandler(a.pokerService, a.config.path.clusterViolations))
+	a.mux.Handle(a.config.path.exportViolations, middleware.NewAuthMiddleware(middleware.NewRequireRole(appHttp.NewExportViolationsHandler(a.pokerService, a.config.path.exportViolations), model.RolePartner), a.store, a.pokerService))
+	a.mux.Handle(a.config.path.violationTile, appHttp.NewViolationTileHandler(a.pokerService, a.config.path.violationTile, a.config.service.Tiles.MaxAge))
+	a.mux.Handle(a.config.path.getViolation, middleware.NewOptionalAuthMiddleware(appHttp.NewGetViolationHandler(a.pokerService, a.config.path.getViolation), a.store, a.pokerService))
+	a.mux.Handle(a.config.path.confirmViolation, middleware.NewAuthMiddleware(appHttp.NewConfirmViolationHandler(a.pokerService, a.config.path.confirmViolation), a.store, a.pokerService))
+	a.mux.Handle(a.config.path.unconfirmViolation, middleware.NewAuthMiddleware(appHttp.NewUnconfirmViolationHandler(a.pokerService, a.config.path.unconfirmViolation), a.store, a.pokerService))
+	a.mux.Handle(a.config.path.proposeResolve, middleware.NewAuthMiddleware(appHttp.NewProposeResolveHandler(a.pokerService, a.config.path.proposeResolve), a.store, a.pokerService))
+	a.mux.Handle(a.config.path.resolveViolation, middleware.NewAuthMiddleware(appHttp.NewResolveViolationHandler(a.pokerService, a.config.path.resolveViolation), a.store, a.pokerService))
+	a.mux.Handle(a.config.path.sync, middleware.NewAuthMiddleware(appHttp.NewSyncHandler(a.pokerService, a.config.path.sync), a.store, a.pokerService))
+	a.mux.Handle(a.config.path.listComments, middleware.NewOptionalAuthMiddleware(appHttp.NewListCommentsHandler(a.pokerService, a.config.path.listComments), a.store, a.pokerService))
+	a.mux.Handle(a.config.path.addComment, middleware.NewAuthMiddleware(appHttp.NewAddCommentHandler(a.pokerService, a.config.path.addComment), a.store, a.pokerService))
+	a.mux.Handle(a.config.path.editComment, middleware.NewAuthMiddleware(appHttp.NewEditCommentHandler(a.pokerService, a.config.path.editComment), a.store, a.pokerService))
+	a.mux.Handle(a.config.path.deleteComment, middleware.NewAuthMiddleware(appHttp.NewDeleteCommentHandler(a.pokerService, a.config.path.deleteComment), a.store, a.pokerService))
+	a.mux.Handle(a.config.path.moderateComment, middleware.NewAuthMiddleware(middleware.NewRequireRole(appHttp.NewModerateCommentHandler(a.pokerService, a.config.path.moderateComment), model.RoleModerator), a.store, a.pokerService))
+	a.mux.Handle(a.config.path.upload, middleware.NewAuthMiddleware(appHttp.NewUploadHandler(a.pokerService, a.config.path.upload), a.store, a.pokerService))
+	a.mux.Handle(a.config.path.uploadComplete, middleware.NewAuthMiddleware(appHttp.NewUploadCompleteHandler(a.pokerService, a.config.path.uploadComplete), a.store, a.pokerService))
+	a.mux.Handle(a.config.path.realtime, middleware.NewAuthMiddleware(appHttp.NewRealtimeHandler(a.hub, a.config.path.realtime), a.store, a.pokerService))
+	a.mux.Handle(a.config.path.setUserRole, middleware.NewAuthMiddleware(middleware.NewRequireRole(appHttp.NewSetUserRoleHandler(a.pokerService, a.config.path.setUserRole), model.RoleAdmin), a.store, a.pokerService))
+	a.mux.Handle(a.config.path.listAudit, middleware.NewAuthMiddleware(middleware.NewRequireRole(appHttp.NewListAuditHandler(a.pokerService, a.config.path.listAudit), model.RoleAdmin), a.store, a.pokerService))
+	a.mux.Handle(a.config.path.listOutbox, middleware.NewAuthMiddleware(middleware.NewRequireRole(appHttp.NewListOutboxHandler(a.pokerService, a.config.path.listOutbox), model.RoleAdmin), a.store, a.pokerService))
+	a.mux.Handle(a.config.path.replayOutbox, middleware.NewAuthMiddleware(middleware.NewRequireRole(appHttp.NewReplayOutboxHandler(a.pokerService, a.config.path.replayOutbox), model.RoleAdmin), a.store, a.pokerService))
+	a.mux.Handle(a.config.path.pendingModeration, middleware.NewAuthMiddleware(middleware.NewRequireRole(appHttp.NewPendingModerationHandler(a.pokerService, a.config.path.pendingModeration), model.RoleModerator), a.store, a.pokerService))
+	a.mux.Handle(a.config.path.moderationAction, middleware.NewAuthMiddleware(middleware.NewRequireRole(appHttp.NewModerationActionHandler(a.pokerService, a.config.path.moderationAction), model.RoleModerator), a.store, a.pokerService))
+	a.mux.Handle(a.config.path.moderationResult, appHttp.NewModerationResultHandler(a.pokerService, a.config.path.moderationResult, a.config.moderation.http.Secret))
+	if a.localStorage != nil {
+		a.mux.Handle(a.config.path.getStorageObject, a.localStorage)
+		a.mux.Handle(a.config.path.putStorageObject, a.localStorage)
+	}
 	fmt.Println("start server")
 
 	return a.server.ListenAndServe()
@@ -77,16 +141,48 @@ func NewApp(ctx context.Context, config config, dbConn *pgxpool.Pool) (*App, err
 
 	var (
 		mux   = http.NewServeMux()
-		hub   = ws.NewHub()
+		hub   = ws.NewHub(config.wsAllowedOrigins)
 		store = sessions.NewCookieStore([]byte(config.sectrets.storeSecret))
 	)
 
+	switch config.service.Moderation.InitialStatus {
+	case model.ModerationPending, model.ModerationApproved:
+	default:
+		return nil, fmt.Errorf("invalid initial moderation status %q", config.service.Moderation.InitialStatus)
+	}
+	if m := config.service.Moderation; m.AutoApproveBelow > m.RejectFrom {
+		return nil, fmt.Errorf("moderation approve threshold %g is above reject threshold %g", m.AutoApproveBelow, m.RejectFrom)
+	}
+	if d := config.service.Dedup; !d.Policy.Valid() {
+		return nil, fmt.Errorf("invalid dedup policy %q", d.Policy)
+	} else if d.Policy != model.DedupOff && (d.RadiusM <= 0 || d.Window <= 0 || d.MaxCandidates <= 0) {
+		return nil, fmt.Errorf("dedup radius, window and max candidates must be positive")
+	}
+	if config.service.Export.ReporterSecret == "" {
+		return nil, fmt.Errorf("export reporter secret is required")
+	}
+	if config.service.Comments.EditWindow < 0 {
+		return nil, fmt.Errorf("comment edit window must not be negative")
+	}
+	if config.service.Image.MaxPixels <= 0 {
+		return nil, fmt.Errorf("image max pixels must be positive")
+	}
+	if config.service.Tiles.MaxFeatures <= 0 {
+		return nil, fmt.Errorf("tile max features must be positive")
+	}
+	if config.imageSweepInterval <= 0 {
+		return nil, fmt.Errorf("image sweep interval must be positive")
+	}
+	if config.eventQueueSize <= 0 {
+		return nil, fmt.Errorf("event queue size must be positive")
+	}
+
 	// Build repository
 	repo := repository.NewPokerRepository(dbConn)
 
 	// Build token services
 	accessTokenService := tokenservice.NewtokenService([]byte(config.sectrets.accessTokenSecret), 30*time.Minute, model.Access_Token_Type)
-	refreshTokenService := tokenservice.NewtokenService([]byte(config.sectrets.refreshTokenSecret), 30*24*time.Hour, model.Refresh_Token_Type)
+	refreshTokenService := tokenservice.NewtokenService([]byte(config.sectrets.refreshTokenSecret), config.service.RefreshTokenTTL, model.Refresh_Token_Type)
 
 	// Build providers user data map from config
 	providersMap := make(authinterface.ProvidersUserData)
@@ -96,8 +192,98 @@ func NewApp(ctx context.Context, config config, dbConn *pgxpool.Pool) (*App, err
 		}
 	}
 
+	// Build object storage
+	var (
+		objectStorage service.ObjectStorage
+		localStorage  *objectstorage.LocalStorage
+		err           error
+	)
+	switch config.storage.backend {
+	case "s3":
+		objectStorage, err = objectstorage.NewS3Storage(config.storage.s3)
+	case "local":
+		localStorage, err = objectstorage.NewLocalStorage(config.storage.local)
+		objectStorage = localStorage
+	default:
+		err = fmt.Errorf("unknown storage backend %q", config.storage.backend)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Build automated moderator
+	var moderator service.Moderator
+	switch config.moderation.backend {
+	case "none":
+	case "fake":
+		moderator = moderation.NewFakeModerator(moderation.DefaultFakeRules)
+	case "http":
+		moderator, err = moderation.NewHTTPModerator(config.moderation.http)
+	default:
+		err = fmt.Errorf("unknown moderation backend %q", config.moderation.backend)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Build push senders
+	pushRouter := push.NewRouter()
+	if config.push.fcm.ProjectID != "" {
+		fcm, err := push.NewFCMSender(config.push.fcm)
+		if err != nil {
+			return nil, err
+		}
+		pushRouter.Register(model.PlatformAndroid, fcm)
+	}
+	if config.push.apns.Topic != "" {
+		apns, err := push.NewAPNsSender(config.push.apns)
+		if err != nil {
+			return nil, err
+		}
+		pushRouter.Register(model.PlatformIOS, apns)
+	}
+	var pushSender service.PushSender
+	if !pushRouter.Empty() {
+		pushSender = pushRouter
+	}
+
+	// Build rules engine
+	rulesEngine := rules.NewEngine(repo, config.rulesReloadInterval)
+
+	// Build image processing pool; its jobs call back into the service built below.
+	var pokerService *service.PokerService
+	imagePool := imageproc.NewPool(config.imagePool,
+		func(ctx context.Context, storageKey string) error { return pokerService.ProcessPhoto(ctx, storageKey) },
+		func(ctx context.Context, storageKey string, err error) {
+			pokerService.FailPhotoProcessing(ctx, storageKey, err)
+		},
+	)
+
+	// Build event bus. The realtime feed is delivered in the request; inbox
+	// notifications and the audit log of events are written in the background.
+	// The outbox delivers the notifications again if the process stops first.
+	events := service.NewEventBus()
+	events.Subscribe("realtime", hub.HandleEvent)
+
 	// Build service
-	pokerService := service.NewPokerService(repo, &hubAdapter{Hub: hub}, accessTokenService, refreshTokenService, providersMap)
+	pokerService = service.NewPokerService(repo, events, accessTokenService, refreshTokenService, providersMap, rulesEngine, objectStorage, imagePool, moderator, pushSender, hub, config.service)
+	events.SubscribeAsync("notifications", pokerService.HandleNotificationEvent, config.eventQueueSize)
+	events.SubscribeAsync("audit", pokerService.HandleAuditEvent, config.eventQueueSize)
+
+	// Build outbox relay; it carries out the side effects the service records
+	// together with its changes.
+	relay, err := outbox.NewRelay(repo, config.outbox)
+	if err != nil {
+		return nil, err
+	}
+	relay.Register(model.OutboxTopicInboxEvent, pokerService.DeliverInboxEvent)
+	if pushSender != nil {
+		relay.Register(model.OutboxTopicPushEvent, pokerService.DeliverPushEvent)
+		relay.Register(model.OutboxTopicPushSend, pokerService.SendPush)
+	}
+	if moderator != nil {
+		relay.Register(model.OutboxTopicModerationSubmit, pokerService.SubmitForModeration)
+	}
 
 	/*
 		// Создаем CORS middleware
@@ -122,7 +308,11 @@ func NewApp(ctx context.Context, config config, dbConn *pgxpool.Pool) (*App, err
 	*/
 
 	// Обертываем основной обработчик
-	handler := middleware.NewLogMux(mux)
+	trustedProxies, err := uhttp.ParseTrustedProxies(config.trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	handler := middleware.NewRequestID(middleware.NewLogMux(mux), trustedProxies)
 
 	return &App{
 		mux:           mux,
@@ -132,6 +322,9 @@ func NewApp(ctx context.Context, config config, dbConn *pgxpool.Pool) (*App, err
 		hub:           hub,
 		store:         store,
 		provadersConf: config.provadersConf,
+		localStorage:  localStorage,
+		imagePool:     imagePool,
+		relay:         relay,
 	}, nil
 
 }
@@ -10,7 +10,7 @@ import (
 
 type (
 	TokenService interface {
-		GenerateToken(userID model.UserID, isAdmin bool) (string, error)
+		GenerateToken(userID model.UserID, role model.Role, sessionID string) (string, error)
 		ValidateToken(tokenString string) (*model.Claims, error)
 	}
 
@@ -3,11 +3,21 @@ package app
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	authinterface "github.com/inzarubin80/Server/internal/app/authinterface"
 	providerUserData "github.com/inzarubin80/Server/internal/app/clients/provider_user_data"
 	"github.com/inzarubin80/Server/internal/app/defenitions"
 	"github.com/inzarubin80/Server/internal/app/icons"
+	"github.com/inzarubin80/Server/internal/imageproc"
+	"github.com/inzarubin80/Server/internal/model"
+	"github.com/inzarubin80/Server/internal/moderation"
+	"github.com/inzarubin80/Server/internal/objectstorage"
+	"github.com/inzarubin80/Server/internal/outbox"
+	"github.com/inzarubin80/Server/internal/push"
+	"github.com/inzarubin80/Server/internal/service"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/yandex"
 )
@@ -19,8 +29,9 @@ type (
 	path struct {
 		index, getPoker, createPoker, createTask,
 		getTasks, getTask, updateTask, deleteTask,
-		getComents, addComent, setVotingTask,
-		getVotingControlState, ws, login, exchange, createViolation, session, refreshToken, logOut, getProviders,
+		listComments, addComment, editComment, deleteComment, moderateComment, setVotingTask,
+		getVotingControlState, ws, login, exchange, createViolation, listViolations, clusterViolations, exportViolations, violationTile, getViolation, confirmViolation, unconfirmViolation, proposeResolve, resolveViolation, session, refreshToken, logOut, listSessions, revokeSession, revokeAllSessions, registerDevice, unregisterDevice, listNotifications, unreadNotifications, readNotification, readAllNotifications, setUserRole, pendingModeration, moderationAction, moderationResult, listAudit, listOutbox, replayOutbox, sync, getProviders,
+		upload, uploadComplete, getStorageObject, putStorageObject, realtime,
 		ping, vote, getUserEstimates, setVotingControlState, setUserName, getUser, setUserSettings, getLastSession, deletePoker string
 	}
 
@@ -30,11 +41,50 @@ type (
 		refreshTokenSecret string
 	}
 
+	// moderationConfig selects the automated moderator: "none", "fake" or "http".
+	moderationConfig struct {
+		backend string
+		http    moderation.HTTPConfig
+	}
+
+	// pushConfig configures the push providers. FCM is enabled when a project id
+	// is set and APNs when a topic is set; without either, pushes are not sent.
+	pushConfig struct {
+		fcm  push.FCMConfig
+		apns push.APNsConfig
+	}
+
+	// storageConfig selects the object storage backend for photos: "local" or "s3".
+	storageConfig struct {
+		backend string
+		local   objectstorage.LocalConfig
+		s3      objectstorage.S3Config
+	}
+
 	config struct {
 		addr          string
 		path          path
 		sectrets      sectrets
 		provadersConf authinterface.MapProviderOauthConf
+		// rulesReloadInterval is how long violation rules are cached before reloading.
+		rulesReloadInterval time.Duration
+		storage             storageConfig
+		moderation          moderationConfig
+		push                pushConfig
+		outbox              outbox.RelayConfig
+		service             service.Config
+		imagePool           imageproc.PoolConfig
+		// imageSweepInterval is how often photos still pending processing are
+		// scheduled again.
+		imageSweepInterval time.Duration
+		// eventQueueSize is how many domain events an asynchronous event bus
+		// subscriber may have pending before new ones are dropped.
+		eventQueueSize int
+		// wsAllowedOrigins lists the browser origins allowed to open /api/realtime.
+		wsAllowedOrigins []string
+		// trustedProxies lists the addresses or CIDR ranges of the reverse proxies
+		// whose X-Forwarded-For is believed; without them the peer address is used.
+		trustedProxies []string
 		// TLS debug settings
 		tlsEnabled  bool
 		tlsCertFile string
@@ -99,9 +149,39 @@ func NewConfig(opts Options) config {
 			createPoker:  "POST	/api/poker",
 			getProviders: "GET /api/providers",
 
-			login:           "POST	/api/user/login",
-			exchange:        "POST	/api/user/exchange",
-			createViolation: "POST	/api/violations",
+			login:             "POST	/api/user/login",
+			exchange:          "POST	/api/user/exchange",
+			createViolation:   "POST	/api/violations",
+			listViolations:    "GET	/api/violations",
+			clusterViolations: "GET	/api/violations/clusters",
+			exportViolations:  "GET	/api/violations/export",
+			violationTile:     fmt.Sprintf("GET	/api/tiles/violations/{%s}/{%s}/{%s}", defenitions.ParamTileZ, defenitions.ParamTileX, defenitions.ParamTileY),
+			getViolation:      fmt.Sprintf("GET	/api/violations/{%s}", defenitions.ParamViolationID),
+
+			confirmViolation:   fmt.Sprintf("POST	/api/violations/{%s}/confirm", defenitions.ParamViolationID),
+			unconfirmViolation: fmt.Sprintf("DELETE	/api/violations/{%s}/confirm", defenitions.ParamViolationID),
+			proposeResolve:     fmt.Sprintf("POST	/api/violations/{%s}/propose_resolve", defenitions.ParamViolationID),
+			resolveViolation:   fmt.Sprintf("POST	/api/violations/{%s}/resolve", defenitions.ParamViolationID),
+
+			listComments:    fmt.Sprintf("GET	/api/violations/{%s}/comments", defenitions.ParamViolationID),
+			addComment:      fmt.Sprintf("POST	/api/violations/{%s}/comments", defenitions.ParamViolationID),
+			editComment:     fmt.Sprintf("PATCH	/api/violations/{%s}/comments/{%s}", defenitions.ParamViolationID, defenitions.ParamCommentID),
+			deleteComment:   fmt.Sprintf("DELETE	/api/violations/{%s}/comments/{%s}", defenitions.ParamViolationID, defenitions.ParamCommentID),
+			moderateComment: fmt.Sprintf("POST	/api/violations/{%s}/comments/{%s}/moderate", defenitions.ParamViolationID, defenitions.ParamCommentID),
+
+			sync: "POST	/api/sync",
+
+			pendingModeration: "GET	/api/violations/pending_moderation",
+			moderationAction:  fmt.Sprintf("POST	/api/moderation/{%s}/action", defenitions.ParamViolationID),
+			moderationResult:  "POST	/internal/moderation/result",
+
+			upload:           "POST	/api/upload",
+			uploadComplete:   "POST	/api/upload/complete",
+			getStorageObject: "GET	/api/storage/{key...}",
+			putStorageObject: "PUT	/api/storage/{key...}",
+
+			realtime: "GET	/api/realtime",
+
 			setUserName:     "POST	/api/user/name",
 			setUserSettings: "POST	/api/user/settings",
 
@@ -109,7 +189,25 @@ func NewConfig(opts Options) config {
 
 			refreshToken: "POST	/api/user/refresh",
 			session:      "GET		/api/user/session",
-			logOut:       "GET		/api/user/logout",
+			logOut:       "POST	/api/user/logout",
+
+			listSessions:      "GET	/api/user/sessions",
+			revokeSession:     fmt.Sprintf("DELETE	/api/user/sessions/{%s}", defenitions.ParamSessionID),
+			revokeAllSessions: "POST	/api/user/sessions/revoke_all",
+
+			registerDevice:   "POST	/api/devices/register",
+			unregisterDevice: fmt.Sprintf("DELETE	/api/devices/{%s}", defenitions.ParamDeviceToken),
+
+			listNotifications:    "GET	/api/notifications",
+			unreadNotifications:  "GET	/api/notifications/unread_count",
+			readNotification:     fmt.Sprintf("POST	/api/notifications/{%s}/read", defenitions.ParamNotificationID),
+			readAllNotifications: "POST	/api/notifications/read_all",
+
+			setUserRole: fmt.Sprintf("POST	/api/admin/users/{%s}/role", defenitions.ParamUserID),
+			listAudit:   "GET	/api/admin/audit",
+
+			listOutbox:   "GET	/api/admin/outbox",
+			replayOutbox: fmt.Sprintf("POST	/api/admin/outbox/{%s}/replay", defenitions.ParamOutboxMessageID),
 
 			getLastSession: fmt.Sprintf("GET	/api/sessions/{%s}/{%s}", defenitions.Page, defenitions.PageSize),
 		},
@@ -120,11 +218,182 @@ func NewConfig(opts Options) config {
 			refreshTokenSecret: os.Getenv("REFRESH_TOKEN_SECRET"),
 		},
 
-		provadersConf: provaders,
-		tlsEnabled:    true,
-		tlsCertFile:   os.Getenv("TLS_CERT_FILE"),
-		tlsKeyFile:    os.Getenv("TLS_KEY_FILE"),
+		provadersConf:       provaders,
+		rulesReloadInterval: envDuration("RULES_RELOAD_INTERVAL", time.Minute),
+
+		storage: storageConfig{
+			backend: envString("STORAGE_BACKEND", "local"),
+			local: objectstorage.LocalConfig{
+				Dir:     envString("LOCAL_STORAGE_DIR", "./data/uploads"),
+				BaseURL: envString("LOCAL_STORAGE_URL", os.Getenv("APP_ROOT")+"/api/storage"),
+				Secret:  envString("LOCAL_STORAGE_SECRET", os.Getenv("STORE_SECRET")),
+			},
+			s3: objectstorage.S3Config{
+				Endpoint:  os.Getenv("S3_ENDPOINT"),
+				Region:    os.Getenv("S3_REGION"),
+				Bucket:    os.Getenv("S3_BUCKET"),
+				AccessKey: os.Getenv("S3_ACCESS_KEY"),
+				SecretKey: os.Getenv("S3_SECRET_KEY"),
+				PublicURL: os.Getenv("S3_PUBLIC_URL"),
+				PathStyle: envBool("S3_PATH_STYLE", false),
+			},
+		},
+
+		service: service.Config{
+			MaxPhotoSize:    envInt64("UPLOAD_MAX_FILE_SIZE", 10<<20),
+			MaxReportSize:   envInt64("UPLOAD_MAX_REPORT_SIZE", 40<<20),
+			MaxReportPhotos: int(envInt64("UPLOAD_MAX_PHOTOS", 10)),
+			UploadURLTTL:    envDuration("UPLOAD_URL_TTL", 15*time.Minute),
+			RefreshTokenTTL: envDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+			Image: imageproc.Options{
+				ThumbSize:   int(envInt64("IMAGE_THUMB_SIZE", 320)),
+				DisplaySize: int(envInt64("IMAGE_DISPLAY_SIZE", 1600)),
+				JPEGQuality: int(envInt64("IMAGE_JPEG_QUALITY", 85)),
+				MaxPixels:   envInt64("IMAGE_MAX_PIXELS", 50_000_000),
+			},
+			Moderation: service.ModerationConfig{
+				InitialStatus:    model.ModerationStatus(envString("MODERATION_INITIAL_STATUS", string(model.ModerationPending))),
+				AutoApproveBelow: envFloat("MODERATION_APPROVE_BELOW", 0.3),
+				RejectFrom:       envFloat("MODERATION_REJECT_FROM", 0.7),
+			},
+			Dedup: service.DedupConfig{
+				Policy:        model.DedupPolicy(envString("DEDUP_POLICY", string(model.DedupSuggest))),
+				RadiusM:       envFloat("DEDUP_RADIUS_M", 50),
+				Window:        envDuration("DEDUP_WINDOW", 30*24*time.Hour),
+				MaxCandidates: int(envInt64("DEDUP_MAX_CANDIDATES", 5)),
+			},
+			Clusters: service.ClusterConfig{
+				PointsZoom: int(envInt64("CLUSTER_POINTS_ZOOM", 16)),
+				MaxPoints:  int(envInt64("CLUSTER_MAX_POINTS", 500)),
+			},
+			Tiles: service.TileConfig{
+				MaxFeatures: int(envInt64("TILE_MAX_FEATURES", 10000)),
+				MaxAge:      envDuration("TILE_MAX_AGE", time.Minute),
+			},
+			Export: service.ExportConfig{
+				ReporterSecret: envString("EXPORT_REPORTER_SECRET", os.Getenv("STORE_SECRET")),
+			},
+			Comments: service.CommentConfig{
+				EditWindow: envDuration("COMMENT_EDIT_WINDOW", 15*time.Minute),
+			},
+		},
+
+		moderation: moderationConfig{
+			backend: envString("MODERATION_BACKEND", "none"),
+			http: moderation.HTTPConfig{
+				URL:         os.Getenv("MODERATION_URL"),
+				CallbackURL: envString("MODERATION_CALLBACK_URL", os.Getenv("APP_ROOT")+"/internal/moderation/result"),
+				Secret:      os.Getenv("MODERATION_SECRET"),
+				Timeout:     envDuration("MODERATION_TIMEOUT", 5*time.Second),
+			},
+		},
+
+		push: pushConfig{
+			fcm: push.FCMConfig{
+				Endpoint:        envString("FCM_ENDPOINT", push.DefaultFCMEndpoint),
+				ProjectID:       os.Getenv("FCM_PROJECT_ID"),
+				CredentialsFile: os.Getenv("FCM_CREDENTIALS_FILE"),
+				TokenURL:        os.Getenv("FCM_TOKEN_URL"),
+				AccessToken:     os.Getenv("FCM_ACCESS_TOKEN"),
+				Timeout:         envDuration("PUSH_TIMEOUT", 10*time.Second),
+			},
+			apns: push.APNsConfig{
+				Endpoint:  envString("APNS_ENDPOINT", push.DefaultAPNsEndpoint),
+				Topic:     os.Getenv("APNS_TOPIC"),
+				KeyFile:   os.Getenv("APNS_KEY_FILE"),
+				KeyID:     os.Getenv("APNS_KEY_ID"),
+				TeamID:    os.Getenv("APNS_TEAM_ID"),
+				AuthToken: os.Getenv("APNS_AUTH_TOKEN"),
+				Timeout:   envDuration("PUSH_TIMEOUT", 10*time.Second),
+			},
+		},
+
+		outbox: outbox.RelayConfig{
+			PollInterval: envDuration("OUTBOX_POLL_INTERVAL", time.Second),
+			BatchSize:    int(envInt64("OUTBOX_BATCH_SIZE", 100)),
+			Workers:      int(envInt64("OUTBOX_WORKERS", 4)),
+			MaxAttempts:  int(envInt64("OUTBOX_MAX_ATTEMPTS", 8)),
+			Backoff:      envDuration("OUTBOX_RETRY_BACKOFF", 5*time.Second),
+			MaxBackoff:   envDuration("OUTBOX_RETRY_MAX_BACKOFF", time.Hour),
+			Lease:        envDuration("OUTBOX_LEASE", 2*time.Minute),
+		},
+
+		imagePool: imageproc.PoolConfig{
+			Workers:     int(envInt64("IMAGE_WORKERS", 2)),
+			QueueSize:   int(envInt64("IMAGE_QUEUE_SIZE", 256)),
+			MaxAttempts: int(envInt64("IMAGE_MAX_ATTEMPTS", 5)),
+			Backoff:     envDuration("IMAGE_RETRY_BACKOFF", 2*time.Second),
+			MaxBackoff:  envDuration("IMAGE_RETRY_MAX_BACKOFF", 5*time.Minute),
+		},
+		imageSweepInterval: envDuration("IMAGE_SWEEP_INTERVAL", 5*time.Minute),
+		eventQueueSize:     int(envInt64("EVENT_QUEUE_SIZE", 256)),
+
+		wsAllowedOrigins: envList("WS_ALLOWED_ORIGINS"),
+		trustedProxies:   envList("TRUSTED_PROXIES"),
+
+		tlsEnabled:  true,
+		tlsCertFile: os.Getenv("TLS_CERT_FILE"),
+		tlsKeyFile:  os.Getenv("TLS_KEY_FILE"),
 	}
 
 	return config
 }
+
+// envDuration reads a time.Duration such as "30s" from the environment.
+func envDuration(key string, def time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+		fmt.Printf("invalid %s=%q, using %s\n", key, v, def)
+	}
+	return def
+}
+
+func envString(key string, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// envList reads a comma separated list from the environment.
+func envList(key string) []string {
+	var res []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+func envInt64(key string, def int64) int64 {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+		fmt.Printf("invalid %s=%q, using %d\n", key, v, def)
+	}
+	return def
+}
+
+func envFloat(key string, def float64) float64 {
+	if v := os.Getenv(key); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+		fmt.Printf("invalid %s=%q, using %g\n", key, v, def)
+	}
+	return def
+}
+
+func envBool(key string, def bool) bool {
+	if v := os.Getenv(key); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+		fmt.Printf("invalid %s=%q, using %t\n", key, v, def)
+	}
+	return def
+}
@@ -3,13 +3,43 @@ package defenitions
 const (
 	AuthorizationCode         = "authorization_code"
 	UserID                    = "user_id"
+	SessionID                 = "session_id"
+	Role                      = "role"
 	DisplayName               = "display_name"
 	DefaultEmail              = "default_email"
 	SessionAuthenticationName = "authentication"
 	Token                     = "token"
 	ProviderKey               = "provider_Key"
-	// ParamPokerID is the URL parameter name used for poker identifiers in routes.
-	ParamPokerID = "poker_id"
-	Page         = "page"
-	PageSize     = "page_size"
+	// ParamViolationID is the URL parameter name used for violation identifiers in routes.
+	ParamViolationID = "violation_id"
+	// ParamSessionID is the URL parameter name used for session (device) identifiers.
+	ParamSessionID = "session_id"
+	// ParamUserID is the URL parameter name used for user identifiers in admin routes.
+	ParamUserID = "user_id"
+	// ParamDeviceToken is the URL parameter name used for push tokens of devices.
+	ParamDeviceToken = "device_token"
+	// ParamNotificationID is the URL parameter name used for inbox notification identifiers.
+	ParamNotificationID = "notification_id"
+	// ParamOutboxMessageID is the URL parameter name used for outbox message identifiers in admin routes.
+	ParamOutboxMessageID = "message_id"
+	// ParamCommentID is the URL parameter name used for comment identifiers.
+	ParamCommentID = "comment_id"
+	// HeaderIdempotencyKey makes a retried create return the result of the first request.
+	HeaderIdempotencyKey = "Idempotency-Key"
+	Page             = "page"
+	PageSize         = "page_size"
+	Cursor           = "cursor"
+
+	// Query parameters of violation list filters.
+	ParamBBox   = "bbox"
+	ParamType   = "type"
+	ParamStatus = "status"
+	ParamFrom   = "from"
+	ParamTo     = "to"
+	ParamZoom   = "zoom"
+
+	// URL parameters of map tile routes; the y parameter carries the .mvt extension.
+	ParamTileZ = "z"
+	ParamTileX = "x"
+	ParamTileY = "y"
 )
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/inzarubin80/Server/internal/app/defenitions"
+	"github.com/inzarubin80/Server/internal/app/uhttp"
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+type (
+	clusterViolationsService interface {
+		ClusterViolations(ctx context.Context, filter *model.ViolationFilter, zoom int) (*model.ViolationClusters, error)
+	}
+
+	// ClusterViolationsHandler serves map markers for a bbox and zoom level. It
+	// accepts the filters of the list endpoint; cursor and page_size are ignored.
+	ClusterViolationsHandler struct {
+		name    string
+		service clusterViolationsService
+	}
+)
+
+func NewClusterViolationsHandler(service clusterViolationsService, name string) *ClusterViolationsHandler {
+	return &ClusterViolationsHandler{
+		name:    name,
+		service: service,
+	}
+}
+
+func (h *ClusterViolationsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	query := r.URL.Query()
+
+	filter, err := parseViolationFilter(query)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	zoom, err := strconv.Atoi(query.Get(defenitions.ParamZoom))
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("%v: %s is invalid", model.ErrInvalidParameter, defenitions.ParamZoom))
+		return
+	}
+
+	clusters, err := h.service.ClusterViolations(r.Context(), filter, zoom)
+	if err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
+	jsonData, err := json.Marshal(clusters)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	uhttp.SendSuccessfulResponse(w, jsonData)
+}
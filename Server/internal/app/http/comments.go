@@ -0,0 +1,283 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/inzarubin80/Server/internal/app/defenitions"
+	"github.com/inzarubin80/Server/internal/app/uhttp"
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+type (
+	commentsService interface {
+		AddComment(ctx context.Context, userID model.UserID, violationID model.ViolationID, text string) (*model.Comment, error)
+		ListComments(ctx context.Context, violationID model.ViolationID, viewer model.Viewer, cursor string, limit int) (*model.CommentsPage, error)
+		EditComment(ctx context.Context, userID model.UserID, violationID model.ViolationID, commentID model.CommentID, text string) (*model.Comment, error)
+		DeleteComment(ctx context.Context, userID model.UserID, violationID model.ViolationID, commentID model.CommentID) error
+		ModerateComment(ctx context.Context, moderatorID model.UserID, violationID model.ViolationID, commentID model.CommentID, action string, reason string) (*model.Comment, error)
+	}
+
+	// ListCommentsHandler returns the discussion thread of a violation. The route
+	// is public; authors also see their pending comments and moderators all of them.
+	ListCommentsHandler struct {
+		name    string
+		service commentsService
+	}
+
+	AddCommentHandler struct {
+		name    string
+		service commentsService
+	}
+
+	// EditCommentHandler lets authors correct a comment shortly after posting it.
+	EditCommentHandler struct {
+		name    string
+		service commentsService
+	}
+
+	DeleteCommentHandler struct {
+		name    string
+		service commentsService
+	}
+
+	// ModerateCommentHandler hides or shows a comment. It is a moderator route.
+	ModerateCommentHandler struct {
+		name    string
+		service commentsService
+	}
+)
+
+func NewListCommentsHandler(service commentsService, name string) *ListCommentsHandler {
+	return &ListCommentsHandler{
+		name:    name,
+		service: service,
+	}
+}
+
+func (h *ListCommentsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	violationID, err := uhttp.ValidatePatchStringParameter(r, defenitions.ParamViolationID)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// The viewer is set when the request carries a token.
+	var viewer model.Viewer
+	viewer.UserID, _ = ctx.Value(defenitions.UserID).(model.UserID)
+	viewer.Role, _ = ctx.Value(defenitions.Role).(model.Role)
+
+	query := r.URL.Query()
+
+	var limit int
+	if raw := query.Get("limit"); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil || limit <= 0 {
+			uhttp.SendErrorResponse(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+	}
+
+	page, err := h.service.ListComments(ctx, model.ViolationID(violationID), viewer, query.Get(defenitions.Cursor), limit)
+	if err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
+	resp, err := json.Marshal(page)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	uhttp.SendSuccessfulResponse(w, resp)
+}
+
+func NewAddCommentHandler(service commentsService, name string) *AddCommentHandler {
+	return &AddCommentHandler{
+		name:    name,
+		service: service,
+	}
+}
+
+func (h *AddCommentHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, ok := ctx.Value(defenitions.UserID).(model.UserID)
+	if !ok {
+		uhttp.SendErrorResponse(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	violationID, err := uhttp.ValidatePatchStringParameter(r, defenitions.ParamViolationID)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	comment, err := h.service.AddComment(ctx, userID, model.ViolationID(violationID), req.Text)
+	if err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
+	resp, err := json.Marshal(comment)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	uhttp.SendSuccessfulResponse(w, resp)
+}
+
+func NewEditCommentHandler(service commentsService, name string) *EditCommentHandler {
+	return &EditCommentHandler{
+		name:    name,
+		service: service,
+	}
+}
+
+func (h *EditCommentHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, ok := ctx.Value(defenitions.UserID).(model.UserID)
+	if !ok {
+		uhttp.SendErrorResponse(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	violationID, commentID, err := commentPathParams(r)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	comment, err := h.service.EditComment(ctx, userID, violationID, commentID, req.Text)
+	if err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
+	resp, err := json.Marshal(comment)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	uhttp.SendSuccessfulResponse(w, resp)
+}
+
+func NewDeleteCommentHandler(service commentsService, name string) *DeleteCommentHandler {
+	return &DeleteCommentHandler{
+		name:    name,
+		service: service,
+	}
+}
+
+func (h *DeleteCommentHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, ok := ctx.Value(defenitions.UserID).(model.UserID)
+	if !ok {
+		uhttp.SendErrorResponse(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	violationID, commentID, err := commentPathParams(r)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.service.DeleteComment(ctx, userID, violationID, commentID); err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
+	uhttp.SendSuccessfulResponse(w, []byte("{}"))
+}
+
+func NewModerateCommentHandler(service commentsService, name string) *ModerateCommentHandler {
+	return &ModerateCommentHandler{
+		name:    name,
+		service: service,
+	}
+}
+
+func (h *ModerateCommentHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	moderatorID, ok := ctx.Value(defenitions.UserID).(model.UserID)
+	if !ok {
+		uhttp.SendErrorResponse(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	violationID, commentID, err := commentPathParams(r)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req struct {
+		Action string `json:"action"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	comment, err := h.service.ModerateComment(ctx, moderatorID, violationID, commentID, req.Action, req.Reason)
+	if err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
+	resp, err := json.Marshal(comment)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	uhttp.SendSuccessfulResponse(w, resp)
+}
+
+func commentPathParams(r *http.Request) (model.ViolationID, model.CommentID, error) {
+
+	violationID, err := uhttp.ValidatePatchStringParameter(r, defenitions.ParamViolationID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	rawCommentID, err := uhttp.ValidatePatchStringParameter(r, defenitions.ParamCommentID)
+	if err != nil {
+		return "", 0, err
+	}
+	commentID, err := strconv.ParseInt(rawCommentID, 10, 64)
+	if err != nil || commentID <= 0 {
+		return "", 0, fmt.Errorf("%w: %s is invalid", model.ErrInvalidParameter, defenitions.ParamCommentID)
+	}
+
+	return model.ViolationID(violationID), model.CommentID(commentID), nil
+}
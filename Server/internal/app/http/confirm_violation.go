@@ -0,0 +1,59 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/inzarubin80/Server/internal/app/defenitions"
+	"github.com/inzarubin80/Server/internal/app/uhttp"
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+type (
+	confirmViolationService interface {
+		ConfirmViolation(ctx context.Context, userID model.UserID, violationID model.ViolationID) (*model.Violation, error)
+	}
+
+	ConfirmViolationHandler struct {
+		name    string
+		service confirmViolationService
+	}
+)
+
+func NewConfirmViolationHandler(service confirmViolationService, name string) *ConfirmViolationHandler {
+	return &ConfirmViolationHandler{
+		name:    name,
+		service: service,
+	}
+}
+
+func (h *ConfirmViolationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, ok := ctx.Value(defenitions.UserID).(model.UserID)
+	if !ok {
+		uhttp.SendErrorResponse(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	violationID, err := uhttp.ValidatePatchStringParameter(r, defenitions.ParamViolationID)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	v, err := h.service.ConfirmViolation(ctx, userID, model.ViolationID(violationID))
+	if err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
+	resp, err := json.Marshal(v)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	uhttp.SendSuccessfulResponse(w, resp)
+}
@@ -3,6 +3,7 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/gorilla/sessions"
@@ -13,7 +14,23 @@ import (
 
 type (
 	createViolationService interface {
-		CreateViolation(ctx context.Context, userID model.UserID, vType model.ViolationType, description string, lat, lng float64) (*model.Violation, error)
+		CreateViolation(ctx context.Context, userID model.UserID, draft *model.ViolationDraft) (*model.ViolationSubmission, error)
+	}
+
+	// createViolationResponse is the violation, flagged when the report was merged
+	// into an existing one or had already been created by an earlier request.
+	createViolationResponse struct {
+		*model.Violation
+		Merged   bool `json:"merged,omitempty"`
+		Replayed bool `json:"replayed,omitempty"`
+	}
+
+	// duplicatesResponse is the 409 body listing reports the new one may duplicate.
+	// The client either confirms one of them or resubmits with ignore_duplicates.
+	duplicatesResponse struct {
+		Error      bool
+		Message    string
+		Duplicates []*model.DuplicateCandidate `json:"duplicates"`
 	}
 
 	CreateViolationHandler struct {
@@ -35,10 +52,15 @@ func (h *CreateViolationHandler) ServeHTTP(w http.ResponseWriter, r *http.Reques
 	ctx := r.Context()
 
 	var req struct {
-		Type        string  `json:"type"`
-		Description string  `json:"description"`
-		Lat         float64 `json:"lat"`
-		Lng         float64 `json:"lng"`
+		// ID is the client-side UUID of a report queued offline.
+		ID          string   `json:"id"`
+		Type        string   `json:"type"`
+		Description string   `json:"description"`
+		Lat         float64  `json:"lat"`
+		Lng         float64  `json:"lng"`
+		Photos      []string `json:"photos"`
+		// IgnoreDuplicates creates the report even if it duplicates a nearby one.
+		IgnoreDuplicates bool `json:"ignore_duplicates"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -52,13 +74,34 @@ func (h *CreateViolationHandler) ServeHTTP(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	v, err := h.service.CreateViolation(ctx, userID, model.ViolationType(req.Type), req.Description, req.Lat, req.Lng)
+	submission, err := h.service.CreateViolation(ctx, userID, &model.ViolationDraft{
+		ID:               model.ViolationID(req.ID),
+		IdempotencyKey:   r.Header.Get(defenitions.HeaderIdempotencyKey),
+		Type:             model.ViolationType(req.Type),
+		Description:      req.Description,
+		Lat:              req.Lat,
+		Lng:              req.Lng,
+		PhotoKeys:        req.Photos,
+		IgnoreDuplicates: req.IgnoreDuplicates,
+	})
 	if err != nil {
+		var duplicates *model.DuplicatesError
+		if errors.As(err, &duplicates) {
+			jsonData, _ := json.Marshal(duplicatesResponse{Error: true, Message: err.Error(), Duplicates: duplicates.Candidates})
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			w.Write(jsonData)
+			return
+		}
+		if errors.Is(err, model.ErrConflict) || errors.Is(err, model.ErrorNotFound) {
+			uhttp.SendServiceErrorResponse(w, err)
+			return
+		}
 		uhttp.SendErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	resp, err := json.Marshal(v)
+	resp, err := json.Marshal(createViolationResponse{Violation: submission.Violation, Merged: submission.Merged, Replayed: submission.Replayed})
 	if err != nil {
 		uhttp.SendErrorResponse(w, http.StatusInternalServerError, err.Error())
 		return
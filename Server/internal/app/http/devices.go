@@ -0,0 +1,100 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/inzarubin80/Server/internal/app/defenitions"
+	"github.com/inzarubin80/Server/internal/app/uhttp"
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+type (
+	devicesService interface {
+		RegisterDevice(ctx context.Context, userID model.UserID, platform string, token string) (*model.Device, error)
+		UnregisterDevice(ctx context.Context, userID model.UserID, token string) error
+	}
+
+	// RegisterDeviceHandler subscribes a device of the user to push notifications.
+	RegisterDeviceHandler struct {
+		name    string
+		service devicesService
+	}
+
+	// UnregisterDeviceHandler unsubscribes a device, e.g. on logout.
+	UnregisterDeviceHandler struct {
+		name    string
+		service devicesService
+	}
+)
+
+func NewRegisterDeviceHandler(service devicesService, name string) *RegisterDeviceHandler {
+	return &RegisterDeviceHandler{
+		name:    name,
+		service: service,
+	}
+}
+
+func (h *RegisterDeviceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, ok := ctx.Value(defenitions.UserID).(model.UserID)
+	if !ok {
+		uhttp.SendErrorResponse(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req struct {
+		Token    string `json:"token"`
+		Platform string `json:"platform"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	device, err := h.service.RegisterDevice(ctx, userID, req.Platform, req.Token)
+	if err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
+	resp, err := json.Marshal(device)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	uhttp.SendSuccessfulResponse(w, resp)
+}
+
+func NewUnregisterDeviceHandler(service devicesService, name string) *UnregisterDeviceHandler {
+	return &UnregisterDeviceHandler{
+		name:    name,
+		service: service,
+	}
+}
+
+func (h *UnregisterDeviceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, ok := ctx.Value(defenitions.UserID).(model.UserID)
+	if !ok {
+		uhttp.SendErrorResponse(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	token, err := uhttp.ValidatePatchStringParameter(r, defenitions.ParamDeviceToken)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.service.UnregisterDevice(ctx, userID, token); err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
+	uhttp.SendSuccessfulResponse(w, []byte("{}"))
+}
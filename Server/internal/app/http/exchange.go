@@ -13,7 +13,7 @@ import (
 
 type (
 	serviceLogin interface {
-		Login(ctx context.Context, providerKey string, authorizationCode string, codeVerifier string) (*model.AuthData, error)
+		Login(ctx context.Context, providerKey string, authorizationCode string, codeVerifier string, client model.ClientInfo) (*model.AuthData, error)
 	}
 
 	ExchangeHandler struct {
@@ -50,7 +50,7 @@ func (h *ExchangeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	authData, err := h.service.Login(r.Context(), req.Provider, req.Code, req.CodeVerifier)
+	authData, err := h.service.Login(r.Context(), req.Provider, req.Code, req.CodeVerifier, uhttp.ClientInfo(r))
 	if err != nil {
 		uhttp.SendErrorResponse(w, http.StatusUnauthorized, err.Error())
 		return
@@ -72,11 +72,14 @@ func (h *ExchangeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	type response struct {
 		Token  string      `json:"token"`
 		UserID model.UserID `json:"user_id"`
+		// RefreshToken is for clients that don't keep cookies; see RefreshTokenHandler.
+		RefreshToken string `json:"refresh_token"`
 	}
 
 	resp := response{
-		Token:  authData.AccessToken,
-		UserID: authData.UserID,
+		Token:        authData.AccessToken,
+		UserID:       authData.UserID,
+		RefreshToken: authData.RefreshToken,
 	}
 
 	jsonData, err := json.Marshal(resp)
@@ -0,0 +1,107 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/inzarubin80/Server/internal/app/uhttp"
+	"github.com/inzarubin80/Server/internal/export"
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+const (
+	paramFormat = "format"
+	// exportFlushEvery is the number of rows after which the response is flushed
+	// to the client.
+	exportFlushEvery = 500
+)
+
+type (
+	exportViolationsService interface {
+		ExportViolations(ctx context.Context, filter *model.ViolationFilter, fn func(*model.ExportedViolation) error) error
+	}
+
+	// ExportViolationsHandler streams the violations matching the list filters
+	// as a GeoJSON, CSV or KML download.
+	ExportViolationsHandler struct {
+		name    string
+		service exportViolationsService
+	}
+)
+
+func NewExportViolationsHandler(service exportViolationsService, name string) *ExportViolationsHandler {
+	return &ExportViolationsHandler{
+		name:    name,
+		service: service,
+	}
+}
+
+func (h *ExportViolationsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	query := r.URL.Query()
+
+	formatName := query.Get(paramFormat)
+	if formatName == "" {
+		formatName = export.FormatGeoJSON
+	}
+	format, ok := export.Lookup(formatName)
+	if !ok {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("%v: %s must be geojson, csv or kml", model.ErrInvalidParameter, paramFormat))
+		return
+	}
+
+	filter, err := parseViolationFilter(query)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writer, err := export.NewWriter(formatName, w)
+	if err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
+	// The headers are sent with the first row, so that errors raised before any
+	// row is read can still be reported with a proper status.
+	rows := 0
+	start := func() {
+		w.Header().Set("Content-Type", format.ContentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="violations-%s.%s"`, time.Now().UTC().Format("20060102"), format.Extension))
+		w.WriteHeader(http.StatusOK)
+	}
+	flusher, _ := w.(http.Flusher)
+
+	err = h.service.ExportViolations(r.Context(), filter, func(v *model.ExportedViolation) error {
+		if rows == 0 {
+			start()
+		}
+		rows++
+		if err := writer.Write(v); err != nil {
+			return err
+		}
+		if flusher != nil && rows%exportFlushEvery == 0 {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		if rows == 0 {
+			uhttp.SendServiceErrorResponse(w, err)
+			return
+		}
+		// The status line is gone; an incomplete document is all the client can get.
+		log.Printf("export: aborted after %d rows: %v", rows, err)
+		return
+	}
+
+	if rows == 0 {
+		start()
+	}
+	if err := writer.Close(); err != nil {
+		log.Printf("export: finish document: %v", err)
+	}
+}
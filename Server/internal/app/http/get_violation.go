@@ -0,0 +1,57 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/inzarubin80/Server/internal/app/defenitions"
+	"github.com/inzarubin80/Server/internal/app/uhttp"
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+type (
+	getViolationService interface {
+		GetViolation(ctx context.Context, violationID model.ViolationID, viewer model.Viewer) (*model.ViolationDetails, error)
+	}
+
+	GetViolationHandler struct {
+		name    string
+		service getViolationService
+	}
+)
+
+func NewGetViolationHandler(service getViolationService, name string) *GetViolationHandler {
+	return &GetViolationHandler{
+		name:    name,
+		service: service,
+	}
+}
+
+func (h *GetViolationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	violationID, err := uhttp.ValidatePatchStringParameter(r, defenitions.ParamViolationID)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// The route is public; the viewer is set when the request carries a token.
+	var viewer model.Viewer
+	viewer.UserID, _ = r.Context().Value(defenitions.UserID).(model.UserID)
+	viewer.Role, _ = r.Context().Value(defenitions.Role).(model.Role)
+
+	details, err := h.service.GetViolation(r.Context(), model.ViolationID(violationID), viewer)
+	if err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
+	jsonData, err := json.Marshal(details)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	uhttp.SendSuccessfulResponse(w, jsonData)
+}
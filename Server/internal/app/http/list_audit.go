@@ -0,0 +1,98 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/inzarubin80/Server/internal/app/defenitions"
+	"github.com/inzarubin80/Server/internal/app/uhttp"
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+type (
+	listAuditService interface {
+		ListAuditLogs(ctx context.Context, filter *model.AuditFilter, cursor string) (*model.AuditPage, error)
+	}
+
+	// ListAuditHandler returns audit entries filtered by actor_id, action,
+	// entity_type, entity_id, from and to. It is an admin route.
+	ListAuditHandler struct {
+		name    string
+		service listAuditService
+	}
+)
+
+func NewListAuditHandler(service listAuditService, name string) *ListAuditHandler {
+	return &ListAuditHandler{
+		name:    name,
+		service: service,
+	}
+}
+
+func (h *ListAuditHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	query := r.URL.Query()
+
+	filter, err := parseAuditFilter(query)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := h.service.ListAuditLogs(r.Context(), filter, query.Get(defenitions.Cursor))
+	if err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
+	jsonData, err := json.Marshal(page)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	uhttp.SendSuccessfulResponse(w, jsonData)
+}
+
+func parseAuditFilter(query url.Values) (*model.AuditFilter, error) {
+
+	filter := &model.AuditFilter{
+		Action:     query.Get("action"),
+		EntityType: query.Get("entity_type"),
+		EntityID:   query.Get("entity_id"),
+	}
+
+	if raw := query.Get("actor_id"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid actor_id")
+		}
+		actorID := model.UserID(id)
+		filter.ActorID = &actorID
+	}
+
+	for param, dst := range map[string]**time.Time{defenitions.ParamFrom: &filter.CreatedFrom, defenitions.ParamTo: &filter.CreatedTo} {
+		if raw := query.Get(param); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: expected RFC 3339 time", param)
+			}
+			*dst = &t
+		}
+	}
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("invalid limit")
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,136 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inzarubin80/Server/internal/app/defenitions"
+	"github.com/inzarubin80/Server/internal/app/uhttp"
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+type (
+	listViolationsService interface {
+		ListViolations(ctx context.Context, filter *model.ViolationFilter) (*model.ViolationsPage, error)
+	}
+
+	ListViolationsHandler struct {
+		name    string
+		service listViolationsService
+	}
+)
+
+func NewListViolationsHandler(service listViolationsService, name string) *ListViolationsHandler {
+	return &ListViolationsHandler{
+		name:    name,
+		service: service,
+	}
+}
+
+func (h *ListViolationsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	filter, err := parseViolationFilter(r.URL.Query())
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := h.service.ListViolations(r.Context(), filter)
+	if err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
+	jsonData, err := json.Marshal(page)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	uhttp.SendSuccessfulResponse(w, jsonData)
+}
+
+// parseViolationFilter reads the list filters from the query string:
+// bbox=minLng,minLat,maxLng,maxLat, comma separated type and status lists,
+// from/to as RFC3339 timestamps, cursor and page_size.
+func parseViolationFilter(q url.Values) (*model.ViolationFilter, error) {
+
+	filter := &model.ViolationFilter{}
+
+	if v := q.Get(defenitions.ParamBBox); v != "" {
+		parts := strings.Split(v, ",")
+		if len(parts) != 4 {
+			return nil, fmt.Errorf("%w: %s must be minLng,minLat,maxLng,maxLat", model.ErrInvalidParameter, defenitions.ParamBBox)
+		}
+		var coords [4]float64
+		for i, p := range parts {
+			c, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s is invalid", model.ErrInvalidParameter, defenitions.ParamBBox)
+			}
+			coords[i] = c
+		}
+		filter.BBox = &model.BBox{MinLng: coords[0], MinLat: coords[1], MaxLng: coords[2], MaxLat: coords[3]}
+	}
+
+	for _, t := range splitList(q.Get(defenitions.ParamType)) {
+		filter.Types = append(filter.Types, model.ViolationType(t))
+	}
+
+	for _, s := range splitList(q.Get(defenitions.ParamStatus)) {
+		filter.Statuses = append(filter.Statuses, model.ViolationStatus(s))
+	}
+
+	if v := q.Get(defenitions.ParamFrom); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s is invalid", model.ErrInvalidParameter, defenitions.ParamFrom)
+		}
+		filter.CreatedFrom = &t
+	}
+
+	if v := q.Get(defenitions.ParamTo); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s is invalid", model.ErrInvalidParameter, defenitions.ParamTo)
+		}
+		filter.CreatedTo = &t
+	}
+
+	if v := q.Get(defenitions.Cursor); v != "" {
+		cursor, err := model.ParseViolationCursor(v)
+		if err != nil {
+			return nil, err
+		}
+		filter.Cursor = cursor
+	}
+
+	if v := q.Get(defenitions.PageSize); v != "" {
+		pageSize, err := strconv.Atoi(v)
+		if err != nil || pageSize <= 0 {
+			return nil, fmt.Errorf("%w: %s is invalid", model.ErrInvalidParameter, defenitions.PageSize)
+		}
+		filter.Limit = pageSize
+	}
+
+	return filter, nil
+}
+
+func splitList(v string) []string {
+	if v == "" {
+		return nil
+	}
+	var res []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			res = append(res, item)
+		}
+	}
+	return res
+}
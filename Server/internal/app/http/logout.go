@@ -1,38 +1,67 @@
 package http
 
 import (
+	"context"
+	"errors"
 	"net/http"
 
 	"github.com/gorilla/sessions"
 	"github.com/inzarubin80/Server/internal/app/defenitions"
 	"github.com/inzarubin80/Server/internal/app/uhttp"
+	"github.com/inzarubin80/Server/internal/model"
 )
 
 type (
+	serviceLogout interface {
+		Logout(ctx context.Context, refreshToken string) error
+	}
+
 	LogOutHandler struct {
-		name  string
-		store *sessions.CookieStore
+		name    string
+		store   *sessions.CookieStore
+		service serviceLogout
 	}
 )
 
-func NewLogOutHandlerHandler( name string, store *sessions.CookieStore) *LogOutHandler {
+func NewLogOutHandlerHandler(name string, store *sessions.CookieStore, service serviceLogout) *LogOutHandler {
 	return &LogOutHandler{
-		name:  name,
-		store: store,
+		name:    name,
+		store:   store,
+		service: service,
 	}
 }
 
+// ServeHTTP revokes the session of the refresh token (from the body or the cookie)
+// and deletes the cookie.
 func (h *LogOutHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
+	token, _, err := refreshTokenFromRequest(r, h.store)
+	switch {
+	case err == nil:
+		if err := h.service.Logout(r.Context(), token); err != nil {
+			uhttp.SendServiceErrorResponse(w, err)
+			return
+		}
+	case !errors.Is(err, model.ErrUnauthorized):
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
 	session, err := h.store.Get(r, defenitions.SessionAuthenticationName)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if session == nil {
+		uhttp.SendErrorResponse(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
 	for k := range session.Values {
 		delete(session.Values, k)
 	}
+	session.Options.MaxAge = -1
+
+	if err := session.Save(r, w); err != nil {
+		uhttp.SendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
 
 	uhttp.SendSuccessfulResponse(w, []byte("{}"))
 
@@ -16,6 +16,8 @@ type (
 		h       http.Handler
 		store   *sessions.CookieStore
 		service serviceAuth
+		// optional lets requests without a token through anonymously.
+		optional bool
 	}
 
 	serviceAuth interface {
@@ -27,6 +29,13 @@ func NewAuthMiddleware(h http.Handler, store *sessions.CookieStore, service serv
 	return &AuthMiddleware{h: h, store: store, service: service}
 }
 
+// NewOptionalAuthMiddleware is NewAuthMiddleware for public routes whose response
+// depends on the user: requests without a token are served anonymously, requests
+// with an invalid token are still rejected.
+func NewOptionalAuthMiddleware(h http.Handler, store *sessions.CookieStore, service serviceAuth) *AuthMiddleware {
+	return &AuthMiddleware{h: h, store: store, service: service, optional: true}
+}
+
 func (m *AuthMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
@@ -35,6 +44,11 @@ func (m *AuthMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	accessToken, err = m.extractTokenFromHeader(r)
 
+	if err != nil && m.optional && r.Header.Get("Authorization") == "" {
+		m.h.ServeHTTP(w, r)
+		return
+	}
+
 	if err != nil {
 		http.Error(w, "Unauthorized not access token", http.StatusUnauthorized)
 		return
@@ -47,6 +61,8 @@ func (m *AuthMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx = context.WithValue(ctx, defenitions.UserID, claims.UserID)
+	ctx = context.WithValue(ctx, defenitions.SessionID, claims.SessionID)
+	ctx = context.WithValue(ctx, defenitions.Role, claims.Role)
 	newRequest := r.WithContext(ctx)
 	m.h.ServeHTTP(w, newRequest)
 
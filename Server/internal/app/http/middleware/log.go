@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httputil"
+	"strings"
 )
 
 type LogMux struct {
@@ -18,7 +19,9 @@ func NewLogMux(h http.Handler) http.Handler {
 
 func (m *LogMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
-	dumpR, err := httputil.DumpRequest(r, true)
+	// Photo uploads go straight through the local storage handler; do not print binary bodies.
+	withBody := !strings.HasPrefix(r.Header.Get("Content-Type"), "image/")
+	dumpR, err := httputil.DumpRequest(r, withBody)
 
 	if err != nil {
 		fmt.Println("Failed to dump request", err.Error())
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"net/netip"
+
+	"github.com/google/uuid"
+	"github.com/inzarubin80/Server/internal/app/uhttp"
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+// RequestIDHeader carries the request id; an id sent by a proxy is kept.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds ids accepted from clients.
+const maxRequestIDLength = 128
+
+type RequestID struct {
+	h              http.Handler
+	trustedProxies []netip.Prefix
+}
+
+// NewRequestID puts the request id and client address into the request context,
+// where the audit log picks them up, and echoes the id in the response. The
+// address is taken from X-Forwarded-For only behind one of trustedProxies.
+func NewRequestID(h http.Handler, trustedProxies []netip.Prefix) http.Handler {
+	return &RequestID{h: h, trustedProxies: trustedProxies}
+}
+
+func (m *RequestID) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	id := r.Header.Get(RequestIDHeader)
+	if id == "" || len(id) > maxRequestIDLength {
+		id = uuid.NewString()
+	}
+	w.Header().Set(RequestIDHeader, id)
+
+	ctx := model.WithRequestMeta(r.Context(), model.RequestMeta{
+		RequestID: id,
+		IP:        uhttp.ClientIP(r, m.trustedProxies),
+	})
+
+	m.h.ServeHTTP(w, r.WithContext(ctx))
+}
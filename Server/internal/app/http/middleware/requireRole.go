@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/inzarubin80/Server/internal/app/defenitions"
+	"github.com/inzarubin80/Server/internal/app/uhttp"
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+type (
+	// RequireRole rejects requests of users below minRole. It reads the role put
+	// into the context by AuthMiddleware, so it must be wrapped by it:
+	//
+	//	NewAuthMiddleware(NewRequireRole(handler, model.RoleModerator), store, service)
+	RequireRole struct {
+		h       http.Handler
+		minRole model.Role
+	}
+)
+
+func NewRequireRole(h http.Handler, minRole model.Role) *RequireRole {
+	return &RequireRole{h: h, minRole: minRole}
+}
+
+func (m *RequireRole) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	role, ok := r.Context().Value(defenitions.Role).(model.Role)
+	if !ok {
+		uhttp.SendErrorResponse(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if !role.AtLeast(m.minRole) {
+		uhttp.SendErrorResponse(w, http.StatusForbidden, "forbidden")
+		return
+	}
+
+	m.h.ServeHTTP(w, r)
+}
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/inzarubin80/Server/internal/app/defenitions"
+	"github.com/inzarubin80/Server/internal/app/uhttp"
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+type (
+	moderationActionService interface {
+		ModerateViolation(ctx context.Context, moderatorID model.UserID, violationID model.ViolationID, action string, reason string) (*model.Violation, error)
+	}
+
+	// ModerationActionHandler approves or rejects a report. It is a moderator route.
+	ModerationActionHandler struct {
+		name    string
+		service moderationActionService
+	}
+)
+
+func NewModerationActionHandler(service moderationActionService, name string) *ModerationActionHandler {
+	return &ModerationActionHandler{
+		name:    name,
+		service: service,
+	}
+}
+
+func (h *ModerationActionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	moderatorID, ok := ctx.Value(defenitions.UserID).(model.UserID)
+	if !ok {
+		uhttp.SendErrorResponse(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	violationID, err := uhttp.ValidatePatchStringParameter(r, defenitions.ParamViolationID)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req struct {
+		Action string `json:"action"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	v, err := h.service.ModerateViolation(ctx, moderatorID, model.ViolationID(violationID), req.Action, req.Reason)
+	if err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
+	resp, err := json.Marshal(model.NewModeratedViolation(v))
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	uhttp.SendSuccessfulResponse(w, resp)
+}
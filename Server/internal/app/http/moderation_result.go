@@ -0,0 +1,74 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+
+	"github.com/inzarubin80/Server/internal/app/uhttp"
+	"github.com/inzarubin80/Server/internal/model"
+	"github.com/inzarubin80/Server/internal/moderation"
+)
+
+type (
+	moderationResultService interface {
+		ApplyModerationResult(ctx context.Context, result *model.ModerationResult) (*model.Violation, error)
+		ApplyCommentModerationResult(ctx context.Context, result *model.ModerationResult) (*model.Comment, error)
+	}
+
+	// ModerationResultHandler receives the asynchronous results of the moderation
+	// service, for reports and, when comment_id is set, for comments. It is an
+	// internal route authenticated by the shared secret.
+	ModerationResultHandler struct {
+		name    string
+		service moderationResultService
+		secret  string
+	}
+)
+
+func NewModerationResultHandler(service moderationResultService, name string, secret string) *ModerationResultHandler {
+	return &ModerationResultHandler{
+		name:    name,
+		service: service,
+		secret:  secret,
+	}
+}
+
+func (h *ModerationResultHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	// Without a configured secret the route is closed.
+	provided := r.Header.Get(moderation.SecretHeader)
+	if h.secret == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(h.secret)) != 1 {
+		uhttp.SendErrorResponse(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var result model.ModerationResult
+	if err := json.NewDecoder(r.Body).Decode(&result); err != nil {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	var (
+		updated any
+		err     error
+	)
+	if result.CommentID != 0 {
+		updated, err = h.service.ApplyCommentModerationResult(r.Context(), &result)
+	} else {
+		updated, err = h.service.ApplyModerationResult(r.Context(), &result)
+	}
+	if err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
+	resp, err := json.Marshal(updated)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	uhttp.SendSuccessfulResponse(w, resp)
+}
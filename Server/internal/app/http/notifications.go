@@ -0,0 +1,196 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/inzarubin80/Server/internal/app/defenitions"
+	"github.com/inzarubin80/Server/internal/app/uhttp"
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+type (
+	notificationsService interface {
+		ListNotifications(ctx context.Context, userID model.UserID, unreadOnly bool, cursor string, limit int) (*model.NotificationPage, error)
+		UnreadNotificationsCount(ctx context.Context, userID model.UserID) (int, error)
+		MarkNotificationRead(ctx context.Context, userID model.UserID, id model.NotificationID) (int, error)
+		MarkAllNotificationsRead(ctx context.Context, userID model.UserID) (int, error)
+	}
+
+	// ListNotificationsHandler lists the user's inbox; ?unread=true lists only
+	// unread notifications.
+	ListNotificationsHandler struct {
+		name    string
+		service notificationsService
+	}
+
+	// UnreadNotificationsHandler returns the unread badge count.
+	UnreadNotificationsHandler struct {
+		name    string
+		service notificationsService
+	}
+
+	// ReadNotificationHandler marks one notification as read.
+	ReadNotificationHandler struct {
+		name    string
+		service notificationsService
+	}
+
+	// ReadAllNotificationsHandler marks the whole inbox as read.
+	ReadAllNotificationsHandler struct {
+		name    string
+		service notificationsService
+	}
+
+	unreadCountResponse struct {
+		UnreadCount int `json:"unread_count"`
+	}
+)
+
+func NewListNotificationsHandler(service notificationsService, name string) *ListNotificationsHandler {
+	return &ListNotificationsHandler{
+		name:    name,
+		service: service,
+	}
+}
+
+func (h *ListNotificationsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, ok := ctx.Value(defenitions.UserID).(model.UserID)
+	if !ok {
+		uhttp.SendErrorResponse(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	query := r.URL.Query()
+
+	var unreadOnly bool
+	if raw := query.Get("unread"); raw != "" {
+		var err error
+		if unreadOnly, err = strconv.ParseBool(raw); err != nil {
+			uhttp.SendErrorResponse(w, http.StatusBadRequest, "invalid unread")
+			return
+		}
+	}
+
+	var limit int
+	if raw := query.Get("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil || limit <= 0 {
+			uhttp.SendErrorResponse(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+	}
+
+	page, err := h.service.ListNotifications(ctx, userID, unreadOnly, query.Get(defenitions.Cursor), limit)
+	if err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
+	resp, err := json.Marshal(page)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	uhttp.SendSuccessfulResponse(w, resp)
+}
+
+func NewUnreadNotificationsHandler(service notificationsService, name string) *UnreadNotificationsHandler {
+	return &UnreadNotificationsHandler{
+		name:    name,
+		service: service,
+	}
+}
+
+func (h *UnreadNotificationsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, ok := ctx.Value(defenitions.UserID).(model.UserID)
+	if !ok {
+		uhttp.SendErrorResponse(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	count, err := h.service.UnreadNotificationsCount(ctx, userID)
+	if err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
+	sendUnreadCount(w, count)
+}
+
+func NewReadNotificationHandler(service notificationsService, name string) *ReadNotificationHandler {
+	return &ReadNotificationHandler{
+		name:    name,
+		service: service,
+	}
+}
+
+func (h *ReadNotificationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, ok := ctx.Value(defenitions.UserID).(model.UserID)
+	if !ok {
+		uhttp.SendErrorResponse(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	params, err := uhttp.ValidatePatchNumberParameters(r, []uhttp.ValidateParameter{
+		{Fild: defenitions.ParamNotificationID, Min: 1, Max: math.MaxInt64},
+	})
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	id := params[defenitions.ParamNotificationID]
+
+	count, err := h.service.MarkNotificationRead(ctx, userID, model.NotificationID(id))
+	if err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
+	sendUnreadCount(w, count)
+}
+
+func NewReadAllNotificationsHandler(service notificationsService, name string) *ReadAllNotificationsHandler {
+	return &ReadAllNotificationsHandler{
+		name:    name,
+		service: service,
+	}
+}
+
+func (h *ReadAllNotificationsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, ok := ctx.Value(defenitions.UserID).(model.UserID)
+	if !ok {
+		uhttp.SendErrorResponse(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if _, err := h.service.MarkAllNotificationsRead(ctx, userID); err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
+	sendUnreadCount(w, 0)
+}
+
+func sendUnreadCount(w http.ResponseWriter, count int) {
+
+	resp, err := json.Marshal(unreadCountResponse{UnreadCount: count})
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	uhttp.SendSuccessfulResponse(w, resp)
+}
@@ -0,0 +1,113 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/inzarubin80/Server/internal/app/defenitions"
+	"github.com/inzarubin80/Server/internal/app/uhttp"
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+type (
+	outboxService interface {
+		ListOutboxMessages(ctx context.Context, filter *model.OutboxFilter, cursor string) (*model.OutboxPage, error)
+		ReplayOutboxMessage(ctx context.Context, actorID model.UserID, id int64) (*model.OutboxMessage, error)
+	}
+
+	// ListOutboxHandler returns outbox messages filtered by status and topic,
+	// e.g. ?status=dead for the dead letters. It is an admin route.
+	ListOutboxHandler struct {
+		name    string
+		service outboxService
+	}
+
+	// ReplayOutboxHandler queues a dead-lettered message again. It is an admin route.
+	ReplayOutboxHandler struct {
+		name    string
+		service outboxService
+	}
+)
+
+func NewListOutboxHandler(service outboxService, name string) *ListOutboxHandler {
+	return &ListOutboxHandler{
+		name:    name,
+		service: service,
+	}
+}
+
+func (h *ListOutboxHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	query := r.URL.Query()
+
+	filter := &model.OutboxFilter{
+		Status: query.Get("status"),
+		Topic:  query.Get("topic"),
+	}
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			uhttp.SendErrorResponse(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	page, err := h.service.ListOutboxMessages(r.Context(), filter, query.Get(defenitions.Cursor))
+	if err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
+	resp, err := json.Marshal(page)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	uhttp.SendSuccessfulResponse(w, resp)
+}
+
+func NewReplayOutboxHandler(service outboxService, name string) *ReplayOutboxHandler {
+	return &ReplayOutboxHandler{
+		name:    name,
+		service: service,
+	}
+}
+
+func (h *ReplayOutboxHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	actorID, ok := ctx.Value(defenitions.UserID).(model.UserID)
+	if !ok {
+		uhttp.SendErrorResponse(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	rawID, err := uhttp.ValidatePatchStringParameter(r, defenitions.ParamOutboxMessageID)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	id, err := strconv.ParseInt(rawID, 10, 64)
+	if err != nil || id <= 0 {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, "invalid message_id")
+		return
+	}
+
+	msg, err := h.service.ReplayOutboxMessage(ctx, actorID, id)
+	if err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
+	resp, err := json.Marshal(msg)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	uhttp.SendSuccessfulResponse(w, resp)
+}
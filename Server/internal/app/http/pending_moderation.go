@@ -0,0 +1,62 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/inzarubin80/Server/internal/app/uhttp"
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+type (
+	pendingModerationService interface {
+		ListPendingModeration(ctx context.Context, limit int) ([]*model.Violation, error)
+	}
+
+	// PendingModerationHandler returns the moderation queue. It is a moderator route.
+	PendingModerationHandler struct {
+		name    string
+		service pendingModerationService
+	}
+)
+
+func NewPendingModerationHandler(service pendingModerationService, name string) *PendingModerationHandler {
+	return &PendingModerationHandler{
+		name:    name,
+		service: service,
+	}
+}
+
+func (h *PendingModerationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	var limit int
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			uhttp.SendErrorResponse(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	items, err := h.service.ListPendingModeration(r.Context(), limit)
+	if err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
+	views := make([]*model.ModeratedViolation, len(items))
+	for i, v := range items {
+		views[i] = model.NewModeratedViolation(v)
+	}
+
+	jsonData, err := json.Marshal(views)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	uhttp.SendSuccessfulResponse(w, jsonData)
+}
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/inzarubin80/Server/internal/app/defenitions"
+	"github.com/inzarubin80/Server/internal/app/uhttp"
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+type (
+	proposeResolveService interface {
+		ProposeResolve(ctx context.Context, userID model.UserID, violationID model.ViolationID, comment string, photoKeys []string) (*model.Violation, error)
+	}
+
+	ProposeResolveHandler struct {
+		name    string
+		service proposeResolveService
+	}
+)
+
+func NewProposeResolveHandler(service proposeResolveService, name string) *ProposeResolveHandler {
+	return &ProposeResolveHandler{
+		name:    name,
+		service: service,
+	}
+}
+
+func (h *ProposeResolveHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req struct {
+		Comment string   `json:"comment"`
+		Photos  []string `json:"photos"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	userID, ok := ctx.Value(defenitions.UserID).(model.UserID)
+	if !ok {
+		uhttp.SendErrorResponse(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	violationID, err := uhttp.ValidatePatchStringParameter(r, defenitions.ParamViolationID)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	v, err := h.service.ProposeResolve(ctx, userID, model.ViolationID(violationID), req.Comment, req.Photos)
+	if err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
+	resp, err := json.Marshal(v)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	uhttp.SendSuccessfulResponse(w, resp)
+}
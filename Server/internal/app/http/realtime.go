@@ -0,0 +1,45 @@
+package http
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/inzarubin80/Server/internal/app/defenitions"
+	"github.com/inzarubin80/Server/internal/app/uhttp"
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+type (
+	realtimeHub interface {
+		ServeWS(w http.ResponseWriter, r *http.Request, userID model.UserID) error
+	}
+
+	// RealtimeHandler upgrades authenticated requests to a WebSocket connection.
+	// Browsers cannot set headers on WebSocket requests, so the access token may
+	// also be passed as the accessToken query parameter.
+	RealtimeHandler struct {
+		name string
+		hub  realtimeHub
+	}
+)
+
+func NewRealtimeHandler(hub realtimeHub, name string) *RealtimeHandler {
+	return &RealtimeHandler{
+		name: name,
+		hub:  hub,
+	}
+}
+
+func (h *RealtimeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	userID, ok := r.Context().Value(defenitions.UserID).(model.UserID)
+	if !ok {
+		uhttp.SendErrorResponse(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	// The upgrader has already written the error response when this fails.
+	if err := h.hub.ServeWS(w, r, userID); err != nil {
+		log.Printf("realtime: user %d: %v", userID, err)
+	}
+}
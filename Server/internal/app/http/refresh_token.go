@@ -2,18 +2,19 @@ package http
 
 import (
 	"context"
-	//"encoding/json"
+	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/gorilla/sessions"
 	"github.com/inzarubin80/Server/internal/app/defenitions"
-	//"github.com/inzarubin80/Server/internal/app/uhttp"
+	"github.com/inzarubin80/Server/internal/app/uhttp"
 	"github.com/inzarubin80/Server/internal/model"
 )
 
 type (
 	serviceRefreshToken interface {
-		RefreshToken(ctx context.Context, refreshToken string) (*model.AuthData, error)
+		RefreshToken(ctx context.Context, refreshToken string, client model.ClientInfo) (*model.AuthData, error)
 	}
 
 	RefreshTokenHandler struct {
@@ -31,48 +32,84 @@ func NewRefreshTokenHandler(service serviceRefreshToken, name string, store *ses
 	}
 }
 
+// ServeHTTP rotates the refresh token. Browsers send it in the session cookie;
+// mobile clients that don't keep cookies send {"refresh_token": "..."} and get
+// the new refresh token back in the response body.
 func (h *RefreshTokenHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 
-	session, err := h.store.Get(r, defenitions.SessionAuthenticationName)
-	if err != nil {
-		http.Error(w, "Unauthorized not session", http.StatusUnauthorized)
-		return
+	type response struct {
+		Token        string       `json:"token"`
+		UserID       model.UserID `json:"user_id"`
+		RefreshToken string       `json:"refresh_token,omitempty"`
 	}
 
-	tokenString, ok := session.Values[defenitions.Token].(string)
-	if !ok {
-		http.Error(w, "Unauthorized not Token", http.StatusUnauthorized)
+	tokenString, session, err := refreshTokenFromRequest(r, h.store)
+	if err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
 		return
 	}
+	fromBody := session == nil
 
-	authData, err := h.service.RefreshToken(ctx, tokenString)
+	authData, err := h.service.RefreshToken(ctx, tokenString, uhttp.ClientInfo(r))
 	if err != nil {
-		http.Error(w, "Unauthorized not session", http.StatusUnauthorized)
+		uhttp.SendServiceErrorResponse(w, err)
 		return
 	}
 
-	session.Values[defenitions.Token] = string(authData.RefreshToken)
-	err = session.Save(r, w)
+	resp := response{
+		Token:  authData.AccessToken,
+		UserID: authData.UserID,
+	}
+
+	if fromBody {
+		resp.RefreshToken = authData.RefreshToken
+	} else {
+		session.Values[defenitions.Token] = authData.RefreshToken
+		if err := session.Save(r, w); err != nil {
+			uhttp.SendErrorResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
 
+	jsonData, err := json.Marshal(resp)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		uhttp.SendErrorResponse(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	/*
-	responseLoginData := &ResponseLoginData{
-		Token:  authData.AccessToken,
-		UserID: authData.UserID,
+	uhttp.SendSuccessfulResponse(w, jsonData)
+}
+
+// refreshTokenFromRequest returns the refresh token sent as {"refresh_token": "..."}
+// or, when the body has none, the one kept in the cookie session. session is nil
+// when the token came from the body.
+func refreshTokenFromRequest(r *http.Request, store *sessions.CookieStore) (string, *sessions.Session, error) {
+
+	type request struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	var req request
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return "", nil, fmt.Errorf("%w: invalid json", model.ErrInvalidParameter)
+		}
+	}
+	if req.RefreshToken != "" {
+		return req.RefreshToken, nil, nil
 	}
 
-	jsonResponseLoginData, err := json.Marshal(responseLoginData)
+	session, err := store.Get(r, defenitions.SessionAuthenticationName)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return "", nil, fmt.Errorf("%w: not session", model.ErrUnauthorized)
+	}
+
+	token, ok := session.Values[defenitions.Token].(string)
+	if !ok {
+		return "", nil, fmt.Errorf("%w: not Token", model.ErrUnauthorized)
 	}
-	uhttp.SendSuccessfulResponse(w, jsonResponseLoginData)
-	*/
 
+	return token, session, nil
 }
@@ -0,0 +1,123 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/inzarubin80/Server/internal/app/defenitions"
+	"github.com/inzarubin80/Server/internal/app/uhttp"
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+type (
+	sessionsService interface {
+		ListSessions(ctx context.Context, userID model.UserID, currentSessionID string) ([]*model.DeviceSession, error)
+		RevokeSession(ctx context.Context, userID model.UserID, sessionID string) error
+		RevokeAllSessions(ctx context.Context, userID model.UserID) error
+	}
+
+	// ListSessionsHandler lists the devices the user is logged in on.
+	ListSessionsHandler struct {
+		name    string
+		service sessionsService
+	}
+
+	// RevokeSessionHandler logs one device out.
+	RevokeSessionHandler struct {
+		name    string
+		service sessionsService
+	}
+
+	// RevokeAllSessionsHandler logs the user out on every device.
+	RevokeAllSessionsHandler struct {
+		name    string
+		service sessionsService
+	}
+)
+
+func NewListSessionsHandler(service sessionsService, name string) *ListSessionsHandler {
+	return &ListSessionsHandler{
+		name:    name,
+		service: service,
+	}
+}
+
+func (h *ListSessionsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, ok := ctx.Value(defenitions.UserID).(model.UserID)
+	if !ok {
+		uhttp.SendErrorResponse(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	currentSessionID, _ := ctx.Value(defenitions.SessionID).(string)
+
+	sessions, err := h.service.ListSessions(ctx, userID, currentSessionID)
+	if err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
+	resp, err := json.Marshal(sessions)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	uhttp.SendSuccessfulResponse(w, resp)
+}
+
+func NewRevokeSessionHandler(service sessionsService, name string) *RevokeSessionHandler {
+	return &RevokeSessionHandler{
+		name:    name,
+		service: service,
+	}
+}
+
+func (h *RevokeSessionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, ok := ctx.Value(defenitions.UserID).(model.UserID)
+	if !ok {
+		uhttp.SendErrorResponse(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	sessionID, err := uhttp.ValidatePatchStringParameter(r, defenitions.ParamSessionID)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.service.RevokeSession(ctx, userID, sessionID); err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
+	uhttp.SendSuccessfulResponse(w, []byte("{}"))
+}
+
+func NewRevokeAllSessionsHandler(service sessionsService, name string) *RevokeAllSessionsHandler {
+	return &RevokeAllSessionsHandler{
+		name:    name,
+		service: service,
+	}
+}
+
+func (h *RevokeAllSessionsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, ok := ctx.Value(defenitions.UserID).(model.UserID)
+	if !ok {
+		uhttp.SendErrorResponse(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if err := h.service.RevokeAllSessions(ctx, userID); err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
+	uhttp.SendSuccessfulResponse(w, []byte("{}"))
+}
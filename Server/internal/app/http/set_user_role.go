@@ -0,0 +1,67 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/inzarubin80/Server/internal/app/defenitions"
+	"github.com/inzarubin80/Server/internal/app/uhttp"
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+type (
+	setUserRoleService interface {
+		SetUserRole(ctx context.Context, actorID model.UserID, userID model.UserID, role model.Role) error
+	}
+
+	// SetUserRoleHandler changes the role of a user. It is an admin route.
+	SetUserRoleHandler struct {
+		name    string
+		service setUserRoleService
+	}
+)
+
+func NewSetUserRoleHandler(service setUserRoleService, name string) *SetUserRoleHandler {
+	return &SetUserRoleHandler{
+		name:    name,
+		service: service,
+	}
+}
+
+func (h *SetUserRoleHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	actorID, ok := ctx.Value(defenitions.UserID).(model.UserID)
+	if !ok {
+		uhttp.SendErrorResponse(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	rawUserID, err := uhttp.ValidatePatchStringParameter(r, defenitions.ParamUserID)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	userID, err := strconv.ParseInt(rawUserID, 10, 64)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, "invalid user_id")
+		return
+	}
+
+	var req struct {
+		Role model.Role `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := h.service.SetUserRole(ctx, actorID, model.UserID(userID), req.Role); err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
+	uhttp.SendSuccessfulResponse(w, []byte("{}"))
+}
@@ -0,0 +1,119 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/inzarubin80/Server/internal/app/defenitions"
+	"github.com/inzarubin80/Server/internal/app/uhttp"
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+type (
+	syncService interface {
+		Sync(ctx context.Context, viewer model.Viewer, ops []*model.SyncOperation) ([]*model.SyncResult, error)
+	}
+
+	syncReport struct {
+		Type             string   `json:"type"`
+		Description      string   `json:"description"`
+		Lat              float64  `json:"lat"`
+		Lng              float64  `json:"lng"`
+		Photos           []string `json:"photos"`
+		IgnoreDuplicates bool     `json:"ignore_duplicates"`
+	}
+
+	syncOperation struct {
+		ID            string     `json:"op_id"`
+		Type          string     `json:"type"`
+		ViolationID   string     `json:"violation_id"`
+		BaseUpdatedAt *time.Time `json:"base_updated_at"`
+		// Report is the new violation of a create operation.
+		Report *syncReport `json:"report"`
+		// Comment and Photos are the evidence of a propose_resolve operation.
+		Comment string   `json:"comment"`
+		Photos  []string `json:"photos"`
+	}
+
+	syncResponse struct {
+		Results []*model.SyncResult `json:"results"`
+	}
+
+	// SyncHandler applies the operations an offline client has queued. Every
+	// operation gets a result; conflicts come with the server copy to keep.
+	SyncHandler struct {
+		name    string
+		service syncService
+	}
+)
+
+func NewSyncHandler(service syncService, name string) *SyncHandler {
+	return &SyncHandler{
+		name:    name,
+		service: service,
+	}
+}
+
+func (h *SyncHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, ok := ctx.Value(defenitions.UserID).(model.UserID)
+	if !ok {
+		uhttp.SendErrorResponse(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	role, _ := ctx.Value(defenitions.Role).(model.Role)
+
+	var req struct {
+		Operations []*syncOperation `json:"operations"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	ops := make([]*model.SyncOperation, len(req.Operations))
+	for i, op := range req.Operations {
+		if op == nil {
+			uhttp.SendErrorResponse(w, http.StatusBadRequest, "invalid operation")
+			return
+		}
+
+		ops[i] = &model.SyncOperation{
+			ID:            op.ID,
+			Type:          model.SyncOperationType(op.Type),
+			ViolationID:   model.ViolationID(op.ViolationID),
+			BaseUpdatedAt: op.BaseUpdatedAt,
+			Comment:       op.Comment,
+			PhotoKeys:     op.Photos,
+		}
+
+		if op.Report != nil {
+			ops[i].Draft = &model.ViolationDraft{
+				Type:             model.ViolationType(op.Report.Type),
+				Description:      op.Report.Description,
+				Lat:              op.Report.Lat,
+				Lng:              op.Report.Lng,
+				PhotoKeys:        op.Report.Photos,
+				IgnoreDuplicates: op.Report.IgnoreDuplicates,
+			}
+		}
+	}
+
+	results, err := h.service.Sync(ctx, model.Viewer{UserID: userID, Role: role}, ops)
+	if err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
+	resp, err := json.Marshal(syncResponse{Results: results})
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	uhttp.SendSuccessfulResponse(w, resp)
+}
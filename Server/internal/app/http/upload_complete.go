@@ -0,0 +1,66 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/inzarubin80/Server/internal/app/defenitions"
+	"github.com/inzarubin80/Server/internal/app/uhttp"
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+type (
+	uploadCompleteService interface {
+		CompleteUpload(ctx context.Context, userID model.UserID, storageKey string) (*model.Photo, error)
+	}
+
+	UploadCompleteHandler struct {
+		name    string
+		service uploadCompleteService
+	}
+)
+
+func NewUploadCompleteHandler(service uploadCompleteService, name string) *UploadCompleteHandler {
+	return &UploadCompleteHandler{
+		name:    name,
+		service: service,
+	}
+}
+
+func (h *UploadCompleteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req struct {
+		StorageKey string `json:"storage_key"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if req.StorageKey == "" {
+		uhttp.SendErrorResponse(w, http.StatusBadRequest, "storage_key is required")
+		return
+	}
+
+	userID, ok := ctx.Value(defenitions.UserID).(model.UserID)
+	if !ok {
+		uhttp.SendErrorResponse(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	photo, err := h.service.CompleteUpload(ctx, userID, req.StorageKey)
+	if err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
+	resp, err := json.Marshal(photo)
+	if err != nil {
+		uhttp.SendErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	uhttp.SendSuccessfulResponse(w, resp)
+}
@@ -0,0 +1,78 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inzarubin80/Server/internal/app/defenitions"
+	"github.com/inzarubin80/Server/internal/app/uhttp"
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+const (
+	mvtContentType = "application/vnd.mapbox-vector-tile"
+	mvtExtension   = ".mvt"
+)
+
+type (
+	violationTileService interface {
+		ViolationTile(ctx context.Context, z, x, y int, ifNoneMatch string) (*model.VectorTile, error)
+	}
+
+	// ViolationTileHandler serves /{z}/{x}/{y}.mvt vector tiles of the violations map.
+	ViolationTileHandler struct {
+		name    string
+		service violationTileService
+		maxAge  time.Duration
+	}
+)
+
+func NewViolationTileHandler(service violationTileService, name string, maxAge time.Duration) *ViolationTileHandler {
+	return &ViolationTileHandler{
+		name:    name,
+		service: service,
+		maxAge:  maxAge,
+	}
+}
+
+func (h *ViolationTileHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	rawY, ok := strings.CutSuffix(r.PathValue(defenitions.ParamTileY), mvtExtension)
+	if !ok {
+		uhttp.SendErrorResponse(w, http.StatusNotFound, "tiles are only served as "+mvtExtension)
+		return
+	}
+
+	var coords [3]int
+	for i, raw := range []string{r.PathValue(defenitions.ParamTileZ), r.PathValue(defenitions.ParamTileX), rawY} {
+		c, err := strconv.Atoi(raw)
+		if err != nil {
+			uhttp.SendErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("%v: tile coordinate %q", model.ErrInvalidParameter, raw))
+			return
+		}
+		coords[i] = c
+	}
+
+	tile, err := h.service.ViolationTile(r.Context(), coords[0], coords[1], coords[2], r.Header.Get("If-None-Match"))
+	if err != nil {
+		uhttp.SendServiceErrorResponse(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", tile.ETag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.maxAge.Seconds())))
+
+	if tile.NotModified {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", mvtContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(tile.Data)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(tile.Data)
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
 	"github.com/inzarubin80/Server/internal/model"
 )
 
@@ -25,12 +26,17 @@ func NewtokenService(secretKey []byte, duration time.Duration, tokenType string)
 
 }
 
-func (a *tokenService) GenerateToken(userID model.UserID) (string, error) {
+func (a *tokenService) GenerateToken(userID model.UserID, role model.Role, sessionID string) (string, error) {
 
 	claims := &model.Claims{
 		UserID:    userID,
 		TokenType: a.tokenType,
+		Role:      role,
+		SessionID: sessionID,
 		StandardClaims: jwt.StandardClaims{
+			// Id makes every token unique, so that refresh tokens can be looked up by hash.
+			Id:        uuid.NewString(),
+			IssuedAt:  time.Now().Unix(),
 			ExpiresAt: time.Now().Add(a.duration).Unix(),
 		},
 	}
@@ -0,0 +1,108 @@
+package uhttp
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+// maxUserAgentLength bounds the user agent stored with a session.
+const maxUserAgentLength = 512
+
+// ClientInfo returns the user agent and address of the client. The address is
+// the one resolved for the request by the RequestID middleware, see ClientIP.
+func ClientInfo(r *http.Request) model.ClientInfo {
+
+	ip := model.RequestMetaFrom(r.Context()).IP
+	if ip == "" {
+		ip = peerIP(r)
+	}
+
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	return model.ClientInfo{UserAgent: userAgent, IP: ip}
+}
+
+// ClientIP returns the address of the client. X-Forwarded-For is only believed
+// for requests that come from a trusted proxy, and only as far as trusted
+// proxies appended to it: the client is the rightmost address that is not one
+// of them. Anything further left was sent by the client and may be forged.
+func ClientIP(r *http.Request, trustedProxies []netip.Prefix) string {
+
+	ip := peerIP(r)
+	if !trusted(ip, trustedProxies) {
+		return ip
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		if _, err := netip.ParseAddr(hop); err != nil {
+			break
+		}
+		ip = hop
+		if !trusted(hop, trustedProxies) {
+			break
+		}
+	}
+
+	return ip
+}
+
+// ParseTrustedProxies parses a list of proxy addresses and CIDR ranges.
+func ParseTrustedProxies(list []string) ([]netip.Prefix, error) {
+
+	res := make([]netip.Prefix, 0, len(list))
+	for _, item := range list {
+		if strings.Contains(item, "/") {
+			prefix, err := netip.ParsePrefix(item)
+			if err != nil {
+				return nil, fmt.Errorf("trusted proxy %q: %w", item, err)
+			}
+			res = append(res, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(item)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q: %w", item, err)
+		}
+		addr = addr.Unmap()
+		res = append(res, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return res, nil
+}
+
+func peerIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+func trusted(ip string, trustedProxies []netip.Prefix) bool {
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,7 +2,10 @@ package uhttp
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+
+	"github.com/inzarubin80/Server/internal/model"
 )
 
 type errorResponse struct {
@@ -24,3 +27,23 @@ func SendErrorResponse(w http.ResponseWriter, statusCode int, Message string) {
 	w.Write(jsonData)
 
 }
+
+// SendServiceErrorResponse maps errors returned by the service layer to HTTP status codes.
+func SendServiceErrorResponse(w http.ResponseWriter, err error) {
+
+	switch {
+	case errors.Is(err, model.ErrorNotFound):
+		SendErrorResponse(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, model.ErrInvalidParameter):
+		SendErrorResponse(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, model.ErrUnauthorized):
+		SendErrorResponse(w, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, model.ErrForbidden):
+		SendErrorResponse(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, model.ErrConflict):
+		SendErrorResponse(w, http.StatusConflict, err.Error())
+	default:
+		SendErrorResponse(w, http.StatusInternalServerError, err.Error())
+	}
+
+}
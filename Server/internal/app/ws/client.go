@@ -0,0 +1,187 @@
+package ws
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+const (
+	writeWait       = 10 * time.Second
+	pongWait        = 60 * time.Second
+	pingPeriod      = (pongWait * 9) / 10
+	maxMessageSize  = 4096
+	sendBuffer      = 64
+	maxViolationIDs = 500
+)
+
+const (
+	messageSubscribe   = "subscribe"
+	messageUnsubscribe = "unsubscribe"
+)
+
+type (
+	// Client is a single realtime connection. A client receives events for
+	// violations inside its bbox and for the violation ids it subscribed to.
+	Client struct {
+		hub    *Hub
+		conn   *websocket.Conn
+		userID model.UserID
+		send   chan []byte
+		// done is closed by the hub once the client is removed.
+		done chan struct{}
+
+		mu           sync.RWMutex
+		bbox         *model.BBox
+		violationIDs map[model.ViolationID]bool
+	}
+
+	// clientMessage is what clients send: a subscription change. BBox is
+	// [minLng, minLat, maxLng, maxLat]; an empty subscribe message clears nothing.
+	clientMessage struct {
+		Type         string              `json:"type"`
+		BBox         []float64           `json:"bbox,omitempty"`
+		ViolationIDs []model.ViolationID `json:"violation_ids,omitempty"`
+	}
+
+	errorMessage struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	}
+)
+
+func newClient(hub *Hub, conn *websocket.Conn, userID model.UserID) *Client {
+	return &Client{
+		hub:          hub,
+		conn:         conn,
+		userID:       userID,
+		send:         make(chan []byte, sendBuffer),
+		done:         make(chan struct{}),
+		violationIDs: make(map[model.ViolationID]bool),
+	}
+}
+
+func (c *Client) subscribed(v *model.Violation) bool {
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.violationIDs[v.ID] {
+		return true
+	}
+
+	b := c.bbox
+	return b != nil && v.Lng >= b.MinLng && v.Lng <= b.MaxLng && v.Lat >= b.MinLat && v.Lat <= b.MaxLat
+}
+
+// readPump handles subscription messages and pongs. It unregisters the client
+// when the connection fails.
+func (c *Client) readPump() {
+
+	defer func() {
+		c.hub.unregister <- c
+		c.conn.Close()
+	}()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var msg clientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.reply("invalid message")
+			continue
+		}
+
+		if err := c.apply(&msg); err != "" {
+			c.reply(err)
+		}
+	}
+}
+
+func (c *Client) apply(msg *clientMessage) string {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch msg.Type {
+	case messageSubscribe:
+		if msg.BBox != nil {
+			if len(msg.BBox) != 4 || msg.BBox[0] > msg.BBox[2] || msg.BBox[1] > msg.BBox[3] {
+				return "bbox must be [minLng, minLat, maxLng, maxLat]"
+			}
+			c.bbox = &model.BBox{MinLng: msg.BBox[0], MinLat: msg.BBox[1], MaxLng: msg.BBox[2], MaxLat: msg.BBox[3]}
+		}
+		if len(c.violationIDs)+len(msg.ViolationIDs) > maxViolationIDs {
+			return "too many violation ids"
+		}
+		for _, id := range msg.ViolationIDs {
+			c.violationIDs[id] = true
+		}
+
+	case messageUnsubscribe:
+		if msg.BBox != nil {
+			c.bbox = nil
+		}
+		for _, id := range msg.ViolationIDs {
+			delete(c.violationIDs, id)
+		}
+
+	default:
+		return "unknown message type"
+	}
+
+	return ""
+}
+
+// reply sends an error to the client without blocking the read loop.
+func (c *Client) reply(message string) {
+	data, _ := json.Marshal(errorMessage{Type: "error", Message: message})
+	select {
+	case c.send <- data:
+	case <-c.done:
+	default:
+	}
+}
+
+// writePump writes queued events and pings. It is the only writer of the connection.
+func (c *Client) writePump() {
+
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case <-c.done:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+			return
+
+		case payload := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return
+			}
+
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
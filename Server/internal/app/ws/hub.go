@@ -1,19 +1,236 @@
 package ws
 
-// Minimal stub of ws.Hub to satisfy builds while migrating imports.
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
 
-type Hub struct {
-	// add fields later as needed
-}
+	"github.com/gorilla/websocket"
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+// broadcastBuffer is the number of pending events the hub accepts before dropping.
+const broadcastBuffer = 256
+
+const (
+	messageViolationCreated       = "violation.created"
+	messageViolationUpdated       = "violation.updated"
+	messageViolationStatusChanged = "violation.status_changed"
+	messageViolationRemoved       = "violation.removed"
+	messageNotification           = "notification"
+)
+
+type (
+	// Hub keeps the connected realtime clients and fans violation events out to
+	// the clients whose subscription matches the violation. Inbox notifications
+	// go to the connections of their recipient.
+	Hub struct {
+		upgrader   websocket.Upgrader
+		clients    map[*Client]bool
+		register   chan *Client
+		unregister chan *Client
+		broadcast  chan *Event
+		direct     chan *userMessage
+	}
+
+	// Event is pushed to clients as JSON. Removals carry only the id of the
+	// violation, which is no longer public.
+	Event struct {
+		Type        string            `json:"type"`
+		Violation   *model.Violation  `json:"violation,omitempty"`
+		ViolationID model.ViolationID `json:"violation_id,omitempty"`
+		// subject is matched against subscriptions; it is not sent.
+		subject *model.Violation
+	}
+
+	// NotificationMessage is pushed to the connections of the notification's
+	// recipient only.
+	NotificationMessage struct {
+		Type         string              `json:"type"`
+		Notification *model.Notification `json:"notification"`
+		UnreadCount  int                 `json:"unread_count"`
+	}
+
+	userMessage struct {
+		userID  model.UserID
+		payload []byte
+	}
+)
+
+// NewHub returns a new Hub instance. Connections from browsers are accepted only
+// from allowedOrigins (or the same host when the list is empty); native clients
+// that send no Origin header are always accepted.
+func NewHub(allowedOrigins []string) *Hub {
+
+	origins := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		origins[origin] = true
+	}
 
-// NewHub returns a new Hub instance.
-func NewHub() *Hub {
-	return &Hub{}
+	return &Hub{
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			CheckOrigin: func(r *http.Request) bool {
+				origin := r.Header.Get("Origin")
+				if origin == "" || origins[origin] {
+					return true
+				}
+				if len(origins) > 0 {
+					return false
+				}
+				return origin == "http://"+r.Host || origin == "https://"+r.Host
+			},
+		},
+		clients:    make(map[*Client]bool),
+		register:   make(chan *Client),
+		unregister: make(chan *Client),
+		broadcast:  make(chan *Event, broadcastBuffer),
+		direct:     make(chan *userMessage, broadcastBuffer),
+	}
 }
 
-// Run is a placeholder for the hub run loop.
+// Run is the hub loop. It owns the clients map.
 func (h *Hub) Run() {
-	// no-op for stub
+
+	for {
+		select {
+		case client := <-h.register:
+			h.clients[client] = true
+
+		case client := <-h.unregister:
+			h.remove(client)
+
+		case event := <-h.broadcast:
+			payload, err := json.Marshal(event)
+			if err != nil {
+				log.Printf("ws: marshal %s: %v", event.Type, err)
+				continue
+			}
+
+			for client := range h.clients {
+				if !client.subscribed(event.subject) {
+					continue
+				}
+				h.send(client, payload)
+			}
+
+		case msg := <-h.direct:
+			for client := range h.clients {
+				if client.userID == msg.userID {
+					h.send(client, msg.payload)
+				}
+			}
+		}
+	}
+}
+
+func (h *Hub) send(client *Client, payload []byte) {
+	select {
+	case client.send <- payload:
+	default:
+		// The client cannot keep up; drop it rather than block the hub.
+		h.remove(client)
+	}
+}
+
+// NotifyUser delivers an inbox notification to every connection of the user.
+// Like HandleEvent it never blocks; a dropped message is only missing until the
+// client lists its notifications again.
+func (h *Hub) NotifyUser(userID model.UserID, notification *model.Notification, unreadCount int) {
+
+	payload, err := json.Marshal(&NotificationMessage{Type: messageNotification, Notification: notification, UnreadCount: unreadCount})
+	if err != nil {
+		log.Printf("ws: marshal notification %d: %v", notification.ID, err)
+		return
+	}
+
+	select {
+	case h.direct <- &userMessage{userID: userID, payload: payload}:
+	default:
+		log.Printf("ws: direct queue is full, dropping notification %d", notification.ID)
+	}
+}
+
+// HandleEvent is the event bus subscriber of the hub. It queues the event for
+// delivery and never blocks the publisher. Reports that are not approved by
+// moderation are not broadcast.
+func (h *Hub) HandleEvent(ctx context.Context, event model.Event) error {
+
+	msgType := messageType(event)
+	if msgType == "" {
+		return nil
+	}
+
+	msg := &Event{Type: msgType, subject: event.Subject()}
+	if msgType == messageViolationRemoved {
+		msg.ViolationID = event.Subject().ID
+	} else {
+		msg.Violation = event.Subject()
+	}
+
+	select {
+	case h.broadcast <- msg:
+		return nil
+	default:
+		return fmt.Errorf("broadcast queue is full, dropping %s", msg.Type)
+	}
+}
+
+// messageType maps domain events onto the message types of the realtime
+// protocol: created, status_changed, removed and updated for everything else.
+// It returns "" for events that must not be broadcast.
+func messageType(event model.Event) string {
+
+	if e, ok := event.(*model.ViolationModerated); ok {
+		// A report becomes public when it is approved and disappears when a
+		// public one is rejected. Reports that were never public are not mentioned.
+		switch {
+		case e.Decision.Status == model.ModerationApproved:
+			return messageViolationCreated
+		case e.PreviousStatus == model.ModerationApproved:
+			return messageViolationRemoved
+		}
+		return ""
+	}
+
+	if event.Subject().ModerationStatus != model.ModerationApproved {
+		return ""
+	}
+
+	switch e := event.(type) {
+	case *model.ViolationCreated:
+		return messageViolationCreated
+	case *model.ViolationStatusChanged:
+		if e.Change.FromStatus != e.Change.ToStatus {
+			return messageViolationStatusChanged
+		}
+	}
+
+	return messageViolationUpdated
 }
 
+// ServeWS upgrades the request and runs the connection until it is closed.
+func (h *Hub) ServeWS(w http.ResponseWriter, r *http.Request, userID model.UserID) error {
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return err
+	}
 
+	client := newClient(h, conn, userID)
+	h.register <- client
+
+	go client.writePump()
+	client.readPump()
+	return nil
+}
+
+func (h *Hub) remove(client *Client) {
+	if _, ok := h.clients[client]; ok {
+		delete(h.clients, client)
+		close(client.done)
+	}
+}
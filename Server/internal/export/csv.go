@@ -0,0 +1,67 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+var csvHeader = []string{"id", "type", "status", "lat", "lng", "confirmations", "description", "created_at", "updated_at", "reporter"}
+
+type csvWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) Write(v *model.ExportedViolation) error {
+
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+
+	// csv.Writer buffers; the buffer is flushed by the next write once it is full.
+	return c.w.Write([]string{
+		string(v.ID),
+		string(v.Type),
+		string(v.Status),
+		strconv.FormatFloat(v.Lat, 'f', -1, 64),
+		strconv.FormatFloat(v.Lng, 'f', -1, 64),
+		strconv.Itoa(v.ConfirmationsCount),
+		csvText(v.Description),
+		v.CreatedAt.UTC().Format(time.RFC3339),
+		v.UpdatedAt.UTC().Format(time.RFC3339),
+		v.Reporter,
+	})
+}
+
+func (c *csvWriter) Close() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// csvText keeps spreadsheets from evaluating user text that starts like a formula.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func (c *csvWriter) writeHeader() error {
+	if c.headerWritten {
+		return nil
+	}
+	c.headerWritten = true
+	return c.w.Write(csvHeader)
+}
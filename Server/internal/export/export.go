@@ -0,0 +1,54 @@
+// Package export writes violations as GeoJSON, CSV or KML documents one at a time,
+// so exports of any size are streamed without being held in memory.
+package export
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+const (
+	FormatGeoJSON = "geojson"
+	FormatCSV     = "csv"
+	FormatKML     = "kml"
+)
+
+// Writer encodes one document. Close completes the document; it must be called
+// even if no violation was written.
+type Writer interface {
+	Write(v *model.ExportedViolation) error
+	Close() error
+}
+
+// Format describes how a format is served.
+type Format struct {
+	ContentType string
+	Extension   string
+}
+
+var formats = map[string]Format{
+	FormatGeoJSON: {ContentType: "application/geo+json", Extension: "geojson"},
+	FormatCSV:     {ContentType: "text/csv; charset=utf-8", Extension: "csv"},
+	FormatKML:     {ContentType: "application/vnd.google-earth.kml+xml", Extension: "kml"},
+}
+
+// Lookup returns the description of the format.
+func Lookup(format string) (Format, bool) {
+	f, ok := formats[format]
+	return f, ok
+}
+
+// NewWriter returns a writer of the format that writes to w.
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatGeoJSON:
+		return newGeoJSONWriter(w), nil
+	case FormatCSV:
+		return newCSVWriter(w), nil
+	case FormatKML:
+		return newKMLWriter(w), nil
+	}
+	return nil, fmt.Errorf("%w: unknown export format %q", model.ErrInvalidParameter, format)
+}
@@ -0,0 +1,85 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+type (
+	geoJSONWriter struct {
+		w       io.Writer
+		written int
+	}
+
+	geoJSONFeature struct {
+		Type       string            `json:"type"`
+		ID         model.ViolationID `json:"id"`
+		Geometry   geoJSONPoint      `json:"geometry"`
+		Properties geoJSONProperties `json:"properties"`
+	}
+
+	geoJSONPoint struct {
+		Type string `json:"type"`
+		// Coordinates are longitude first, as RFC 7946 requires.
+		Coordinates [2]float64 `json:"coordinates"`
+	}
+
+	geoJSONProperties struct {
+		Type          model.ViolationType   `json:"type"`
+		Description   string                `json:"description"`
+		Status        model.ViolationStatus `json:"status"`
+		Confirmations int                   `json:"confirmations"`
+		CreatedAt     time.Time             `json:"created_at"`
+		UpdatedAt     time.Time             `json:"updated_at"`
+		Reporter      string                `json:"reporter"`
+	}
+)
+
+func newGeoJSONWriter(w io.Writer) *geoJSONWriter {
+	return &geoJSONWriter{w: w}
+}
+
+func (g *geoJSONWriter) Write(v *model.ExportedViolation) error {
+
+	data, err := json.Marshal(geoJSONFeature{
+		Type:     "Feature",
+		ID:       v.ID,
+		Geometry: geoJSONPoint{Type: "Point", Coordinates: [2]float64{v.Lng, v.Lat}},
+		Properties: geoJSONProperties{
+			Type:          v.Type,
+			Description:   v.Description,
+			Status:        v.Status,
+			Confirmations: v.ConfirmationsCount,
+			CreatedAt:     v.CreatedAt,
+			UpdatedAt:     v.UpdatedAt,
+			Reporter:      v.Reporter,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	separator := ",\n"
+	if g.written == 0 {
+		separator = `{"type":"FeatureCollection","features":[` + "\n"
+	}
+	g.written++
+
+	if _, err := io.WriteString(g.w, separator); err != nil {
+		return err
+	}
+	_, err = g.w.Write(data)
+	return err
+}
+
+func (g *geoJSONWriter) Close() error {
+	end := "\n]}\n"
+	if g.written == 0 {
+		end = `{"type":"FeatureCollection","features":[]}` + "\n"
+	}
+	_, err := io.WriteString(g.w, end)
+	return err
+}
@@ -0,0 +1,88 @@
+package export
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+const (
+	kmlHeader = xml.Header + `<kml xmlns="http://www.opengis.net/kml/2.2">
+<Document>
+<name>Violations</name>
+`
+	kmlFooter = "</Document>\n</kml>\n"
+)
+
+type (
+	kmlWriter struct {
+		w             io.Writer
+		enc           *xml.Encoder
+		headerWritten bool
+	}
+
+	kmlPlacemark struct {
+		XMLName      xml.Name  `xml:"Placemark"`
+		ID           string    `xml:"id,attr"`
+		Name         string    `xml:"name"`
+		Description  string    `xml:"description"`
+		ExtendedData []kmlData `xml:"ExtendedData>Data"`
+		Coordinates  string    `xml:"Point>coordinates"`
+	}
+
+	kmlData struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:"value"`
+	}
+)
+
+func newKMLWriter(w io.Writer) *kmlWriter {
+	return &kmlWriter{w: w, enc: xml.NewEncoder(w)}
+}
+
+func (k *kmlWriter) Write(v *model.ExportedViolation) error {
+
+	if err := k.writeHeader(); err != nil {
+		return err
+	}
+
+	err := k.enc.Encode(kmlPlacemark{
+		ID:          string(v.ID),
+		Name:        string(v.Type),
+		Description: v.Description,
+		ExtendedData: []kmlData{
+			{Name: "status", Value: string(v.Status)},
+			{Name: "confirmations", Value: strconv.Itoa(v.ConfirmationsCount)},
+			{Name: "created_at", Value: v.CreatedAt.UTC().Format(time.RFC3339)},
+			{Name: "updated_at", Value: v.UpdatedAt.UTC().Format(time.RFC3339)},
+			{Name: "reporter", Value: v.Reporter},
+		},
+		Coordinates: strconv.FormatFloat(v.Lng, 'f', -1, 64) + "," + strconv.FormatFloat(v.Lat, 'f', -1, 64),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(k.w, "\n")
+	return err
+}
+
+func (k *kmlWriter) Close() error {
+	if err := k.writeHeader(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(k.w, kmlFooter)
+	return err
+}
+
+func (k *kmlWriter) writeHeader() error {
+	if k.headerWritten {
+		return nil
+	}
+	k.headerWritten = true
+	_, err := io.WriteString(k.w, kmlHeader)
+	return err
+}
@@ -0,0 +1,248 @@
+// Package imageproc re-encodes uploaded photos without metadata and produces the
+// resized variants served to clients. It relies only on the standard image packages.
+package imageproc
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+const (
+	VariantThumb   = "thumb"
+	VariantDisplay = "display"
+	// VariantOriginal is the full-size image without metadata.
+	VariantOriginal = "original"
+)
+
+type (
+	Options struct {
+		// ThumbSize is the edge of the square thumbnail.
+		ThumbSize int
+		// DisplaySize bounds the longest edge of the display variant.
+		DisplaySize int
+		JPEGQuality int
+		// MaxPixels bounds width x height of accepted images. Decoding allocates
+		// the full bitmap, so a small file declaring huge dimensions is rejected
+		// before it is decoded.
+		MaxPixels int64
+	}
+
+	// Image is an encoded image ready to be stored.
+	Image struct {
+		Data   []byte
+		Mime   string
+		Width  int
+		Height int
+	}
+
+	Result struct {
+		// Original is the full-size image re-encoded without EXIF and other metadata.
+		Original *Image
+		Variants map[string]*Image
+	}
+)
+
+// Process decodes a JPEG or PNG, applies the EXIF orientation so the picture stays
+// upright once the metadata is dropped, and encodes the stripped original and variants.
+func Process(r io.Reader, opts Options) (*Result, error) {
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image header: %w", err)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > opts.MaxPixels {
+		return nil, fmt.Errorf("image of %dx%d exceeds %d pixels", cfg.Width, cfg.Height, opts.MaxPixels)
+	}
+
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+
+	img := toRGBA(src)
+	if format == "jpeg" {
+		img = orient(img, jpegOrientation(data))
+	}
+
+	original, err := encode(img, format, opts.JPEGQuality)
+	if err != nil {
+		return nil, err
+	}
+
+	thumb, err := encode(resize(cropSquare(img), opts.ThumbSize, opts.ThumbSize), "jpeg", opts.JPEGQuality)
+	if err != nil {
+		return nil, err
+	}
+
+	w, h := fit(img.Bounds().Dx(), img.Bounds().Dy(), opts.DisplaySize)
+	display, err := encode(resize(img, w, h), "jpeg", opts.JPEGQuality)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Original: original,
+		Variants: map[string]*Image{
+			VariantThumb:   thumb,
+			VariantDisplay: display,
+		},
+	}, nil
+}
+
+func encode(img *image.RGBA, format string, quality int) (*Image, error) {
+
+	var (
+		buf  bytes.Buffer
+		mime string
+		err  error
+	)
+
+	switch format {
+	case "png":
+		mime = "image/png"
+		err = png.Encode(&buf, img)
+	default:
+		mime = "image/jpeg"
+		err = jpeg.Encode(&buf, flatten(img), &jpeg.Options{Quality: quality})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("encode %s: %w", format, err)
+	}
+
+	return &Image{Data: buf.Bytes(), Mime: mime, Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}, nil
+}
+
+func toRGBA(src image.Image) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Src)
+	return dst
+}
+
+// flatten composes the image over white, since JPEG has no alpha channel.
+func flatten(img *image.RGBA) *image.RGBA {
+	dst := image.NewRGBA(img.Bounds())
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Over)
+	return dst
+}
+
+// fit scales w x h down so that the longest edge is at most max. Images are never enlarged.
+func fit(w, h, max int) (int, int) {
+	if w <= max && h <= max {
+		return w, h
+	}
+	if w >= h {
+		return max, maxInt(1, h*max/w)
+	}
+	return maxInt(1, w*max/h), max
+}
+
+func cropSquare(img *image.RGBA) *image.RGBA {
+	b := img.Bounds()
+	side := minInt(b.Dx(), b.Dy())
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/2
+	return img.SubImage(image.Rect(x0, y0, x0+side, y0+side)).(*image.RGBA)
+}
+
+// resize scales the image with a box filter: every destination pixel is the average
+// of the source pixels it covers. The target is clamped so images are never enlarged.
+func resize(src *image.RGBA, w, h int) *image.RGBA {
+
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	w, h = minInt(w, sw), minInt(h, sh)
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		sy0, sy1 := y*sh/h, maxInt((y+1)*sh/h, y*sh/h+1)
+		for x := 0; x < w; x++ {
+			sx0, sx1 := x*sw/w, maxInt((x+1)*sw/w, x*sw/w+1)
+
+			var r, g, bl, a, n uint32
+			for sy := sy0; sy < sy1; sy++ {
+				i := src.PixOffset(b.Min.X+sx0, b.Min.Y+sy)
+				for sx := sx0; sx < sx1; sx++ {
+					r += uint32(src.Pix[i])
+					g += uint32(src.Pix[i+1])
+					bl += uint32(src.Pix[i+2])
+					a += uint32(src.Pix[i+3])
+					n++
+					i += 4
+				}
+			}
+
+			j := dst.PixOffset(x, y)
+			dst.Pix[j] = uint8(r / n)
+			dst.Pix[j+1] = uint8(g / n)
+			dst.Pix[j+2] = uint8(bl / n)
+			dst.Pix[j+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// orient applies an EXIF orientation (1-8) to the image.
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[src.PixOffset(sx, sy):src.PixOffset(sx, sy)+4])
+		}
+	}
+	return dst
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
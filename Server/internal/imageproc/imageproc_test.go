@@ -0,0 +1,198 @@
+package imageproc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+const exifSecret = "GPS 55.7558N 37.6173E"
+
+var testOptions = Options{ThumbSize: 16, DisplaySize: 32, JPEGQuality: 90, MaxPixels: 1 << 20}
+
+func TestProcess(t *testing.T) {
+
+	tests := []struct {
+		name string
+		data []byte
+		// wantW and wantH are the dimensions of the stripped original.
+		wantW, wantH int
+		wantMime     string
+		// topLeft and bottomRight are the colours expected in the middle of
+		// those quarters of the original.
+		topLeft, bottomRight color.RGBA
+	}{
+		{
+			name:  "jpeg without exif",
+			data:  encodeJPEG(t, halves(64, 32)),
+			wantW: 64, wantH: 32, wantMime: "image/jpeg",
+			topLeft: red, bottomRight: blue,
+		},
+		{
+			// Orientation 6 is a 90° clockwise turn: the left half ends up on top.
+			name:  "jpeg rotated by exif",
+			data:  withExif(encodeJPEG(t, halves(64, 32)), 6),
+			wantW: 32, wantH: 64, wantMime: "image/jpeg",
+			topLeft: red, bottomRight: blue,
+		},
+		{
+			name:  "jpeg flipped by exif",
+			data:  withExif(encodeJPEG(t, halves(64, 32)), 3),
+			wantW: 64, wantH: 32, wantMime: "image/jpeg",
+			topLeft: blue, bottomRight: red,
+		},
+		{
+			name:  "png keeps its format",
+			data:  encodePNG(t, halves(64, 32)),
+			wantW: 64, wantH: 32, wantMime: "image/png",
+			topLeft: red, bottomRight: blue,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			res, err := Process(bytes.NewReader(tt.data), testOptions)
+			if err != nil {
+				t.Fatalf("Process: %v", err)
+			}
+
+			original := res.Original
+			if original.Mime != tt.wantMime || original.Width != tt.wantW || original.Height != tt.wantH {
+				t.Fatalf("original = %s %dx%d, want %s %dx%d", original.Mime, original.Width, original.Height, tt.wantMime, tt.wantW, tt.wantH)
+			}
+			if bytes.Contains(original.Data, []byte("Exif")) || bytes.Contains(original.Data, []byte(exifSecret)) {
+				t.Error("original still carries the EXIF segment")
+			}
+
+			img, _, err := image.Decode(bytes.NewReader(original.Data))
+			if err != nil {
+				t.Fatalf("decode original: %v", err)
+			}
+			b := img.Bounds()
+			if got := img.At(b.Dx()/4, b.Dy()/4); !near(got, tt.topLeft) {
+				t.Errorf("top left = %v, want %v", got, tt.topLeft)
+			}
+			if got := img.At(b.Dx()*3/4, b.Dy()*3/4); !near(got, tt.bottomRight) {
+				t.Errorf("bottom right = %v, want %v", got, tt.bottomRight)
+			}
+
+			thumb := res.Variants[VariantThumb]
+			if thumb == nil || thumb.Width != testOptions.ThumbSize || thumb.Height != testOptions.ThumbSize {
+				t.Errorf("thumb = %+v, want %dx%d", thumb, testOptions.ThumbSize, testOptions.ThumbSize)
+			}
+			display := res.Variants[VariantDisplay]
+			if display == nil || max(display.Width, display.Height) != testOptions.DisplaySize {
+				t.Errorf("display = %+v, want longest edge %d", display, testOptions.DisplaySize)
+			}
+			for name, v := range res.Variants {
+				if bytes.Contains(v.Data, []byte(exifSecret)) {
+					t.Errorf("%s variant carries the EXIF segment", name)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessRejectsTooManyPixels(t *testing.T) {
+
+	opts := testOptions
+	opts.MaxPixels = 64*32 - 1
+
+	if _, err := Process(bytes.NewReader(encodeJPEG(t, halves(64, 32))), opts); err == nil {
+		t.Fatal("Process accepted an image above MaxPixels")
+	}
+}
+
+func TestJPEGOrientation(t *testing.T) {
+
+	plain := encodeJPEG(t, halves(8, 8))
+	for orientation := 1; orientation <= 8; orientation++ {
+		if got := jpegOrientation(withExif(plain, orientation)); got != orientation {
+			t.Errorf("orientation %d read as %d", orientation, got)
+		}
+	}
+	if got := jpegOrientation(plain); got != 1 {
+		t.Errorf("jpeg without exif read as %d, want 1", got)
+	}
+	if got := jpegOrientation([]byte("not a jpeg")); got != 1 {
+		t.Errorf("garbage read as %d, want 1", got)
+	}
+}
+
+var (
+	red  = color.RGBA{R: 255, A: 255}
+	blue = color.RGBA{B: 255, A: 255}
+)
+
+// halves is a w x h image, red on the left and blue on the right.
+func halves(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if x < w/2 {
+				img.Set(x, y, red)
+			} else {
+				img.Set(x, y, blue)
+			}
+		}
+	}
+	return img
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withExif inserts an APP1 segment with the orientation and a stand-in for GPS
+// data right after the SOI marker of the JPEG.
+func withExif(data []byte, orientation int) []byte {
+
+	tiff := []byte("II*\x00")
+	tiff = binary.LittleEndian.AppendUint32(tiff, 8)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, exifOrientationTag)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3) // SHORT
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, uint16(orientation))
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 0)
+	tiff = append(tiff, exifSecret...)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+
+	out := []byte{0xFF, 0xD8, 0xFF, 0xE1}
+	out = binary.BigEndian.AppendUint16(out, uint16(len(segment)+2))
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func near(c color.Color, want color.RGBA) bool {
+	r, g, b, _ := c.RGBA()
+	return absDiff(r>>8, uint32(want.R)) < 40 && absDiff(g>>8, uint32(want.G)) < 40 && absDiff(b>>8, uint32(want.B)) < 40
+}
+
+func absDiff(a, b uint32) uint32 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
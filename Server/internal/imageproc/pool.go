@@ -0,0 +1,142 @@
+package imageproc
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+type (
+	PoolConfig struct {
+		Workers     int
+		QueueSize   int
+		MaxAttempts int
+		// Backoff is the delay before the first retry; it doubles on every attempt up to MaxBackoff.
+		Backoff    time.Duration
+		MaxBackoff time.Duration
+	}
+
+	// Pool runs photo processing jobs on a fixed number of goroutines and retries
+	// failed jobs with exponential backoff. A photo is queued at most once until
+	// its job succeeds or fails for good.
+	Pool struct {
+		config    PoolConfig
+		handle    func(ctx context.Context, storageKey string) error
+		onFailure func(ctx context.Context, storageKey string, err error)
+		jobs      chan job
+		wg        sync.WaitGroup
+
+		mu     sync.Mutex
+		queued map[string]bool
+	}
+
+	job struct {
+		storageKey string
+		attempt    int
+	}
+)
+
+// NewPool creates a pool. handle processes one photo; onFailure is called once a
+// photo has failed MaxAttempts times.
+func NewPool(config PoolConfig, handle func(ctx context.Context, storageKey string) error, onFailure func(ctx context.Context, storageKey string, err error)) *Pool {
+	return &Pool{
+		config:    config,
+		handle:    handle,
+		onFailure: onFailure,
+		jobs:      make(chan job, config.QueueSize),
+		queued:    make(map[string]bool),
+	}
+}
+
+// Run starts the workers and blocks until ctx is cancelled and the workers have stopped.
+func (p *Pool) Run(ctx context.Context) {
+
+	for i := 0; i < p.config.Workers; i++ {
+		p.wg.Add(1)
+		go p.worker(ctx)
+	}
+
+	<-ctx.Done()
+	p.wg.Wait()
+}
+
+// Enqueue schedules a photo for processing unless it is already scheduled. When
+// the queue is full the photo stays pending and is picked up again by the next
+// sweep of pending photos.
+func (p *Pool) Enqueue(storageKey string) {
+
+	p.mu.Lock()
+	if p.queued[storageKey] {
+		p.mu.Unlock()
+		return
+	}
+	p.queued[storageKey] = true
+	p.mu.Unlock()
+
+	p.enqueue(job{storageKey: storageKey, attempt: 1})
+}
+
+func (p *Pool) enqueue(j job) {
+	select {
+	case p.jobs <- j:
+	default:
+		log.Printf("imageproc: queue is full, %s stays pending", j.storageKey)
+		p.done(j.storageKey)
+	}
+}
+
+// done lets the photo be scheduled again.
+func (p *Pool) done(storageKey string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.queued, storageKey)
+}
+
+func (p *Pool) worker(ctx context.Context) {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case j := <-p.jobs:
+			p.process(ctx, j)
+		}
+	}
+}
+
+func (p *Pool) process(ctx context.Context, j job) {
+
+	err := p.handle(ctx, j.storageKey)
+	if err == nil {
+		p.done(j.storageKey)
+		return
+	}
+
+	if j.attempt >= p.config.MaxAttempts {
+		log.Printf("imageproc: %s failed after %d attempts: %v", j.storageKey, j.attempt, err)
+		p.onFailure(ctx, j.storageKey, err)
+		p.done(j.storageKey)
+		return
+	}
+
+	delay := p.backoff(j.attempt)
+	log.Printf("imageproc: %s attempt %d failed, retrying in %s: %v", j.storageKey, j.attempt, delay, err)
+
+	time.AfterFunc(delay, func() {
+		if ctx.Err() != nil {
+			p.done(j.storageKey)
+			return
+		}
+		p.enqueue(job{storageKey: j.storageKey, attempt: j.attempt + 1})
+	})
+}
+
+func (p *Pool) backoff(attempt int) time.Duration {
+	delay := p.config.Backoff << (attempt - 1)
+	if delay <= 0 || delay > p.config.MaxBackoff {
+		return p.config.MaxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,85 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	AuditViolationCreate         = "violation.create"
+	AuditViolationConfirm        = "violation.confirm"
+	AuditViolationUnconfirm      = "violation.unconfirm"
+	AuditViolationStatusChange   = "violation.status_change"
+	AuditViolationProposeResolve = "violation.propose_resolve"
+	AuditViolationModerate       = "violation.moderate"
+	AuditViolationMerge          = "violation.merge"
+	AuditRuleFire                = "rule.fire"
+	AuditUserRoleChange          = "user.role_change"
+	AuditOutboxReplay            = "outbox.replay"
+	AuditCommentCreate           = "comment.create"
+	AuditCommentEdit             = "comment.edit"
+	AuditCommentDelete           = "comment.delete"
+	AuditCommentModerate         = "comment.moderate"
+	AuditEventPublish            = "event.publish"
+
+	AuditEntityViolation = "violation"
+	AuditEntityUser      = "user"
+	AuditEntityOutbox    = "outbox_message"
+	AuditEntityComment   = "comment"
+)
+
+type (
+	// AuditEntry is one append-only audit record. ActorID is zero for system actions.
+	AuditEntry struct {
+		ID         int64                  `json:"id"`
+		ActorID    UserID                 `json:"actor_id,omitempty"`
+		Action     string                 `json:"action"`
+		EntityType string                 `json:"entity_type"`
+		EntityID   string                 `json:"entity_id"`
+		Diff       map[string]AuditChange `json:"diff"`
+		RequestID  string                 `json:"request_id,omitempty"`
+		IP         string                 `json:"ip,omitempty"`
+		CreatedAt  time.Time              `json:"created_at"`
+	}
+
+	AuditChange struct {
+		From any `json:"from,omitempty"`
+		To   any `json:"to,omitempty"`
+	}
+
+	AuditFilter struct {
+		ActorID     *UserID
+		Action      string
+		EntityType  string
+		EntityID    string
+		CreatedFrom *time.Time
+		CreatedTo   *time.Time
+		// BeforeID continues a listing below the last returned id.
+		BeforeID int64
+		Limit    int
+	}
+
+	AuditPage struct {
+		Items      []*AuditEntry `json:"items"`
+		NextCursor string        `json:"next_cursor,omitempty"`
+	}
+
+	// RequestMeta identifies the HTTP request a change was made in.
+	RequestMeta struct {
+		RequestID string
+		IP        string
+	}
+
+	requestMetaKey struct{}
+)
+
+func WithRequestMeta(ctx context.Context, meta RequestMeta) context.Context {
+	return context.WithValue(ctx, requestMetaKey{}, meta)
+}
+
+// RequestMetaFrom returns the request metadata of ctx; it is empty outside of
+// HTTP requests (e.g. in background workers).
+func RequestMetaFrom(ctx context.Context) RequestMeta {
+	meta, _ := ctx.Value(requestMetaKey{}).(RequestMeta)
+	return meta
+}
@@ -0,0 +1,54 @@
+package model
+
+import "time"
+
+const (
+	// CommentActionHide takes a comment out of the thread; CommentActionShow
+	// puts it back and approves it if moderation left it pending.
+	CommentActionHide = "hide"
+	CommentActionShow = "show"
+)
+
+type (
+	// Comment is a message in the discussion thread of a violation. Comments are
+	// public once ModerationStatus is approved, unless a moderator hid them.
+	Comment struct {
+		ID               CommentID        `json:"id"`
+		ViolationID      ViolationID      `json:"violation_id"`
+		Author           UserBrief        `json:"author"`
+		Text             string           `json:"text"`
+		ModerationStatus ModerationStatus `json:"moderation_status"`
+		Hidden           bool             `json:"hidden"`
+		// HiddenReason is shown to the author and moderators only.
+		HiddenReason string     `json:"hidden_reason,omitempty"`
+		CreatedAt    time.Time  `json:"created_at"`
+		EditedAt     *time.Time `json:"edited_at,omitempty"`
+	}
+
+	// CommentFilter selects the comments of a violation visible to a viewer:
+	// public ones, the viewer's own and, with AllStatuses, everything.
+	CommentFilter struct {
+		ViolationID ViolationID
+		ViewerID    UserID
+		AllStatuses bool
+		// AfterID continues a listing above the last returned id.
+		AfterID CommentID
+		Limit   int
+	}
+
+	CommentsPage struct {
+		Items      []*Comment `json:"items"`
+		NextCursor string     `json:"next_cursor,omitempty"`
+	}
+)
+
+// Public reports whether everyone may read the comment.
+func (c *Comment) Public() bool {
+	return c.ModerationStatus == ModerationApproved && !c.Hidden
+}
+
+func (v Viewer) CanSeeComment(c *Comment) bool {
+	return c.Public() ||
+		(v.UserID != 0 && v.UserID == c.Author.ID) ||
+		v.Role.AtLeast(RoleModerator)
+}
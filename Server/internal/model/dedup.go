@@ -0,0 +1,107 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// metresPerDegree is the length of a degree of latitude, close enough for bounding boxes.
+const metresPerDegree = 111_320
+
+const (
+	// DedupOff creates every report as is.
+	DedupOff DedupPolicy = "off"
+	// DedupSuggest rejects a report with duplicates nearby and returns them, so
+	// the client can confirm one of them or resubmit with IgnoreDuplicates.
+	DedupSuggest DedupPolicy = "suggest"
+	// DedupMerge turns a report with duplicates nearby into a confirmation of
+	// the nearest one.
+	DedupMerge DedupPolicy = "merge"
+)
+
+type (
+	DedupPolicy string
+
+	// ViolationDraft is a new report as submitted by its author. Offline clients
+	// set ID to a UUID of their own, or send an IdempotencyKey, so that a retried
+	// submission returns the report created by the first one.
+	ViolationDraft struct {
+		ID             ViolationID
+		IdempotencyKey string
+		Type           ViolationType
+		Description    string
+		Lat            float64
+		Lng            float64
+		PhotoKeys      []string
+		// IgnoreDuplicates creates the report even if duplicates are nearby.
+		IgnoreDuplicates bool
+	}
+
+	// ViolationSubmission is the outcome of submitting a draft. Merged is set when
+	// the draft was recorded as a confirmation of the existing Violation, Replayed
+	// when the draft had already been submitted and Violation is the stored report.
+	ViolationSubmission struct {
+		Violation *Violation
+		Merged    bool
+		Replayed  bool
+	}
+
+	// NearbyFilter selects open violations of a type within RadiusM metres of a
+	// point created since Since. Reports that are not approved are only
+	// included for their author, ViewerID.
+	NearbyFilter struct {
+		Type     ViolationType
+		Lat      float64
+		Lng      float64
+		RadiusM  float64
+		Since    time.Time
+		ViewerID UserID
+		Limit    int
+	}
+
+	DuplicateCandidate struct {
+		Violation *Violation `json:"violation"`
+		DistanceM float64    `json:"distance_m"`
+	}
+
+	// DuplicatesError is returned for a draft with duplicates nearby under the
+	// suggest policy. It matches ErrConflict.
+	DuplicatesError struct {
+		Candidates []*DuplicateCandidate
+	}
+)
+
+func (p DedupPolicy) Valid() bool {
+	switch p {
+	case DedupOff, DedupSuggest, DedupMerge:
+		return true
+	}
+	return false
+}
+
+// BBoxAround returns a box that contains the circle of radius metres around the
+// point, clamped to valid coordinates. Boxes are not wrapped across the antimeridian.
+func BBoxAround(lat, lng, radius float64) BBox {
+
+	dLat := radius / metresPerDegree
+	dLng := 180.0
+	if cos := math.Cos(lat * math.Pi / 180); cos > 1e-6 {
+		dLng = math.Min(dLat/cos, 180)
+	}
+
+	return BBox{
+		MinLng: math.Max(lng-dLng, -180),
+		MinLat: math.Max(lat-dLat, -90),
+		MaxLng: math.Min(lng+dLng, 180),
+		MaxLat: math.Min(lat+dLat, 90),
+	}
+}
+
+func (e *DuplicatesError) Error() string {
+	return fmt.Sprintf("%v: %d possible duplicates nearby", ErrConflict, len(e.Candidates))
+}
+
+func (e *DuplicatesError) Unwrap() error {
+	return ErrConflict
+}
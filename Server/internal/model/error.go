@@ -5,3 +5,10 @@ import "errors"
 var ErrorNotFound = errors.New("not found")
 var ErrorTargetTaskNotEmpty = errors.New("target task not empty")
 var ErrInvalidParameter = errors.New("Invalid parameter value")
+var ErrForbidden = errors.New("forbidden")
+var ErrConflict = errors.New("conflict")
+var ErrUnauthorized = errors.New("unauthorized")
+
+// ErrInvalidPushToken is returned by push senders when the provider reports that
+// the device token is no longer valid; the device should be forgotten.
+var ErrInvalidPushToken = errors.New("invalid push token")
@@ -0,0 +1,124 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EventViolationCreated       EventType = "violation.created"
+	EventViolationConfirmed     EventType = "violation.confirmed"
+	EventViolationStatusChanged EventType = "violation.status_changed"
+	EventPhotoAdded             EventType = "photo.added"
+	EventCommentAdded           EventType = "comment.added"
+	EventViolationModerated     EventType = "violation.moderated"
+	EventRuleNotified           EventType = "rule.notified"
+)
+
+type (
+	EventType string
+
+	// Event is a domain event published by the service after the change has been
+	// stored. Subscribers type-switch on the concrete event.
+	Event interface {
+		EventType() EventType
+		// Subject is the violation the event is about, in its state after the event.
+		Subject() *Violation
+		// Actor is the user who caused the event, zero for the system.
+		Actor() UserID
+		// EventID identifies the event; it survives the trip through the outbox.
+		EventID() string
+	}
+
+	// EventMeta is embedded by every event.
+	EventMeta struct {
+		ID string
+		// ActorID is zero for changes made by the system (e.g. rules).
+		ActorID    UserID
+		OccurredAt time.Time
+	}
+
+	ViolationCreated struct {
+		EventMeta
+		Violation *Violation
+		PhotoKeys []string
+	}
+
+	// ViolationConfirmed is published when a user confirms a violation or, with
+	// Withdrawn set, takes the confirmation back.
+	ViolationConfirmed struct {
+		EventMeta
+		Violation *Violation
+		Withdrawn bool
+	}
+
+	// ViolationStatusChanged carries the status history entry. Change.Event is
+	// StatusEventProposeResolve for resolution proposals, which keep the status.
+	ViolationStatusChanged struct {
+		EventMeta
+		Violation *Violation
+		Change    *StatusChange
+	}
+
+	PhotoAdded struct {
+		EventMeta
+		Violation *Violation
+		Kind      string
+		PhotoKeys []string
+	}
+
+	CommentAdded struct {
+		EventMeta
+		Violation *Violation
+		CommentID CommentID
+	}
+
+	// RuleNotified is recorded when a rule with the notify action fires. It is
+	// delivered to inboxes and devices only, not to the realtime feed.
+	RuleNotified struct {
+		EventMeta
+		Violation *Violation
+		Notice    *RuleNotice
+	}
+
+	// RuleNotice is what a notify rule tells the author of the report and, with
+	// ToConfirmers set, the users who confirmed it.
+	RuleNotice struct {
+		Rule         string `json:"rule"`
+		Message      string `json:"message"`
+		ToConfirmers bool   `json:"to_confirmers,omitempty"`
+	}
+
+	// ViolationModerated is published when a moderator approves or rejects a report.
+	// PreviousStatus is the moderation status the report had before the decision.
+	ViolationModerated struct {
+		EventMeta
+		Violation      *Violation
+		Decision       *ModerationDecision
+		PreviousStatus ModerationStatus
+	}
+)
+
+func NewEventMeta(actorID UserID) EventMeta {
+	return EventMeta{ID: uuid.NewString(), ActorID: actorID, OccurredAt: time.Now().UTC()}
+}
+
+func (m EventMeta) Actor() UserID   { return m.ActorID }
+func (m EventMeta) EventID() string { return m.ID }
+
+func (e *ViolationCreated) EventType() EventType       { return EventViolationCreated }
+func (e *ViolationConfirmed) EventType() EventType     { return EventViolationConfirmed }
+func (e *ViolationStatusChanged) EventType() EventType { return EventViolationStatusChanged }
+func (e *PhotoAdded) EventType() EventType             { return EventPhotoAdded }
+func (e *CommentAdded) EventType() EventType           { return EventCommentAdded }
+func (e *ViolationModerated) EventType() EventType     { return EventViolationModerated }
+func (e *RuleNotified) EventType() EventType           { return EventRuleNotified }
+
+func (e *ViolationCreated) Subject() *Violation       { return e.Violation }
+func (e *ViolationConfirmed) Subject() *Violation     { return e.Violation }
+func (e *ViolationStatusChanged) Subject() *Violation { return e.Violation }
+func (e *PhotoAdded) Subject() *Violation             { return e.Violation }
+func (e *CommentAdded) Subject() *Violation           { return e.Violation }
+func (e *ViolationModerated) Subject() *Violation     { return e.Violation }
+func (e *RuleNotified) Subject() *Violation           { return e.Violation }
@@ -1,10 +1,13 @@
 package model
 
 import (
+	"encoding/base64"
+	"fmt"
+	"strings"
 	"time"
+
 	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const (
@@ -12,14 +15,74 @@ const (
 	Refresh_Token_Type        = "refresh_Token"
 )
 
+const (
+	ViolationTypeGarbage       ViolationType = "garbage"
+	ViolationTypePollution     ViolationType = "pollution"
+	ViolationTypeAir           ViolationType = "air"
+	ViolationTypeDeforestation ViolationType = "deforestation"
+	ViolationTypeOther         ViolationType = "other"
+
+	ViolationStatusNew       ViolationStatus = "new"
+	ViolationStatusConfirmed ViolationStatus = "confirmed"
+	ViolationStatusResolved  ViolationStatus = "resolved"
+
+	ResolutionStatusNone     ResolutionStatus = "none"
+	ResolutionStatusProposed ResolutionStatus = "proposed"
+
+	StatusEventChange         = "status_change"
+	StatusEventProposeResolve = "propose_resolve"
+
+	RuleEventCreate         = "create"
+	RuleEventConfirmation   = "confirmation"
+	RuleEventProposeResolve = "propose_resolve"
+
+	RuleActionSetStatus   = "set_status"
+	RuleActionAutoResolve = "auto_resolve"
+	RuleActionNotify      = "notify"
+
+	PhotoKindReport   = "report"
+	PhotoKindEvidence = "evidence"
+
+	PhotoStatusPending  = "pending"
+	PhotoStatusUploaded = "uploaded"
+	PhotoStatusRejected = "rejected"
+
+	PhotoProcessingPending = "pending"
+	PhotoProcessingDone    = "done"
+	PhotoProcessingFailed  = "failed"
+
+	// UploadKeyPrefix holds the objects clients upload; they keep their metadata
+	// and are never served. PhotoKeyPrefix holds the processed copies, which are
+	// public.
+	UploadKeyPrefix = "uploads/"
+	PhotoKeyPrefix  = "photos/"
+
+	ModerationPending  ModerationStatus = "pending"
+	ModerationApproved ModerationStatus = "approved"
+	ModerationRejected ModerationStatus = "rejected"
+
+	ModerationActionApprove = "approve"
+	ModerationActionReject  = "reject"
+
+	RoleUser Role = "user"
+	// RolePartner is given to partner agencies; it grants the data export.
+	RolePartner   Role = "partner"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
 type (
 	ViolationID string
 
-	ViolationType   string
-	ViolationStatus string
+	ViolationType    string
+	ViolationStatus  string
+	ResolutionStatus string
+	ModerationStatus string
+
+	// Role is the access level of a user. Each role includes the rights of the lower ones.
+	Role string
 
 	TaskID     int64
-	PokerID    string
 	UserID     int64
 	Estimate   int64
 	CommentID  int64
@@ -38,28 +101,283 @@ type (
 	User struct {
 		ID                 UserID
 		Name               string
+		Role               Role
 		EvaluationStrategy string
 		MaximumScore       int
 	}
 
 	Violation struct {
-		ID                  ViolationID     `json:"id"`
-		UserID              UserID          `json:"user_id"`
-		Type                ViolationType   `json:"type"`
-		Description         string          `json:"description"`
-		Lat                 float64         `json:"lat"`
-		Lng                 float64         `json:"lng"`
-		Status              ViolationStatus `json:"status"`
-		ConfirmationsCount  int             `json:"confirmations_count"`
-		CreatedAt           time.Time       `json:"created_at"`
-		UpdatedAt           time.Time       `json:"updated_at"`
-	}
-
-	LastSessionPoker struct {
-		PokerID     PokerID
-		UserID      UserID
-		Name        string
-	    IsAdmin     bool
+		ID                 ViolationID      `json:"id"`
+		UserID             UserID           `json:"user_id"`
+		Type               ViolationType    `json:"type"`
+		Description        string           `json:"description"`
+		Lat                float64          `json:"lat"`
+		Lng                float64          `json:"lng"`
+		Status             ViolationStatus  `json:"status"`
+		ResolutionStatus   ResolutionStatus `json:"resolution_status"`
+		ConfirmationsCount int              `json:"confirmations_count"`
+		CreatedAt          time.Time        `json:"created_at"`
+		UpdatedAt          time.Time        `json:"updated_at"`
+
+		// Only approved violations are shown publicly. The score, reason and
+		// moderator are internal; moderators see them through ModeratedViolation.
+		ModerationStatus ModerationStatus `json:"moderation_status"`
+		ModerationScore  *float64         `json:"-"`
+		ModerationReason string           `json:"-"`
+		ModeratedBy      *UserID          `json:"-"`
+		ModeratedAt      *time.Time       `json:"moderated_at,omitempty"`
+	}
+
+	// ModeratedViolation is a violation as moderators see it, with the details
+	// of its moderation.
+	ModeratedViolation struct {
+		*Violation
+		ModerationScore  *float64 `json:"moderation_score,omitempty"`
+		ModerationReason string   `json:"moderation_reason,omitempty"`
+		ModeratedBy      *UserID  `json:"moderated_by,omitempty"`
+	}
+
+	// Viewer is the user a violation is shown to. It is zero for anonymous requests.
+	Viewer struct {
+		UserID UserID
+		Role   Role
+	}
+
+	// ModerationRequest is what automated moderation gets to score: a report or,
+	// when CommentID is set, a comment on it.
+	ModerationRequest struct {
+		ViolationID ViolationID
+		CommentID   CommentID
+		Text        string
+		PhotoURLs   []string
+	}
+
+	// ModerationResult is the score of automated moderation: 0 is clean, 1 is
+	// certainly inappropriate.
+	ModerationResult struct {
+		ViolationID ViolationID `json:"violation_id"`
+		CommentID   CommentID   `json:"comment_id,omitempty"`
+		Score       float64     `json:"score"`
+		Reason      string      `json:"reason"`
+	}
+
+	// ModerationDecision is a moderator's approve or reject of a report.
+	// ModeratorID is zero for decisions of automated moderation.
+	ModerationDecision struct {
+		ViolationID ViolationID
+		Status      ModerationStatus
+		Reason      string
+		ModeratorID UserID
+	}
+
+	// UserBrief is the public part of a user shown next to reports and confirmations.
+	UserBrief struct {
+		ID   UserID `json:"id"`
+		Name string `json:"name"`
+		Role Role   `json:"role,omitempty"`
+	}
+
+	// ViolationDetails is the composed response of the violation detail view.
+	ViolationDetails struct {
+		Violation     *Violation      `json:"violation"`
+		Author        *UserBrief      `json:"author"`
+		Photos        []*Photo        `json:"photos"`
+		Confirmations []*Confirmation `json:"confirmations"`
+		StatusHistory []*StatusChange `json:"status_history"`
+		// ModerationReason is shown to the author and moderators only.
+		ModerationReason string `json:"moderation_reason,omitempty"`
+	}
+
+	// Photo is an uploaded image. ViolationID is empty until the photo is attached to a report.
+	// URL and Variants are only exposed once processing has stripped the metadata.
+	Photo struct {
+		ID               string                   `json:"id"`
+		ViolationID      ViolationID              `json:"violation_id,omitempty"`
+		UserID           UserID                   `json:"user_id"`
+		StorageKey       string                   `json:"-"`
+		Kind             string                   `json:"kind"`
+		Status           string                   `json:"status"`
+		ProcessingStatus string                   `json:"processing_status"`
+		URL              string                   `json:"url,omitempty"`
+		Mime             string                   `json:"mime"`
+		Size             int64                    `json:"size"`
+		Variants         map[string]*PhotoVariant `json:"variants,omitempty"`
+		CreatedAt        time.Time                `json:"created_at"`
+	}
+
+	PhotoVariant struct {
+		StorageKey string `json:"-"`
+		URL        string `json:"url,omitempty"`
+		Mime       string `json:"mime"`
+		Width      int    `json:"width"`
+		Height     int    `json:"height"`
+		Size       int64  `json:"size"`
+	}
+
+	// PresignedUpload tells the client where and how to upload the bytes of a photo.
+	PresignedUpload struct {
+		PhotoID    string            `json:"photo_id"`
+		StorageKey string            `json:"storage_key"`
+		URL        string            `json:"url"`
+		Method     string            `json:"method"`
+		Headers    map[string]string `json:"headers"`
+		ExpiresAt  time.Time         `json:"expires_at"`
+	}
+
+	ObjectInfo struct {
+		Key         string
+		Size        int64
+		ContentType string
+	}
+
+	Confirmation struct {
+		ViolationID ViolationID `json:"violation_id"`
+		User        UserBrief   `json:"user"`
+		CreatedAt   time.Time   `json:"created_at"`
+	}
+
+	// StatusChange is an entry of the violation status history. ActorID is zero
+	// when the transition was made by the system rather than a user. Evidence
+	// holds storage keys and is not exposed; EvidencePhotoIDs refer to the
+	// photos of the violation details instead.
+	StatusChange struct {
+		ViolationID      ViolationID     `json:"violation_id"`
+		Event            string          `json:"event"`
+		FromStatus       ViolationStatus `json:"from_status"`
+		ToStatus         ViolationStatus `json:"to_status"`
+		ActorID          UserID          `json:"actor_id"`
+		Reason           string          `json:"reason"`
+		Evidence         []string        `json:"-"`
+		EvidencePhotoIDs []string        `json:"evidence_photo_ids,omitempty"`
+		CreatedAt        time.Time       `json:"created_at"`
+	}
+
+	// ViolationRule is a row of violation_rules. Threshold is compared with the
+	// weighted number of confirmations; a nil threshold fires on the condition alone.
+	ViolationRule struct {
+		ID           string
+		Name         string
+		TriggerEvent string
+		Condition    RuleCondition
+		Threshold    *int
+		WeightMap    map[string]int
+		Action       string
+		ActionParams map[string]any
+	}
+
+	// RuleCondition holds the supported keys of violation_rules.condition.
+	RuleCondition struct {
+		ActorRole        *string `json:"actor_role,omitempty"`
+		MinPhotos        *int    `json:"min_photos,omitempty"`
+		AuthorIsReporter *bool   `json:"author_is_reporter,omitempty"`
+	}
+
+	// RuleEvent is what the rules engine evaluates rules against.
+	RuleEvent struct {
+		Type       string
+		Violation  *Violation
+		ActorID    UserID
+		ActorRole  string
+		PhotoCount int
+		// VoterRoles holds the role of every user who has confirmed the violation.
+		VoterRoles []string
+	}
+
+	RuleFiring struct {
+		Rule  *ViolationRule
+		Score int
+	}
+
+	// BBox is a WGS84 bounding box, longitudes first as in the bbox query parameter.
+	BBox struct {
+		MinLng float64
+		MinLat float64
+		MaxLng float64
+		MaxLat float64
+	}
+
+	// ViolationCursor is a keyset position in the (created_at, id) ordering of violations.
+	ViolationCursor struct {
+		CreatedAt time.Time
+		ID        ViolationID
+	}
+
+	ViolationFilter struct {
+		BBox        *BBox
+		Types       []ViolationType
+		Statuses    []ViolationStatus
+		CreatedFrom *time.Time
+		CreatedTo   *time.Time
+		Cursor      *ViolationCursor
+		Limit       int
+		// ModerationStatus restricts the list to one moderation state; empty means any.
+		ModerationStatus ModerationStatus
+	}
+
+	ViolationsPage struct {
+		Items      []*Violation `json:"items"`
+		NextCursor string       `json:"next_cursor,omitempty"`
+	}
+
+	// ExportedViolation is a violation as published to data partners. The author
+	// is replaced by Reporter, a pseudonym that is stable across exports but
+	// cannot be traced back to the user.
+	ExportedViolation struct {
+		ID                 ViolationID
+		Type               ViolationType
+		Description        string
+		Lat                float64
+		Lng                float64
+		Status             ViolationStatus
+		ConfirmationsCount int
+		CreatedAt          time.Time
+		UpdatedAt          time.Time
+		Reporter           string
+	}
+
+	// ViolationStats summarises the violations matching a filter. LastUpdatedAt
+	// is nil when there are none.
+	ViolationStats struct {
+		Count         int
+		LastUpdatedAt *time.Time
+	}
+
+	// VectorTile is an encoded Mapbox Vector Tile. NotModified is set instead of
+	// Data when the tile still has the ETag the client sent.
+	VectorTile struct {
+		Data        []byte
+		ETag        string
+		NotModified bool
+	}
+
+	// ClusterCell is the number of violations of one type and status in a grid
+	// cell; Lat and Lng are their mean position. ViolationID is one of them.
+	ClusterCell struct {
+		X, Y        int64
+		Type        ViolationType
+		Status      ViolationStatus
+		Count       int
+		Lat, Lng    float64
+		ViolationID ViolationID
+	}
+
+	// ViolationCluster is a map marker for the violations of a grid cell, placed
+	// at their centroid. ViolationID is set when the cell holds a single violation.
+	ViolationCluster struct {
+		Lat         float64                 `json:"lat"`
+		Lng         float64                 `json:"lng"`
+		Count       int                     `json:"count"`
+		ByType      map[ViolationType]int   `json:"by_type"`
+		ByStatus    map[ViolationStatus]int `json:"by_status"`
+		ViolationID ViolationID             `json:"violation_id,omitempty"`
+	}
+
+	// ViolationClusters is the map content of a bbox: clusters at low zoom, the
+	// violations themselves once few enough of them are in view.
+	ViolationClusters struct {
+		Zoom     int                 `json:"zoom"`
+		Clusters []*ViolationCluster `json:"clusters"`
+		Points   []*Violation        `json:"points"`
 	}
 
 	UserSettings struct {
@@ -84,9 +402,44 @@ type (
 
 
 
+	// Session is one refresh token. Rotation marks the token used and issues the
+	// next one in the same family; the token itself is never stored, only its hash.
+	Session struct {
+		ID        string
+		FamilyID  string
+		UserID    UserID
+		TokenHash string
+		CreatedAt time.Time
+		ExpiresAt time.Time
+		UsedAt    *time.Time
+		RevokedAt *time.Time
+		Client    ClientInfo
+	}
+
+	// ClientInfo describes the device a session was opened from.
+	ClientInfo struct {
+		UserAgent string
+		IP        string
+	}
+
+	// DeviceSession is a session family as shown to the user: one per logged-in device.
+	DeviceSession struct {
+		ID         string    `json:"id"`
+		UserAgent  string    `json:"user_agent"`
+		IP         string    `json:"ip"`
+		CreatedAt  time.Time `json:"created_at"`
+		LastSeenAt time.Time `json:"last_seen_at"`
+		ExpiresAt  time.Time `json:"expires_at"`
+		Current    bool      `json:"current"`
+	}
+
 	Claims struct {
 		UserID    UserID `json:"user_id"`
 		TokenType string `json:"token_type"` // Добавляем поле для типа токена
+		// Role is set in access tokens only.
+		Role Role `json:"role,omitempty"`
+		// SessionID is the session family the token belongs to.
+		SessionID string `json:"sid,omitempty"`
 		jwt.StandardClaims
 	}
 
@@ -94,9 +447,83 @@ type (
 	
 )
 
-func (p PokerID) UUID() pgtype.UUID {
-	return pgtype.UUID{
-		Bytes: uuid.MustParse(string(p)),
-		Valid: true,
+func (t ViolationType) Valid() bool {
+	switch t {
+	case ViolationTypeGarbage, ViolationTypePollution, ViolationTypeAir, ViolationTypeDeforestation, ViolationTypeOther:
+		return true
 	}
+	return false
+}
+
+// NewModeratedViolation returns the moderators' view of v.
+func NewModeratedViolation(v *Violation) *ModeratedViolation {
+	return &ModeratedViolation{
+		Violation:        v,
+		ModerationScore:  v.ModerationScore,
+		ModerationReason: v.ModerationReason,
+		ModeratedBy:      v.ModeratedBy,
+	}
+}
+
+// CanSee reports whether the viewer may see the violation: approved reports are
+// public, the others are visible to their author and to moderators.
+func (v Viewer) CanSee(violation *Violation) bool {
+	return violation.ModerationStatus == ModerationApproved ||
+		(v.UserID != 0 && v.UserID == violation.UserID) ||
+		v.Role.AtLeast(RoleModerator)
+}
+
+func (r Role) Valid() bool {
+	_, ok := roleRanks[r]
+	return ok
+}
+
+// AtLeast reports whether r grants the rights of min.
+func (r Role) AtLeast(min Role) bool {
+	rank, ok := roleRanks[r]
+	return ok && rank >= roleRanks[min]
+}
+
+var roleRanks = map[Role]int{
+	RoleUser:      1,
+	RolePartner:   2,
+	RoleModerator: 3,
+	RoleAdmin:     4,
+}
+
+func (s ViolationStatus) Valid() bool {
+	switch s {
+	case ViolationStatusNew, ViolationStatusConfirmed, ViolationStatusResolved:
+		return true
+	}
+	return false
+}
+
+// Encode returns the opaque cursor string handed to clients as next_cursor.
+func (c ViolationCursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + string(c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func ParseViolationCursor(s string) (*ViolationCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: cursor", ErrInvalidParameter)
+	}
+
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, fmt.Errorf("%w: cursor", ErrInvalidParameter)
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return nil, fmt.Errorf("%w: cursor", ErrInvalidParameter)
+	}
+
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("%w: cursor", ErrInvalidParameter)
+	}
+
+	return &ViolationCursor{CreatedAt: t, ID: ViolationID(id)}, nil
 }
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	// NotificationStatusChanged: a report the user created or confirmed changed status.
+	NotificationStatusChanged = "violation.status_changed"
+	// NotificationCommentAdded: a report the user created or confirmed got a comment.
+	NotificationCommentAdded = "violation.comment_added"
+	// NotificationRuleNotice: a notify rule fired on a report the user created or confirmed.
+	NotificationRuleNotice = "violation.rule_notice"
+)
+
+type (
+	NotificationID int64
+
+	// Notification is an entry of a user's in-app inbox. Payload holds the details
+	// of the event; its shape depends on Type.
+	Notification struct {
+		ID          NotificationID  `json:"id"`
+		UserID      UserID          `json:"-"`
+		Type        string          `json:"type"`
+		ViolationID ViolationID     `json:"violation_id,omitempty"`
+		Payload     json.RawMessage `json:"payload"`
+		Read        bool            `json:"read"`
+		CreatedAt   time.Time       `json:"created_at"`
+		// EventID is the event the notification is about. A user gets at most one
+		// notification per event, however often the event is delivered.
+		EventID string `json:"-"`
+	}
+
+	NotificationFilter struct {
+		UserID     UserID
+		UnreadOnly bool
+		// BeforeID continues a listing below the last returned id.
+		BeforeID NotificationID
+		Limit    int
+	}
+
+	NotificationPage struct {
+		Items       []*Notification `json:"items"`
+		NextCursor  string          `json:"next_cursor,omitempty"`
+		UnreadCount int             `json:"unread_count"`
+	}
+)
@@ -0,0 +1,84 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusDone    = "done"
+	// OutboxStatusDead marks messages that exhausted their attempts or have no
+	// handler; they stay until an admin replays them.
+	OutboxStatusDead = "dead"
+
+	// OutboxTopicInboxEvent adds inbox notifications about a domain event.
+	OutboxTopicInboxEvent = "inbox.event"
+	// OutboxTopicPushEvent queues push notifications about a domain event.
+	OutboxTopicPushEvent = "push.event"
+	// OutboxTopicPushSend delivers one push notification to one device.
+	OutboxTopicPushSend = "push.send"
+	// OutboxTopicModerationSubmit sends a new report to automated moderation.
+	OutboxTopicModerationSubmit = "moderation.submit"
+)
+
+type (
+	// OutboxMessage is a side effect recorded in the transaction of the change
+	// that caused it and carried out afterwards by the relay, at least once.
+	OutboxMessage struct {
+		ID            int64           `json:"id"`
+		Topic         string          `json:"topic"`
+		Payload       json.RawMessage `json:"payload"`
+		Status        string          `json:"status"`
+		Attempts      int             `json:"attempts"`
+		NextAttemptAt time.Time       `json:"next_attempt_at"`
+		LastError     string          `json:"last_error,omitempty"`
+		CreatedAt     time.Time       `json:"created_at"`
+		ProcessedAt   *time.Time      `json:"processed_at,omitempty"`
+	}
+
+	OutboxFilter struct {
+		Status string
+		Topic  string
+		// BeforeID continues a listing below the last returned id.
+		BeforeID int64
+		Limit    int
+	}
+
+	OutboxPage struct {
+		Items      []*OutboxMessage `json:"items"`
+		NextCursor string           `json:"next_cursor,omitempty"`
+	}
+
+	// EventRecord is the payload of event topics: enough of a domain event to
+	// rebuild it around the current state of the violation.
+	EventRecord struct {
+		// ID is the id of the event; records written before it was added have none.
+		ID          string        `json:"id,omitempty"`
+		Type        EventType     `json:"type"`
+		ViolationID ViolationID   `json:"violation_id"`
+		ActorID     UserID        `json:"actor_id,omitempty"`
+		OccurredAt  time.Time     `json:"occurred_at"`
+		Withdrawn   bool          `json:"withdrawn,omitempty"`
+		Change      *StatusChange `json:"change,omitempty"`
+		CommentID   CommentID     `json:"comment_id,omitempty"`
+		Notice      *RuleNotice   `json:"notice,omitempty"`
+		// Status and ConfirmationsCount are the state of the violation right
+		// after the event; deliveries describe the event with them rather than
+		// with the state at delivery time.
+		Status             ViolationStatus `json:"status,omitempty"`
+		ConfirmationsCount int             `json:"confirmations_count,omitempty"`
+	}
+
+	// ModerationSubmission is the payload of OutboxTopicModerationSubmit. It is
+	// about a comment when CommentID is set.
+	ModerationSubmission struct {
+		ViolationID ViolationID `json:"violation_id"`
+		CommentID   CommentID   `json:"comment_id,omitempty"`
+		PhotoKeys   []string    `json:"photo_keys,omitempty"`
+	}
+)
+
+func ValidOutboxStatus(status string) bool {
+	return status == OutboxStatusPending || status == OutboxStatusDone || status == OutboxStatusDead
+}
@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+const (
+	PlatformAndroid = "android"
+	PlatformIOS     = "ios"
+)
+
+type (
+	// Device is a phone registered for push notifications.
+	Device struct {
+		ID        int64     `json:"id"`
+		UserID    UserID    `json:"user_id"`
+		Platform  string    `json:"platform"`
+		Token     string    `json:"token"`
+		CreatedAt time.Time `json:"created_at"`
+		UpdatedAt time.Time `json:"updated_at"`
+	}
+
+	// PushMessage is one notification for one device, the payload of
+	// OutboxTopicPushSend.
+	PushMessage struct {
+		UserID   UserID `json:"user_id"`
+		Platform string `json:"platform"`
+		Token    string `json:"token"`
+		Title    string `json:"title"`
+		Body     string `json:"body"`
+		// Data is delivered to the app alongside the notification.
+		Data map[string]string `json:"data,omitempty"`
+	}
+)
+
+func ValidPlatform(platform string) bool {
+	return platform == PlatformAndroid || platform == PlatformIOS
+}
@@ -0,0 +1,64 @@
+package model
+
+import "time"
+
+const (
+	// SyncCreate submits a report queued offline; ViolationID is its client-side UUID.
+	SyncCreate SyncOperationType = "create"
+	// SyncConfirm confirms the violation.
+	SyncConfirm SyncOperationType = "confirm"
+	// SyncUnconfirm withdraws a confirmation of the violation.
+	SyncUnconfirm SyncOperationType = "unconfirm"
+	// SyncProposeResolve suggests that the violation has been fixed.
+	SyncProposeResolve SyncOperationType = "propose_resolve"
+)
+
+const (
+	// SyncApplied means the operation was applied or had been applied before.
+	SyncApplied SyncStatus = "applied"
+	// SyncConflict means the server copy has changed since the client's base
+	// or no longer allows the operation. The operation was not applied.
+	SyncConflict SyncStatus = "conflict"
+	// SyncFailed means the operation was rejected; the result's Error says why.
+	SyncFailed SyncStatus = "failed"
+)
+
+type (
+	SyncOperationType string
+	SyncStatus        string
+
+	// SyncOperation is a change queued by a client while it was offline.
+	// BaseUpdatedAt is the updated_at of the violation the client made the change
+	// against; when it is set and the server copy is newer, the change is reported
+	// as a conflict instead of being applied.
+	SyncOperation struct {
+		ID            string
+		Type          SyncOperationType
+		ViolationID   ViolationID
+		BaseUpdatedAt *time.Time
+		// Draft is the report of SyncCreate.
+		Draft *ViolationDraft
+		// Comment and PhotoKeys are the evidence of SyncProposeResolve.
+		Comment   string
+		PhotoKeys []string
+	}
+
+	// SyncResult is the outcome of a SyncOperation. Violation is the server copy
+	// the client should store; it is nil when the client may not see the report.
+	SyncResult struct {
+		OperationID string                `json:"op_id"`
+		Status      SyncStatus            `json:"status"`
+		Violation   *Violation            `json:"violation,omitempty"`
+		Merged      bool                  `json:"merged,omitempty"`
+		Duplicates  []*DuplicateCandidate `json:"duplicates,omitempty"`
+		Error       string                `json:"error,omitempty"`
+	}
+)
+
+func (t SyncOperationType) Valid() bool {
+	switch t {
+	case SyncCreate, SyncConfirm, SyncUnconfirm, SyncProposeResolve:
+		return true
+	}
+	return false
+}
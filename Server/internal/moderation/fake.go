@@ -0,0 +1,61 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+type (
+	// FakeRule scores text matching Pattern with Score.
+	FakeRule struct {
+		Pattern *regexp.Regexp
+		Score   float64
+		Label   string
+	}
+
+	// FakeModerator is a deterministic stand-in for the ML service: the score of a
+	// report is the highest score of the rules its text matches, 0 otherwise.
+	// Photos are not looked at.
+	FakeModerator struct {
+		rules []FakeRule
+	}
+)
+
+// DefaultFakeRules cover the three outcomes with the default thresholds:
+// links go to the queue, spam and insults are rejected. Words are matched on
+// letter boundaries because \b only knows ASCII letters.
+var DefaultFakeRules = []FakeRule{
+	{Pattern: regexp.MustCompile(`(?i)https?://`), Score: 0.5, Label: "link"},
+	{Pattern: regexp.MustCompile(`(?i)(^|\P{L})(casino|viagra|crypto|казино)(\P{L}|$)`), Score: 0.9, Label: "spam"},
+	{Pattern: regexp.MustCompile(`(?i)(^|\P{L})(idiot|stupid|moron|идиот|дурак)(\P{L}|$)`), Score: 0.8, Label: "toxic"},
+}
+
+func NewFakeModerator(rules []FakeRule) *FakeModerator {
+	return &FakeModerator{rules: rules}
+}
+
+func (m *FakeModerator) Moderate(ctx context.Context, req *model.ModerationRequest) (*model.ModerationResult, error) {
+
+	result := &model.ModerationResult{ViolationID: req.ViolationID, CommentID: req.CommentID}
+
+	var labels []string
+	for _, rule := range m.rules {
+		if !rule.Pattern.MatchString(req.Text) {
+			continue
+		}
+		labels = append(labels, rule.Label)
+		if rule.Score > result.Score {
+			result.Score = rule.Score
+		}
+	}
+
+	if len(labels) > 0 {
+		result.Reason = fmt.Sprintf("matched: %s", strings.Join(labels, ", "))
+	}
+
+	return result, nil
+}
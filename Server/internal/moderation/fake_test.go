@@ -0,0 +1,50 @@
+package moderation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+func TestFakeModerator(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		text   string
+		score  float64
+		reason string
+	}{
+		{name: "clean", text: "Garbage dump next to the river bank", score: 0},
+		{name: "link", text: "photos at https://example.com/dump", score: 0.5, reason: "matched: link"},
+		{name: "spam", text: "Best CASINO in town", score: 0.9, reason: "matched: spam"},
+		{name: "cyrillic spam", text: "лучшее казино города", score: 0.9, reason: "matched: spam"},
+		{name: "toxic", text: "the owner is an idiot", score: 0.8, reason: "matched: toxic"},
+		{name: "word inside a word", text: "cryptography lecture notes", score: 0},
+		{name: "cyrillic word inside a word", text: "дураки", score: 0},
+		{name: "highest score wins", text: "idiot, see http://x.io for crypto", score: 0.9, reason: "matched: link, spam, toxic"},
+	}
+
+	m := NewFakeModerator(DefaultFakeRules)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			req := &model.ModerationRequest{ViolationID: "v-1", CommentID: 7, Text: tt.text}
+			res, err := m.Moderate(context.Background(), req)
+			if err != nil {
+				t.Fatalf("Moderate: %v", err)
+			}
+
+			if res.Score != tt.score {
+				t.Errorf("score = %g, want %g", res.Score, tt.score)
+			}
+			if res.Reason != tt.reason {
+				t.Errorf("reason = %q, want %q", res.Reason, tt.reason)
+			}
+			if res.ViolationID != req.ViolationID || res.CommentID != req.CommentID {
+				t.Errorf("result is for %s/%d, want %s/%d", res.ViolationID, res.CommentID, req.ViolationID, req.CommentID)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+type (
+	HTTPConfig struct {
+		// URL is the endpoint of the moderation service that accepts submissions.
+		URL string
+		// CallbackURL is sent with each submission; the service posts its result
+		// there when it answers asynchronously.
+		CallbackURL string
+		// Secret is sent in SecretHeader so the service can authenticate us and
+		// is expected back on the callback.
+		Secret  string
+		Timeout time.Duration
+	}
+
+	// HTTPModerator submits reports and comments to the external moderation service
+	// (nsfw_detector/detoxify). The service answers either 200 with the result
+	// or 202 and posts the result to the callback later.
+	HTTPModerator struct {
+		config HTTPConfig
+		client *http.Client
+	}
+
+	submitRequest struct {
+		ViolationID model.ViolationID `json:"violation_id"`
+		CommentID   model.CommentID   `json:"comment_id,omitempty"`
+		Text        string            `json:"text"`
+		PhotoURLs   []string          `json:"photo_urls"`
+		CallbackURL string            `json:"callback_url,omitempty"`
+	}
+)
+
+// SecretHeader carries the shared secret in both directions.
+const SecretHeader = "X-Moderation-Secret"
+
+func NewHTTPModerator(config HTTPConfig) (*HTTPModerator, error) {
+
+	if u, err := url.Parse(config.URL); err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid moderation url %q", config.URL)
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Second
+	}
+
+	return &HTTPModerator{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}, nil
+}
+
+// Moderate returns nil and no error when the service accepted the report for
+// asynchronous processing.
+func (m *HTTPModerator) Moderate(ctx context.Context, req *model.ModerationRequest) (*model.ModerationResult, error) {
+
+	body, err := json.Marshal(submitRequest{
+		ViolationID: req.ViolationID,
+		CommentID:   req.CommentID,
+		Text:        req.Text,
+		PhotoURLs:   req.PhotoURLs,
+		CallbackURL: m.config.CallbackURL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, m.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set(SecretHeader, m.config.Secret)
+
+	resp, err := m.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("moderation service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusAccepted:
+		return nil, nil
+
+	case http.StatusOK:
+		var result model.ModerationResult
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return nil, fmt.Errorf("moderation service: decode result: %w", err)
+		}
+		result.ViolationID = req.ViolationID
+		result.CommentID = req.CommentID
+		return &result, nil
+
+	default:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("moderation service: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+}
@@ -0,0 +1,3 @@
+// Package moderation provides the automated content moderators: a client for the
+// external ML moderation service and a deterministic keyword based stand-in.
+package moderation
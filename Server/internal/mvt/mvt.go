@@ -0,0 +1,282 @@
+// Package mvt encodes point features as Mapbox Vector Tiles (specification 2.1)
+// and converts between WGS84 coordinates and Web Mercator tile coordinates.
+// The protobuf encoding is written by hand, so the package has no dependencies.
+package mvt
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// DefaultExtent is the number of units along a tile edge.
+const DefaultExtent = 4096
+
+// MaxZoom is the highest zoom level accepted by ValidTile.
+const MaxZoom = 22
+
+// maxLat is the latitude at which Web Mercator tiles end.
+const maxLat = 85.05112878
+
+// Protobuf field numbers of vector_tile.proto.
+const (
+	fieldTileLayers = 3
+
+	fieldLayerName     = 1
+	fieldLayerFeatures = 2
+	fieldLayerKeys     = 3
+	fieldLayerValues   = 4
+	fieldLayerExtent   = 5
+	fieldLayerVersion  = 15
+
+	fieldFeatureID       = 1
+	fieldFeatureTags     = 2
+	fieldFeatureType     = 3
+	fieldFeatureGeometry = 4
+
+	fieldValueString = 1
+	fieldValueDouble = 3
+	fieldValueSint   = 6
+	fieldValueBool   = 7
+
+	wireVarint = 0
+	wire64Bit  = 1
+	wireBytes  = 2
+
+	geomTypePoint = 1
+	commandMoveTo = 1
+)
+
+type (
+	// Layer is a named set of features sharing one coordinate extent.
+	Layer struct {
+		Name     string
+		Extent   uint32
+		Features []Feature
+	}
+
+	// Feature is a point in tile coordinates: (0, 0) is the top left corner of
+	// the tile and (Extent, Extent) the bottom right one. Points in the buffer
+	// around the tile have coordinates outside that range.
+	Feature struct {
+		ID uint64
+		X  int64
+		Y  int64
+		// Properties hold string, bool, int, int64 and float64 values.
+		Properties map[string]any
+	}
+
+	// Bounds is the WGS84 area covered by a tile.
+	Bounds struct {
+		MinLng, MinLat, MaxLng, MaxLat float64
+	}
+)
+
+// ValidTile reports whether z/x/y addresses an existing tile.
+func ValidTile(z, x, y int) bool {
+	if z < 0 || z > MaxZoom {
+		return false
+	}
+	n := 1 << z
+	return x >= 0 && x < n && y >= 0 && y < n
+}
+
+// TileBounds returns the area of tile z/x/y, widened on every side by buffer
+// tile units of the given extent.
+func TileBounds(z, x, y int, extent, buffer uint32) Bounds {
+
+	n := math.Exp2(float64(z))
+	pad := float64(buffer) / float64(extent)
+
+	return Bounds{
+		MinLng: math.Max(tileLng(float64(x)-pad, n), -180),
+		MaxLng: math.Min(tileLng(float64(x+1)+pad, n), 180),
+		MinLat: math.Max(tileLat(float64(y+1)+pad, n), -maxLat),
+		MaxLat: math.Min(tileLat(float64(y)-pad, n), maxLat),
+	}
+}
+
+// Project returns the position of a WGS84 point in tile z/x/y.
+func Project(lat, lng float64, z, x, y int, extent uint32) (int64, int64) {
+
+	n := math.Exp2(float64(z))
+	lat = math.Max(math.Min(lat, maxLat), -maxLat)
+	rad := lat * math.Pi / 180
+
+	worldX := (lng + 180) / 360 * n
+	worldY := (1 - math.Log(math.Tan(rad)+1/math.Cos(rad))/math.Pi) / 2 * n
+
+	return int64(math.Round((worldX - float64(x)) * float64(extent))),
+		int64(math.Round((worldY - float64(y)) * float64(extent)))
+}
+
+func tileLng(x, n float64) float64 {
+	return x/n*360 - 180
+}
+
+func tileLat(y, n float64) float64 {
+	return math.Atan(math.Sinh(math.Pi*(1-2*y/n))) * 180 / math.Pi
+}
+
+// Encode returns the tile holding the layers. Layers without features are skipped.
+func Encode(layers ...*Layer) ([]byte, error) {
+
+	var tile []byte
+	for _, l := range layers {
+		if len(l.Features) == 0 {
+			continue
+		}
+		layer, err := l.encode()
+		if err != nil {
+			return nil, fmt.Errorf("mvt: layer %s: %w", l.Name, err)
+		}
+		tile = appendBytes(tile, fieldTileLayers, layer)
+	}
+
+	return tile, nil
+}
+
+func (l *Layer) encode() ([]byte, error) {
+
+	extent := l.Extent
+	if extent == 0 {
+		extent = DefaultExtent
+	}
+
+	var (
+		keys       []string
+		keyIndex   = make(map[string]uint32)
+		values     [][]byte
+		valueIndex = make(map[any]uint32)
+		features   [][]byte
+	)
+
+	for _, f := range l.Features {
+
+		names := make([]string, 0, len(f.Properties))
+		for name := range f.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		tags := make([]uint64, 0, 2*len(names))
+		for _, name := range names {
+			value := f.Properties[name]
+			if value == nil {
+				continue
+			}
+
+			k, ok := keyIndex[name]
+			if !ok {
+				k = uint32(len(keys))
+				keyIndex[name] = k
+				keys = append(keys, name)
+			}
+
+			v, ok := valueIndex[value]
+			if !ok {
+				encoded, err := encodeValue(value)
+				if err != nil {
+					return nil, fmt.Errorf("property %s: %w", name, err)
+				}
+				v = uint32(len(values))
+				valueIndex[value] = v
+				values = append(values, encoded)
+			}
+
+			tags = append(tags, uint64(k), uint64(v))
+		}
+
+		var feature []byte
+		if f.ID != 0 {
+			feature = appendVarintField(feature, fieldFeatureID, f.ID)
+		}
+		if len(tags) > 0 {
+			feature = appendPacked(feature, fieldFeatureTags, tags)
+		}
+		feature = appendVarintField(feature, fieldFeatureType, geomTypePoint)
+		feature = appendPacked(feature, fieldFeatureGeometry, []uint64{
+			commandMoveTo | 1<<3,
+			zigzag(f.X),
+			zigzag(f.Y),
+		})
+		features = append(features, feature)
+	}
+
+	layer := appendVarintField(nil, fieldLayerVersion, 2)
+	layer = appendBytes(layer, fieldLayerName, []byte(l.Name))
+	for _, f := range features {
+		layer = appendBytes(layer, fieldLayerFeatures, f)
+	}
+	for _, k := range keys {
+		layer = appendBytes(layer, fieldLayerKeys, []byte(k))
+	}
+	for _, v := range values {
+		layer = appendBytes(layer, fieldLayerValues, v)
+	}
+	layer = appendVarintField(layer, fieldLayerExtent, uint64(extent))
+
+	return layer, nil
+}
+
+func encodeValue(value any) ([]byte, error) {
+	switch v := value.(type) {
+	case string:
+		return appendBytes(nil, fieldValueString, []byte(v)), nil
+	case bool:
+		b := uint64(0)
+		if v {
+			b = 1
+		}
+		return appendVarintField(nil, fieldValueBool, b), nil
+	case int:
+		return appendVarintField(nil, fieldValueSint, zigzag(int64(v))), nil
+	case int64:
+		return appendVarintField(nil, fieldValueSint, zigzag(v)), nil
+	case float64:
+		return appendFixed64Field(nil, fieldValueDouble, math.Float64bits(v)), nil
+	}
+	return nil, fmt.Errorf("unsupported value type %T", value)
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+func appendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+func appendKey(b []byte, field, wireType int) []byte {
+	return appendVarint(b, uint64(field<<3|wireType))
+}
+
+func appendVarintField(b []byte, field int, v uint64) []byte {
+	return appendVarint(appendKey(b, field, wireVarint), v)
+}
+
+func appendFixed64Field(b []byte, field int, v uint64) []byte {
+	b = appendKey(b, field, wire64Bit)
+	for i := 0; i < 8; i++ {
+		b = append(b, byte(v>>(8*i)))
+	}
+	return b
+}
+
+func appendBytes(b []byte, field int, data []byte) []byte {
+	b = appendKey(b, field, wireBytes)
+	b = appendVarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+func appendPacked(b []byte, field int, values []uint64) []byte {
+	var packed []byte
+	for _, v := range values {
+		packed = appendVarint(packed, v)
+	}
+	return appendBytes(b, field, packed)
+}
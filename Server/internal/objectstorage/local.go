@@ -0,0 +1,273 @@
+package objectstorage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+const (
+	localParamKey       = "key"
+	localParamExpires   = "expires"
+	localParamSize      = "size"
+	localParamSignature = "signature"
+)
+
+type (
+	LocalConfig struct {
+		// Dir is the directory objects are stored in.
+		Dir string
+		// BaseURL is the externally visible URL the storage handler is mounted at,
+		// e.g. http://localhost:8090/api/storage.
+		BaseURL string
+		// Secret signs upload URLs.
+		Secret string
+	}
+
+	// LocalStorage keeps objects on the local filesystem. It is meant for development
+	// and tests: it also serves as the HTTP endpoint presigned uploads are sent to.
+	// Only processed photos, under model.PhotoKeyPrefix, are served.
+	LocalStorage struct {
+		dir     string
+		baseURL string
+		secret  []byte
+	}
+)
+
+func NewLocalStorage(config LocalConfig) (*LocalStorage, error) {
+
+	dir, err := filepath.Abs(config.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	return &LocalStorage{
+		dir:     dir,
+		baseURL: strings.TrimRight(config.BaseURL, "/"),
+		secret:  []byte(config.Secret),
+	}, nil
+}
+
+func (s *LocalStorage) PresignPut(ctx context.Context, key string, contentType string, size int64, ttl time.Duration) (*model.PresignedUpload, error) {
+
+	if _, err := s.path(key); err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	sizeStr := strconv.FormatInt(size, 10)
+
+	q := url.Values{}
+	q.Set(localParamExpires, expires)
+	q.Set(localParamSize, sizeStr)
+	q.Set(localParamSignature, s.sign(key, contentType, sizeStr, expires))
+
+	return &model.PresignedUpload{
+		StorageKey: key,
+		URL:        s.URL(key) + "?" + q.Encode(),
+		Method:     http.MethodPut,
+		Headers: map[string]string{
+			"Content-Type":   contentType,
+			"Content-Length": sizeStr,
+		},
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+func (s *LocalStorage) Stat(ctx context.Context, key string) (*model.ObjectInfo, error) {
+
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, notFound(key)
+		}
+		return nil, err
+	}
+
+	return &model.ObjectInfo{
+		Key:         key,
+		Size:        info.Size(),
+		ContentType: mime.TypeByExtension(filepath.Ext(key)),
+	}, nil
+}
+
+func (s *LocalStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, notFound(key)
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+func (s *LocalStorage) Put(ctx context.Context, key string, contentType string, body io.Reader, size int64) error {
+
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if size >= 0 {
+		body = io.LimitReader(body, size)
+	}
+	return writeFile(path, body)
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStorage) URL(key string) string {
+	return s.baseURL + "/" + uriEncode(key, false)
+}
+
+// ServeHTTP accepts presigned PUT uploads and serves processed photos on GET.
+// Uploads and directories are not served. The object key is taken from the
+// {key...} path wildcard.
+func (s *LocalStorage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	key := r.PathValue(localParamKey)
+	path, err := s.path(key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if !strings.HasPrefix(key, model.PhotoKeyPrefix) {
+			http.NotFound(w, r)
+			return
+		}
+		s.serveFile(w, r, path)
+
+	case http.MethodPut:
+		q := r.URL.Query()
+		expires, sizeStr := q.Get(localParamExpires), q.Get(localParamSize)
+
+		expiresAt, err := strconv.ParseInt(expires, 10, 64)
+		if err != nil || time.Now().Unix() > expiresAt {
+			http.Error(w, "upload url expired", http.StatusForbidden)
+			return
+		}
+
+		expected := s.sign(key, r.Header.Get("Content-Type"), sizeStr, expires)
+		if !hmac.Equal([]byte(expected), []byte(q.Get(localParamSignature))) {
+			http.Error(w, "invalid signature", http.StatusForbidden)
+			return
+		}
+
+		size, err := strconv.ParseInt(sizeStr, 10, 64)
+		if err != nil || r.ContentLength != size {
+			http.Error(w, "content length does not match signed size", http.StatusBadRequest)
+			return
+		}
+
+		if err := writeFile(path, http.MaxBytesReader(w, r.Body, size)); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *LocalStorage) serveFile(w http.ResponseWriter, r *http.Request, path string) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		http.NotFound(w, r)
+		return
+	}
+
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
+
+func (s *LocalStorage) sign(key, contentType, size, expires string) string {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(strings.Join([]string{http.MethodPut, key, contentType, size, expires}, "\n")))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// path maps a key to a file inside the storage directory, rejecting keys that escape it.
+func (s *LocalStorage) path(key string) (string, error) {
+
+	if key == "" || strings.Contains(key, "..") || strings.HasPrefix(key, "/") {
+		return "", fmt.Errorf("%w: storage key", model.ErrInvalidParameter)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+func writeFile(path string, body io.Reader) error {
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
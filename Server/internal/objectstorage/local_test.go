@@ -0,0 +1,51 @@
+package objectstorage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLocalStorageServesOnlyProcessedPhotos(t *testing.T) {
+
+	s, err := NewLocalStorage(LocalConfig{Dir: t.TempDir(), BaseURL: "http://localhost/api/storage", Secret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for _, key := range []string{"uploads/1/a.jpg", "photos/1/a_original.jpg"} {
+		if err := s.Put(ctx, key, "image/jpeg", strings.NewReader("jpeg"), 4); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/storage/{key...}", s)
+
+	tests := []struct {
+		key  string
+		code int
+	}{
+		{key: "photos/1/a_original.jpg", code: http.StatusOK},
+		{key: "uploads/1/a.jpg", code: http.StatusNotFound},
+		{key: "photos/", code: http.StatusNotFound},
+		{key: "photos/1/", code: http.StatusNotFound},
+		{key: "photos/1", code: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/storage/"+tt.key, nil))
+			if w.Code != tt.code {
+				t.Errorf("GET %s = %d, want %d", tt.key, w.Code, tt.code)
+			}
+			if tt.code != http.StatusOK && strings.Contains(w.Body.String(), "a_original.jpg") {
+				t.Errorf("GET %s lists the directory", tt.key)
+			}
+		})
+	}
+}
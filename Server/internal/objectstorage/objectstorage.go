@@ -0,0 +1,13 @@
+// Package objectstorage provides the S3-compatible and local-disk backends used to
+// store uploaded photos. Clients upload directly to the backend with a presigned URL.
+package objectstorage
+
+import (
+	"fmt"
+
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+func notFound(key string) error {
+	return fmt.Errorf("%w: object %s", model.ErrorNotFound, key)
+}
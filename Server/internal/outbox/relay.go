@@ -0,0 +1,168 @@
+// Package outbox carries out side effects recorded in the outbox table. Messages
+// are written in the transaction of the change that causes them, so they survive
+// a crash right after the commit; the relay hands them to the handler of their
+// topic at least once.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+type (
+	RelayConfig struct {
+		PollInterval time.Duration
+		BatchSize    int
+		// Workers is how many messages of a batch are handled at the same time.
+		Workers     int
+		MaxAttempts int
+		// Backoff is the delay before the first retry; it doubles on every attempt up to MaxBackoff.
+		Backoff    time.Duration
+		MaxBackoff time.Duration
+		// Lease is how long a claimed message is reserved for this relay; if the
+		// process dies meanwhile, the message is handled again afterwards.
+		Lease time.Duration
+	}
+
+	// Store is the outbox table.
+	Store interface {
+		ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]*model.OutboxMessage, error)
+		CompleteOutbox(ctx context.Context, id int64) error
+		RetryOutbox(ctx context.Context, id int64, at time.Time, reason string) error
+		DeadLetterOutbox(ctx context.Context, id int64, reason string) error
+	}
+
+	// Handler carries out the side effect of a message. It must tolerate being
+	// called again for a message it has already handled.
+	Handler func(ctx context.Context, payload json.RawMessage) error
+
+	// Relay polls the outbox and dispatches due messages to the handlers
+	// registered for their topics. Failed messages are retried with exponential
+	// backoff and dead-lettered after MaxAttempts.
+	Relay struct {
+		store    Store
+		config   RelayConfig
+		handlers map[string]Handler
+	}
+)
+
+func NewRelay(store Store, config RelayConfig) (*Relay, error) {
+
+	if config.PollInterval <= 0 || config.BatchSize <= 0 || config.Workers <= 0 || config.MaxAttempts <= 0 ||
+		config.Backoff <= 0 || config.MaxBackoff < config.Backoff || config.Lease <= 0 {
+		return nil, fmt.Errorf("outbox: invalid relay settings")
+	}
+
+	return &Relay{
+		store:    store,
+		config:   config,
+		handlers: make(map[string]Handler),
+	}, nil
+}
+
+// Register sets the handler of a topic. Handlers are registered before Run.
+func (r *Relay) Register(topic string, handler Handler) {
+	r.handlers[topic] = handler
+}
+
+// Run handles due messages every PollInterval until ctx is cancelled. Full
+// batches are followed by the next one at once.
+func (r *Relay) Run(ctx context.Context) {
+
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := r.RunBatch(ctx)
+			if err != nil {
+				log.Printf("outbox: %v", err)
+			}
+			if err != nil || n < r.config.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunBatch handles one batch of due messages and returns how many it claimed.
+func (r *Relay) RunBatch(ctx context.Context) (int, error) {
+
+	messages, err := r.store.ClaimOutbox(ctx, r.config.BatchSize, r.config.Lease)
+	if err != nil {
+		return 0, fmt.Errorf("claim: %w", err)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		jobs = make(chan *model.OutboxMessage)
+	)
+	for i := 0; i < min(r.config.Workers, len(messages)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for msg := range jobs {
+				r.handle(ctx, msg)
+			}
+		}()
+	}
+	for _, msg := range messages {
+		jobs <- msg
+	}
+	close(jobs)
+	wg.Wait()
+
+	return len(messages), nil
+}
+
+func (r *Relay) handle(ctx context.Context, msg *model.OutboxMessage) {
+
+	handler, ok := r.handlers[msg.Topic]
+	if !ok {
+		r.record(msg, r.store.DeadLetterOutbox(ctx, msg.ID, "no handler for topic "+msg.Topic))
+		return
+	}
+
+	err := handler(ctx, msg.Payload)
+	switch {
+	case err == nil:
+		r.record(msg, r.store.CompleteOutbox(ctx, msg.ID))
+
+	case msg.Attempts >= r.config.MaxAttempts:
+		log.Printf("outbox: %s message %d failed after %d attempts: %v", msg.Topic, msg.ID, msg.Attempts, err)
+		r.record(msg, r.store.DeadLetterOutbox(ctx, msg.ID, err.Error()))
+
+	default:
+		delay := r.backoff(msg.Attempts)
+		log.Printf("outbox: %s message %d attempt %d failed, retrying in %s: %v", msg.Topic, msg.ID, msg.Attempts, delay, err)
+		r.record(msg, r.store.RetryOutbox(ctx, msg.ID, time.Now().Add(delay), err.Error()))
+	}
+}
+
+// record logs a failure to store the outcome of a message; the message is then
+// handled again once its lease expires.
+func (r *Relay) record(msg *model.OutboxMessage, err error) {
+	if err != nil {
+		log.Printf("outbox: record outcome of %s message %d: %v", msg.Topic, msg.ID, err)
+	}
+}
+
+func (r *Relay) backoff(attempt int) time.Duration {
+	delay := r.config.Backoff << (attempt - 1)
+	if delay <= 0 || delay > r.config.MaxBackoff {
+		return r.config.MaxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,197 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+const (
+	DefaultAPNsEndpoint = "https://api.push.apple.com"
+	// APNs rejects provider tokens older than an hour and throttles ones renewed
+	// more often than every 20 minutes.
+	apnsTokenLifetime = 50 * time.Minute
+)
+
+type (
+	APNsConfig struct {
+		// Endpoint is the base URL of the APNs provider API; use
+		// https://api.sandbox.push.apple.com for development builds.
+		Endpoint string
+		// Topic is the bundle identifier of the app.
+		Topic string
+		// KeyFile is the .p8 signing key; KeyID and TeamID identify it.
+		KeyFile string
+		KeyID   string
+		TeamID  string
+		// AuthToken is a fixed provider token used instead of a signing key;
+		// meant for the mock push server.
+		AuthToken string
+		Timeout   time.Duration
+	}
+
+	// APNsSender sends messages to iOS devices over HTTP/2.
+	APNsSender struct {
+		config APNsConfig
+		key    *ecdsa.PrivateKey
+		client *http.Client
+
+		mu       sync.Mutex
+		token    string
+		issuedAt time.Time
+	}
+
+	apnsAlert struct {
+		Title string `json:"title"`
+		Body  string `json:"body"`
+	}
+
+	apnsErrorResponse struct {
+		Reason string `json:"reason"`
+	}
+)
+
+func NewAPNsSender(config APNsConfig) (*APNsSender, error) {
+
+	if config.Topic == "" {
+		return nil, fmt.Errorf("apns: topic is required")
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = DefaultAPNsEndpoint
+	}
+
+	s := &APNsSender{config: config}
+
+	if config.AuthToken == "" {
+		data, err := os.ReadFile(config.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("apns: %w", err)
+		}
+		s.key, err = jwt.ParseECPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("apns: signing key: %w", err)
+		}
+		if config.KeyID == "" || config.TeamID == "" {
+			return nil, fmt.Errorf("apns: key id and team id are required")
+		}
+	}
+
+	// APNs only speaks HTTP/2; plain http endpoints are the mock server.
+	protocols := new(http.Protocols)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+	s.client = &http.Client{
+		Timeout:   config.Timeout,
+		Transport: &http.Transport{Protocols: protocols, ForceAttemptHTTP2: true},
+	}
+
+	return s, nil
+}
+
+func (s *APNsSender) Send(ctx context.Context, msg *model.PushMessage) error {
+
+	payload := map[string]any{
+		"aps": map[string]any{
+			"alert": apnsAlert{Title: msg.Title, Body: msg.Body},
+			"sound": "default",
+		},
+	}
+	for k, v := range msg.Data {
+		if k != "aps" {
+			payload[k] = v
+		}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	token, err := s.providerToken()
+	if err != nil {
+		return err
+	}
+
+	endpoint := strings.TrimRight(s.config.Endpoint, "/") + "/3/device/" + url.PathEscape(msg.Token)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "bearer "+token)
+	req.Header.Set("apns-topic", s.config.Topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("apns: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	raw := readErrorBody(resp)
+	var apnsErr apnsErrorResponse
+	_ = json.Unmarshal(raw, &apnsErr)
+
+	switch apnsErr.Reason {
+	case "BadDeviceToken", "Unregistered", "DeviceTokenNotForTopic":
+		return fmt.Errorf("apns: %w: %s", model.ErrInvalidPushToken, apnsErr.Reason)
+	case "ExpiredProviderToken", "InvalidProviderToken":
+		s.resetProviderToken()
+	}
+	if resp.StatusCode == http.StatusGone {
+		return fmt.Errorf("apns: %w: %s", model.ErrInvalidPushToken, resp.Status)
+	}
+
+	return fmt.Errorf("apns: %s: %s", resp.Status, raw)
+}
+
+// providerToken returns the signed JWT that authenticates requests, renewing it
+// before APNs would consider it expired.
+func (s *APNsSender) providerToken() (string, error) {
+
+	if s.key == nil {
+		return s.config.AuthToken, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && time.Since(s.issuedAt) < apnsTokenLifetime {
+		return s.token, nil
+	}
+
+	now := time.Now()
+	t := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": s.config.TeamID,
+		"iat": now.Unix(),
+	})
+	t.Header["kid"] = s.config.KeyID
+
+	signed, err := t.SignedString(s.key)
+	if err != nil {
+		return "", fmt.Errorf("apns: sign provider token: %w", err)
+	}
+
+	s.token, s.issuedAt = signed, now
+	return signed, nil
+}
+
+func (s *APNsSender) resetProviderToken() {
+	s.mu.Lock()
+	s.token = ""
+	s.mu.Unlock()
+}
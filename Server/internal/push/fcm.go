@@ -0,0 +1,165 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/inzarubin80/Server/internal/model"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/jwt"
+)
+
+const (
+	DefaultFCMEndpoint = "https://fcm.googleapis.com"
+	fcmScope           = "https://www.googleapis.com/auth/firebase.messaging"
+)
+
+type (
+	FCMConfig struct {
+		// Endpoint is the base URL of the FCM API.
+		Endpoint  string
+		ProjectID string
+		// CredentialsFile is the service account key used to obtain OAuth tokens.
+		CredentialsFile string
+		// TokenURL overrides the token endpoint of the service account key.
+		TokenURL string
+		// AccessToken is a fixed OAuth token used instead of a service account;
+		// meant for the mock push server.
+		AccessToken string
+		Timeout     time.Duration
+	}
+
+	// FCMSender sends messages to Android devices with the FCM HTTP v1 API.
+	FCMSender struct {
+		sendURL string
+		client  *http.Client
+	}
+
+	serviceAccountKey struct {
+		ClientEmail  string `json:"client_email"`
+		PrivateKey   string `json:"private_key"`
+		PrivateKeyID string `json:"private_key_id"`
+		TokenURI     string `json:"token_uri"`
+	}
+
+	fcmRequest struct {
+		Message fcmMessage `json:"message"`
+	}
+
+	fcmMessage struct {
+		Token        string            `json:"token"`
+		Notification fcmNotification   `json:"notification"`
+		Data         map[string]string `json:"data,omitempty"`
+	}
+
+	fcmNotification struct {
+		Title string `json:"title"`
+		Body  string `json:"body"`
+	}
+
+	fcmErrorResponse struct {
+		Error struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+			Details []struct {
+				ErrorCode string `json:"errorCode"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+)
+
+func NewFCMSender(config FCMConfig) (*FCMSender, error) {
+
+	if config.ProjectID == "" {
+		return nil, fmt.Errorf("fcm: project id is required")
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = DefaultFCMEndpoint
+	}
+
+	var tokens oauth2.TokenSource
+	switch {
+	case config.AccessToken != "":
+		tokens = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: config.AccessToken})
+	case config.CredentialsFile != "":
+		data, err := os.ReadFile(config.CredentialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("fcm: %w", err)
+		}
+		var key serviceAccountKey
+		if err := json.Unmarshal(data, &key); err != nil {
+			return nil, fmt.Errorf("fcm: credentials file: %w", err)
+		}
+		tokenURL := key.TokenURI
+		if config.TokenURL != "" {
+			tokenURL = config.TokenURL
+		}
+		jwtConfig := &jwt.Config{
+			Email:        key.ClientEmail,
+			PrivateKey:   []byte(key.PrivateKey),
+			PrivateKeyID: key.PrivateKeyID,
+			Scopes:       []string{fcmScope},
+			TokenURL:     tokenURL,
+		}
+		tokens = oauth2.ReuseTokenSource(nil, jwtConfig.TokenSource(context.Background()))
+	default:
+		return nil, fmt.Errorf("fcm: credentials file or access token is required")
+	}
+
+	return &FCMSender{
+		sendURL: strings.TrimRight(config.Endpoint, "/") + "/v1/projects/" + url.PathEscape(config.ProjectID) + "/messages:send",
+		client: &http.Client{
+			Timeout:   config.Timeout,
+			Transport: &oauth2.Transport{Source: tokens, Base: http.DefaultTransport},
+		},
+	}, nil
+}
+
+func (s *FCMSender) Send(ctx context.Context, msg *model.PushMessage) error {
+
+	body, err := json.Marshal(fcmRequest{Message: fcmMessage{
+		Token:        msg.Token,
+		Notification: fcmNotification{Title: msg.Title, Body: msg.Body},
+		Data:         msg.Data,
+	}})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.sendURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fcm: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	raw := readErrorBody(resp)
+	var fcmErr fcmErrorResponse
+	_ = json.Unmarshal(raw, &fcmErr)
+
+	for _, d := range fcmErr.Error.Details {
+		switch d.ErrorCode {
+		// The app was uninstalled or the token belongs to another Firebase project.
+		case "UNREGISTERED", "SENDER_ID_MISMATCH":
+			return fmt.Errorf("fcm: %w: %s", model.ErrInvalidPushToken, d.ErrorCode)
+		}
+	}
+
+	return fmt.Errorf("fcm: %s: %s", resp.Status, raw)
+}
@@ -0,0 +1,61 @@
+// Package push delivers notifications to phones through Firebase Cloud Messaging
+// (HTTP v1 API) and the Apple Push Notification service (HTTP/2 provider API).
+// Both endpoints are configurable so that cmd/pushmock can stand in for them.
+package push
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+// maxErrorBody bounds how much of a provider's error response is read.
+const maxErrorBody = 4096
+
+type (
+	// Sender delivers a message to one device. It returns an error matching
+	// model.ErrInvalidPushToken when the provider rejects the device token.
+	Sender interface {
+		Send(ctx context.Context, msg *model.PushMessage) error
+	}
+
+	// Router sends each message with the sender registered for its platform.
+	Router struct {
+		senders map[string]Sender
+	}
+)
+
+func NewRouter() *Router {
+	return &Router{senders: make(map[string]Sender)}
+}
+
+func (r *Router) Register(platform string, sender Sender) {
+	r.senders[platform] = sender
+}
+
+// Supports reports whether messages for the platform can be delivered.
+func (r *Router) Supports(platform string) bool {
+	_, ok := r.senders[platform]
+	return ok
+}
+
+// Empty reports whether no platform is configured.
+func (r *Router) Empty() bool {
+	return len(r.senders) == 0
+}
+
+func (r *Router) Send(ctx context.Context, msg *model.PushMessage) error {
+	sender, ok := r.senders[msg.Platform]
+	if !ok {
+		return fmt.Errorf("push: no sender for platform %q", msg.Platform)
+	}
+	return sender.Send(ctx, msg)
+}
+
+func readErrorBody(resp *http.Response) []byte {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	return body
+}
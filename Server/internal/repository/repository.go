@@ -2,13 +2,26 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
+
+	"github.com/inzarubin80/Server/internal/storage"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 )
 
+const (
+	// Transactions aborted by a serialization failure or a deadlock are retried
+	// up to txMaxAttempts times with a linearly growing pause.
+	txMaxAttempts = 5
+	txRetryDelay  = 20 * time.Millisecond
+)
+
 type (
 	Repository struct {
-		conn    DBTX
+		conn DBTX
+		// db starts transactions; it is nil for a repository bound to a transaction.
+		db storage.DB
 	}
 
 	DBTX interface {
@@ -16,13 +29,65 @@ type (
 		Query(context.Context, string, ...interface{}) (pgx.Rows, error)
 		QueryRow(context.Context, string, ...interface{}) pgx.Row
 	}
-
-
 )
 
-func NewPokerRepository(conn DBTX) *Repository {
+func NewPokerRepository(db storage.DB) *Repository {
 	return &Repository{
-		
-		conn: conn,
+		conn: db,
+		db:   db,
+	}
+}
+
+// Transact runs txFunc in a serializable transaction. When Postgres aborts the
+// transaction with a serialization failure or a deadlock, it is rolled back and
+// txFunc runs again, so txFunc must not have side effects outside the database.
+// On a repository already bound to a transaction txFunc joins that transaction.
+func (r *Repository) Transact(ctx context.Context, txFunc func(adapters storage.Adapters) error) error {
+
+	if r.db == nil {
+		return txFunc(storage.Adapters{Repository: r})
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := r.transact(ctx, txFunc)
+		if err == nil || attempt == txMaxAttempts || !isRetryableTxError(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * txRetryDelay):
+		}
+	}
+}
+
+func (r *Repository) transact(ctx context.Context, txFunc func(adapters storage.Adapters) error) error {
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.Serializable})
+	if err != nil {
+		return err
+	}
+	// A no-op once the transaction is committed.
+	defer tx.Rollback(ctx)
+
+	if err := txFunc(storage.Adapters{Repository: &Repository{conn: tx}}); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func isRetryableTxError(err error) bool {
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+
+	switch pgErr.Code {
+	case "40001", "40P01": // serialization_failure, deadlock_detected
+		return true
 	}
+	return false
 }
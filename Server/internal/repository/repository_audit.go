@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+const (
+	sqlInsertAuditLog = `
+INSERT INTO audit_logs (actor_id, action, entity_type, entity_id, diff, request_id, ip)
+VALUES (NULLIF($1::bigint, 0), $2, $3, $4, $5, $6, $7);
+`
+
+	sqlSelectAuditLogs = `
+SELECT id, COALESCE(actor_id, 0), action, entity_type, entity_id, diff, request_id, ip, created_at
+FROM audit_logs
+`
+)
+
+func (r *Repository) AddAuditLog(ctx context.Context, entry *model.AuditEntry) error {
+
+	diff, err := json.Marshal(entry.Diff)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.conn.Exec(ctx, sqlInsertAuditLog,
+		int64(entry.ActorID), entry.Action, entry.EntityType, entry.EntityID, diff, entry.RequestID, entry.IP)
+	return err
+}
+
+// ListAuditLogs returns the entries matching the filter, newest first.
+func (r *Repository) ListAuditLogs(ctx context.Context, filter *model.AuditFilter) ([]*model.AuditEntry, error) {
+
+	var (
+		conds []string
+		args  []any
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.ActorID != nil {
+		conds = append(conds, fmt.Sprintf("actor_id = %s", arg(int64(*filter.ActorID))))
+	}
+	if filter.Action != "" {
+		conds = append(conds, fmt.Sprintf("action = %s", arg(filter.Action)))
+	}
+	if filter.EntityType != "" {
+		conds = append(conds, fmt.Sprintf("entity_type = %s", arg(filter.EntityType)))
+	}
+	if filter.EntityID != "" {
+		conds = append(conds, fmt.Sprintf("entity_id = %s", arg(filter.EntityID)))
+	}
+	if filter.CreatedFrom != nil {
+		conds = append(conds, fmt.Sprintf("created_at >= %s", arg(*filter.CreatedFrom)))
+	}
+	if filter.CreatedTo != nil {
+		conds = append(conds, fmt.Sprintf("created_at < %s", arg(*filter.CreatedTo)))
+	}
+	if filter.BeforeID > 0 {
+		conds = append(conds, fmt.Sprintf("id < %s", arg(filter.BeforeID)))
+	}
+
+	query := sqlSelectAuditLogs
+	if len(conds) > 0 {
+		query += "WHERE " + strings.Join(conds, "\n  AND ") + "\n"
+	}
+	query += "ORDER BY id DESC\nLIMIT " + arg(filter.Limit)
+
+	rows, err := r.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make([]*model.AuditEntry, 0, filter.Limit)
+	for rows.Next() {
+		var (
+			entry model.AuditEntry
+			diff  []byte
+		)
+		if err := rows.Scan(&entry.ID, &entry.ActorID, &entry.Action, &entry.EntityType, &entry.EntityID,
+			&diff, &entry.RequestID, &entry.IP, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(diff, &entry.Diff); err != nil {
+			return nil, fmt.Errorf("audit log %d: %w", entry.ID, err)
+		}
+		res = append(res, &entry)
+	}
+
+	return res, rows.Err()
+}
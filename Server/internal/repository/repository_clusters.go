@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+// ClusterViolations counts the violations matching the filter per square grid
+// cell of cellSize degrees, split by type and status. Cells are numbered by
+// FLOOR(coordinate / cellSize), so the grid is the same for every bbox.
+func (r *Repository) ClusterViolations(ctx context.Context, filter *model.ViolationFilter, cellSize float64) ([]*model.ClusterCell, error) {
+
+	var args queryArgs
+	cell := args.add(cellSize) + "::float8"
+
+	query := `
+SELECT FLOOR(lng / ` + cell + `)::bigint AS cx, FLOOR(lat / ` + cell + `)::bigint AS cy, type, status,
+       COUNT(*), AVG(lat), AVG(lng), MIN(id::text)
+FROM violations
+` + whereClause(violationConditions(filter, &args)) + `GROUP BY cx, cy, type, status;`
+
+	rows, err := r.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []*model.ClusterCell
+	for rows.Next() {
+		var c model.ClusterCell
+		if err := rows.Scan(&c.X, &c.Y, &c.Type, &c.Status, &c.Count, &c.Lat, &c.Lng, &c.ViolationID); err != nil {
+			return nil, err
+		}
+		res = append(res, &c)
+	}
+
+	return res, rows.Err()
+}
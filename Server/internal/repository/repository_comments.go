@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+const (
+	commentColumns = `c.id, c.violation_id, c.user_id, COALESCE(u.name, ''), c.text, c.moderation_status, c.hidden, c.hidden_reason, c.created_at, c.edited_at`
+
+	sqlCommentsFrom = `
+FROM c
+LEFT JOIN users u ON u.user_id = c.user_id;
+`
+
+	sqlInsertComment = `
+WITH c AS (
+    INSERT INTO comments (violation_id, user_id, text, moderation_status)
+    VALUES ($1, $2, $3, $4)
+    RETURNING *
+)
+SELECT ` + commentColumns + sqlCommentsFrom
+
+	// An edit is moderated again, so the previous verdict is cleared.
+	sqlUpdateCommentText = `
+WITH c AS (
+    UPDATE comments
+    SET text = $2, moderation_status = $3, moderation_score = NULL, moderation_reason = '', edited_at = NOW()
+    WHERE id = $1
+    RETURNING *
+)
+SELECT ` + commentColumns + sqlCommentsFrom
+
+	// Automated moderation only touches comments no moderator has decided on yet.
+	sqlApplyCommentModerationScore = `
+WITH c AS (
+    UPDATE comments
+    SET moderation_score = $2, moderation_status = $3, moderation_reason = $4
+    WHERE id = $1 AND moderation_status = 'pending' AND hidden_by IS NULL
+    RETURNING *
+)
+SELECT ` + commentColumns + sqlCommentsFrom
+
+	// Showing a comment also approves it, overriding automated moderation.
+	// hidden_by is kept only while the comment is hidden, so that a later edit
+	// of a shown comment goes through automated moderation again.
+	sqlSetCommentVisibility = `
+WITH c AS (
+    UPDATE comments
+    SET hidden = $2, hidden_reason = $3, hidden_by = CASE WHEN $2 THEN $4::bigint END,
+        moderation_status = CASE WHEN $2 THEN moderation_status ELSE 'approved' END
+    WHERE id = $1
+    RETURNING *
+)
+SELECT ` + commentColumns + sqlCommentsFrom
+
+	sqlSelectComments = `
+SELECT ` + commentColumns + `
+FROM comments c
+LEFT JOIN users u ON u.user_id = c.user_id
+`
+
+	sqlMarkCommentPublished = `UPDATE comments SET published_at = NOW() WHERE id = $1 AND published_at IS NULL;`
+
+	sqlDeleteComment = `DELETE FROM comments WHERE id = $1;`
+)
+
+func (r *Repository) CreateComment(ctx context.Context, comment *model.Comment) (*model.Comment, error) {
+	return scanComment(r.conn.QueryRow(ctx, sqlInsertComment,
+		string(comment.ViolationID), int64(comment.Author.ID), comment.Text, string(comment.ModerationStatus)))
+}
+
+func (r *Repository) GetComment(ctx context.Context, id model.CommentID) (*model.Comment, error) {
+
+	c, err := scanComment(r.conn.QueryRow(ctx, sqlSelectComments+"WHERE c.id = $1;", int64(id)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: comment %d", model.ErrorNotFound, id)
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// ListComments returns the comments matching the filter, oldest first.
+func (r *Repository) ListComments(ctx context.Context, filter *model.CommentFilter) ([]*model.Comment, error) {
+
+	var args queryArgs
+	conds := []string{"c.violation_id = " + args.add(string(filter.ViolationID))}
+
+	if !filter.AllStatuses {
+		conds = append(conds, "((c.moderation_status = 'approved' AND NOT c.hidden) OR c.user_id = "+args.add(int64(filter.ViewerID))+")")
+	}
+	if filter.AfterID > 0 {
+		conds = append(conds, "c.id > "+args.add(int64(filter.AfterID)))
+	}
+
+	query := sqlSelectComments + "WHERE " + strings.Join(conds, "\n  AND ") +
+		"\nORDER BY c.id\nLIMIT " + args.add(filter.Limit)
+
+	rows, err := r.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make([]*model.Comment, 0, filter.Limit)
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+	}
+
+	return res, rows.Err()
+}
+
+func (r *Repository) UpdateCommentText(ctx context.Context, id model.CommentID, text string, status model.ModerationStatus) (*model.Comment, error) {
+	return r.updateComment(ctx, sqlUpdateCommentText, id, text, string(status))
+}
+
+// ApplyCommentModerationScore stores the verdict of automated moderation. It
+// returns model.ErrConflict if the comment is no longer pending.
+func (r *Repository) ApplyCommentModerationScore(ctx context.Context, id model.CommentID, status model.ModerationStatus, score float64, reason string) (*model.Comment, error) {
+
+	c, err := scanComment(r.conn.QueryRow(ctx, sqlApplyCommentModerationScore, int64(id), score, string(status), reason))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: comment is not pending moderation", model.ErrConflict)
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// SetCommentVisibility records a moderator's decision to hide or show a comment.
+func (r *Repository) SetCommentVisibility(ctx context.Context, id model.CommentID, hidden bool, reason string, moderatorID model.UserID) (*model.Comment, error) {
+	return r.updateComment(ctx, sqlSetCommentVisibility, id, hidden, reason, int64(moderatorID))
+}
+
+// MarkCommentPublished records that the comment has become public and reports
+// whether this is the first time.
+func (r *Repository) MarkCommentPublished(ctx context.Context, id model.CommentID) (bool, error) {
+
+	tag, err := r.conn.Exec(ctx, sqlMarkCommentPublished, int64(id))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *Repository) DeleteComment(ctx context.Context, id model.CommentID) error {
+
+	tag, err := r.conn.Exec(ctx, sqlDeleteComment, int64(id))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: comment %d", model.ErrorNotFound, id)
+	}
+	return nil
+}
+
+func (r *Repository) updateComment(ctx context.Context, query string, id model.CommentID, args ...any) (*model.Comment, error) {
+
+	c, err := scanComment(r.conn.QueryRow(ctx, query, append([]any{int64(id)}, args...)...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: comment %d", model.ErrorNotFound, id)
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func scanComment(row pgx.Row) (*model.Comment, error) {
+
+	var c model.Comment
+	if err := row.Scan(&c.ID, &c.ViolationID, &c.Author.ID, &c.Author.Name, &c.Text, &c.ModerationStatus,
+		&c.Hidden, &c.HiddenReason, &c.CreatedAt, &c.EditedAt); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+const (
+	// The insert and the counter update run as one statement, so the counter
+	// can never drift from the number of rows in confirmations.
+	sqlAddConfirmation = `
+WITH ins AS (
+    INSERT INTO confirmations (violation_id, user_id)
+    VALUES ($1, $2)
+    ON CONFLICT (violation_id, user_id) DO NOTHING
+    RETURNING violation_id
+)
+UPDATE violations
+SET confirmations_count = confirmations_count + 1, updated_at = NOW()
+WHERE id = (SELECT violation_id FROM ins)
+RETURNING ` + violationColumns + `;
+`
+
+	sqlRemoveConfirmation = `
+WITH del AS (
+    DELETE FROM confirmations
+    WHERE violation_id = $1 AND user_id = $2
+    RETURNING violation_id
+)
+UPDATE violations
+SET confirmations_count = GREATEST(confirmations_count - 1, 0), updated_at = NOW()
+WHERE id = (SELECT violation_id FROM del)
+RETURNING ` + violationColumns + `;
+`
+
+	sqlSelectConfirmations = `
+SELECT c.violation_id, c.user_id, COALESCE(u.name, ''), COALESCE(u.role, 'user'), c.created_at
+FROM confirmations c
+LEFT JOIN users u ON u.user_id = c.user_id
+WHERE c.violation_id = $1
+ORDER BY c.created_at, c.id;
+`
+)
+
+// AddConfirmation records the user's vote and returns the updated violation.
+// It returns model.ErrConflict if the user has already confirmed the violation.
+func (r *Repository) AddConfirmation(ctx context.Context, violationID model.ViolationID, userID model.UserID) (*model.Violation, error) {
+
+	v, err := scanViolation(r.conn.QueryRow(ctx, sqlAddConfirmation, string(violationID), int64(userID)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: violation already confirmed by user", model.ErrConflict)
+		}
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// RemoveConfirmation withdraws the user's vote and returns the updated violation.
+// It returns model.ErrorNotFound if the user has not confirmed the violation.
+func (r *Repository) RemoveConfirmation(ctx context.Context, violationID model.ViolationID, userID model.UserID) (*model.Violation, error) {
+
+	v, err := scanViolation(r.conn.QueryRow(ctx, sqlRemoveConfirmation, string(violationID), int64(userID)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: confirmation", model.ErrorNotFound)
+		}
+		return nil, err
+	}
+
+	return v, nil
+}
+
+func (r *Repository) ListConfirmations(ctx context.Context, violationID model.ViolationID) ([]*model.Confirmation, error) {
+
+	rows, err := r.conn.Query(ctx, sqlSelectConfirmations, string(violationID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []*model.Confirmation{}
+	for rows.Next() {
+		var c model.Confirmation
+		if err := rows.Scan(&c.ViolationID, &c.User.ID, &c.User.Name, &c.User.Role, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		res = append(res, &c)
+	}
+
+	return res, rows.Err()
+}
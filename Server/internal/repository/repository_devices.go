@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+const (
+	deviceColumns = `id, user_id, platform, token, created_at, updated_at`
+
+	// A token belongs to one installation of the app; registering it again,
+	// possibly from another account, moves it to the current user.
+	sqlUpsertDevice = `
+INSERT INTO devices (user_id, platform, token)
+VALUES ($1, $2, $3)
+ON CONFLICT (token) DO UPDATE
+SET user_id = EXCLUDED.user_id, platform = EXCLUDED.platform, updated_at = NOW()
+RETURNING ` + deviceColumns + `;
+`
+
+	sqlDeleteUserDevice = `DELETE FROM devices WHERE user_id = $1 AND token = $2;`
+
+	sqlDeleteDeviceByToken = `DELETE FROM devices WHERE token = $1;`
+
+	sqlSelectUsersDevices = `
+SELECT ` + deviceColumns + `
+FROM devices
+WHERE user_id = ANY($1::bigint[])
+ORDER BY user_id, id;
+`
+)
+
+func (r *Repository) UpsertDevice(ctx context.Context, userID model.UserID, platform string, token string) (*model.Device, error) {
+
+	var d model.Device
+	if err := r.conn.QueryRow(ctx, sqlUpsertDevice, int64(userID), platform, token).Scan(
+		&d.ID, &d.UserID, &d.Platform, &d.Token, &d.CreatedAt, &d.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+// DeleteUserDevice unregisters the user's device. It returns model.ErrorNotFound
+// if the user has no device with the token.
+func (r *Repository) DeleteUserDevice(ctx context.Context, userID model.UserID, token string) error {
+
+	tag, err := r.conn.Exec(ctx, sqlDeleteUserDevice, int64(userID), token)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: device", model.ErrorNotFound)
+	}
+
+	return nil
+}
+
+// DeleteDeviceByToken forgets a token the push provider rejected.
+func (r *Repository) DeleteDeviceByToken(ctx context.Context, token string) error {
+	_, err := r.conn.Exec(ctx, sqlDeleteDeviceByToken, token)
+	return err
+}
+
+func (r *Repository) ListUsersDevices(ctx context.Context, userIDs []model.UserID) ([]*model.Device, error) {
+
+	ids := make([]int64, len(userIDs))
+	for i, id := range userIDs {
+		ids[i] = int64(id)
+	}
+
+	rows, err := r.conn.Query(ctx, sqlSelectUsersDevices, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []*model.Device
+	for rows.Next() {
+		var d model.Device
+		if err := rows.Scan(&d.ID, &d.UserID, &d.Platform, &d.Token, &d.CreatedAt, &d.UpdatedAt); err != nil {
+			return nil, err
+		}
+		res = append(res, &d)
+	}
+
+	return res, rows.Err()
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+const (
+	sqlNotificationColumns = `id, user_id, type, COALESCE(violation_id::text, ''), payload, read_at IS NOT NULL, created_at`
+
+	// Notifications of an event the user already has are skipped and not returned.
+	sqlInsertNotifications = `
+INSERT INTO notifications (user_id, type, violation_id, payload, event_id)
+SELECT user_id, type, violation_id, payload, NULLIF(event_id, '')::uuid
+FROM unnest($1::bigint[], $2::text[], $3::uuid[], $4::jsonb[], $5::text[])
+    AS n (user_id, type, violation_id, payload, event_id)
+ON CONFLICT (user_id, event_id) WHERE event_id IS NOT NULL DO NOTHING
+RETURNING ` + sqlNotificationColumns + `;
+`
+
+	sqlSelectNotifications = `
+SELECT ` + sqlNotificationColumns + `
+FROM notifications
+`
+
+	sqlCountUnreadNotifications = `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL;`
+
+	sqlMarkNotificationRead = `
+UPDATE notifications SET read_at = COALESCE(read_at, NOW())
+WHERE id = $1 AND user_id = $2;
+`
+
+	sqlMarkAllNotificationsRead = `UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL;`
+)
+
+// AddNotifications stores the notifications and returns the ones stored;
+// notifications about an event the user has already been notified of are
+// left out.
+func (r *Repository) AddNotifications(ctx context.Context, notifications []*model.Notification) ([]*model.Notification, error) {
+
+	if len(notifications) == 0 {
+		return nil, nil
+	}
+
+	var (
+		userIDs      = make([]int64, len(notifications))
+		types        = make([]string, len(notifications))
+		violationIDs = make([]string, len(notifications))
+		payloads     = make([]string, len(notifications))
+		eventIDs     = make([]string, len(notifications))
+	)
+	for i, n := range notifications {
+		userIDs[i], types[i], violationIDs[i], payloads[i], eventIDs[i] = int64(n.UserID), n.Type, string(n.ViolationID), string(n.Payload), n.EventID
+	}
+
+	rows, err := r.conn.Query(ctx, sqlInsertNotifications, userIDs, types, violationIDs, payloads, eventIDs)
+	if err != nil {
+		return nil, err
+	}
+	return scanNotifications(rows, len(notifications))
+}
+
+// ListNotifications returns the user's notifications matching the filter, newest first.
+func (r *Repository) ListNotifications(ctx context.Context, filter *model.NotificationFilter) ([]*model.Notification, error) {
+
+	var args queryArgs
+	conds := []string{"user_id = " + args.add(int64(filter.UserID))}
+
+	if filter.UnreadOnly {
+		conds = append(conds, "read_at IS NULL")
+	}
+	if filter.BeforeID > 0 {
+		conds = append(conds, "id < "+args.add(int64(filter.BeforeID)))
+	}
+
+	query := sqlSelectNotifications + "WHERE " + strings.Join(conds, "\n  AND ") +
+		"\nORDER BY id DESC\nLIMIT " + args.add(filter.Limit)
+
+	rows, err := r.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanNotifications(rows, filter.Limit)
+}
+
+func (r *Repository) CountUnreadNotifications(ctx context.Context, userID model.UserID) (int, error) {
+	var count int
+	err := r.conn.QueryRow(ctx, sqlCountUnreadNotifications, int64(userID)).Scan(&count)
+	return count, err
+}
+
+// MarkNotificationRead marks a notification of the user as read. Marking it again
+// keeps the time it was first read.
+func (r *Repository) MarkNotificationRead(ctx context.Context, userID model.UserID, id model.NotificationID) error {
+
+	tag, err := r.conn.Exec(ctx, sqlMarkNotificationRead, int64(id), int64(userID))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: notification %d", model.ErrorNotFound, id)
+	}
+	return nil
+}
+
+// MarkAllNotificationsRead marks every unread notification of the user as read
+// and returns how many there were.
+func (r *Repository) MarkAllNotificationsRead(ctx context.Context, userID model.UserID) (int, error) {
+	tag, err := r.conn.Exec(ctx, sqlMarkAllNotificationsRead, int64(userID))
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+func scanNotifications(rows pgx.Rows, capacity int) ([]*model.Notification, error) {
+
+	defer rows.Close()
+
+	res := make([]*model.Notification, 0, capacity)
+	for rows.Next() {
+		var (
+			n       model.Notification
+			payload []byte
+		)
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.ViolationID, &payload, &n.Read, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		n.Payload = payload
+		res = append(res, &n)
+	}
+
+	return res, rows.Err()
+}
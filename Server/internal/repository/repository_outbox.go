@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+const (
+	sqlOutboxColumns = `id, topic, payload, status, attempts, next_attempt_at, last_error, created_at, processed_at`
+
+	sqlInsertOutbox = `
+INSERT INTO outbox (topic, payload)
+SELECT * FROM unnest($1::text[], $2::jsonb[]);
+`
+
+	// Claimed messages are leased: if the process dies while handling them, they
+	// are due again once the lease has expired. SKIP LOCKED lets several
+	// instances share the outbox.
+	sqlClaimOutbox = `
+UPDATE outbox
+SET attempts = attempts + 1, next_attempt_at = NOW() + make_interval(secs => $2)
+WHERE id IN (
+    SELECT id FROM outbox
+    WHERE status = 'pending' AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING ` + sqlOutboxColumns + `;
+`
+
+	sqlCompleteOutbox = `UPDATE outbox SET status = 'done', processed_at = NOW() WHERE id = $1;`
+
+	sqlRetryOutbox = `UPDATE outbox SET next_attempt_at = $2, last_error = $3 WHERE id = $1;`
+
+	sqlDeadLetterOutbox = `UPDATE outbox SET status = 'dead', last_error = $2, processed_at = NOW() WHERE id = $1;`
+
+	sqlSelectOutbox = `
+SELECT ` + sqlOutboxColumns + `
+FROM outbox
+`
+
+	sqlReplayOutbox = `
+UPDATE outbox
+SET status = 'pending', attempts = 0, next_attempt_at = NOW(), processed_at = NULL
+WHERE id = $1 AND status = 'dead'
+RETURNING ` + sqlOutboxColumns + `;
+`
+
+	sqlGetOutboxStatus = `SELECT status FROM outbox WHERE id = $1;`
+)
+
+func (r *Repository) EnqueueOutbox(ctx context.Context, messages []*model.OutboxMessage) error {
+
+	if len(messages) == 0 {
+		return nil
+	}
+
+	var (
+		topics   = make([]string, len(messages))
+		payloads = make([]string, len(messages))
+	)
+	for i, m := range messages {
+		topics[i], payloads[i] = m.Topic, string(m.Payload)
+	}
+
+	_, err := r.conn.Exec(ctx, sqlInsertOutbox, topics, payloads)
+	return err
+}
+
+// ClaimOutbox takes up to limit due messages for handling and counts the attempt.
+func (r *Repository) ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]*model.OutboxMessage, error) {
+
+	rows, err := r.conn.Query(ctx, sqlClaimOutbox, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	return scanOutboxMessages(rows, limit)
+}
+
+func (r *Repository) CompleteOutbox(ctx context.Context, id int64) error {
+	_, err := r.conn.Exec(ctx, sqlCompleteOutbox, id)
+	return err
+}
+
+// RetryOutbox schedules the next attempt of a message whose handler failed.
+func (r *Repository) RetryOutbox(ctx context.Context, id int64, at time.Time, reason string) error {
+	_, err := r.conn.Exec(ctx, sqlRetryOutbox, id, at, reason)
+	return err
+}
+
+func (r *Repository) DeadLetterOutbox(ctx context.Context, id int64, reason string) error {
+	_, err := r.conn.Exec(ctx, sqlDeadLetterOutbox, id, reason)
+	return err
+}
+
+// ListOutbox returns the messages matching the filter, newest first.
+func (r *Repository) ListOutbox(ctx context.Context, filter *model.OutboxFilter) ([]*model.OutboxMessage, error) {
+
+	var (
+		args  queryArgs
+		conds []string
+	)
+
+	if filter.Status != "" {
+		conds = append(conds, "status = "+args.add(filter.Status))
+	}
+	if filter.Topic != "" {
+		conds = append(conds, "topic = "+args.add(filter.Topic))
+	}
+	if filter.BeforeID > 0 {
+		conds = append(conds, "id < "+args.add(filter.BeforeID))
+	}
+
+	query := sqlSelectOutbox
+	if len(conds) > 0 {
+		query += "WHERE " + strings.Join(conds, "\n  AND ") + "\n"
+	}
+	query += "ORDER BY id DESC\nLIMIT " + args.add(filter.Limit)
+
+	rows, err := r.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanOutboxMessages(rows, filter.Limit)
+}
+
+// ReplayOutbox returns a dead message to the queue with a fresh set of attempts.
+func (r *Repository) ReplayOutbox(ctx context.Context, id int64) (*model.OutboxMessage, error) {
+
+	rows, err := r.conn.Query(ctx, sqlReplayOutbox, id)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := scanOutboxMessages(rows, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) == 1 {
+		return messages[0], nil
+	}
+
+	var status string
+	err = r.conn.QueryRow(ctx, sqlGetOutboxStatus, id).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: outbox message %d", model.ErrorNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%w: outbox message %d is %s", model.ErrConflict, id, status)
+}
+
+func scanOutboxMessages(rows pgx.Rows, capacity int) ([]*model.OutboxMessage, error) {
+
+	defer rows.Close()
+
+	res := make([]*model.OutboxMessage, 0, capacity)
+	for rows.Next() {
+		var (
+			m       model.OutboxMessage
+			payload []byte
+		)
+		if err := rows.Scan(&m.ID, &m.Topic, &payload, &m.Status, &m.Attempts, &m.NextAttemptAt,
+			&m.LastError, &m.CreatedAt, &m.ProcessedAt); err != nil {
+			return nil, err
+		}
+		m.Payload = payload
+		res = append(res, &m)
+	}
+
+	return res, rows.Err()
+}
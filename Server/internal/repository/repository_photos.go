@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/inzarubin80/Server/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+// storedVariant is a photo variant as kept in the variants column. The storage
+// key is not part of the API, so model.PhotoVariant does not serialize it.
+type storedVariant struct {
+	StorageKey string `json:"storage_key"`
+	Mime       string `json:"mime"`
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	Size       int64  `json:"size"`
+}
+
+const (
+	photoColumns = `id, violation_id, user_id, storage_key, kind, status, processing_status, mime, size, variants, created_at`
+
+	sqlInsertPhoto = `
+INSERT INTO photos (id, user_id, storage_key, kind, status, mime, size)
+VALUES ($1, $2, $3, $4, 'pending', $5, $6)
+RETURNING ` + photoColumns + `;
+`
+
+	sqlSelectPhotoByKey = `
+SELECT ` + photoColumns + `
+FROM photos
+WHERE storage_key = $1;
+`
+
+	sqlSelectPhotosByKeys = `
+SELECT ` + photoColumns + `
+FROM photos
+WHERE storage_key = ANY($1::text[]);
+`
+
+	sqlUpdatePhotoStatus = `
+UPDATE photos
+SET status = $2, mime = $3, size = $4, updated_at = NOW()
+WHERE storage_key = $1
+RETURNING ` + photoColumns + `;
+`
+
+	sqlAttachPhotos = `
+UPDATE photos
+SET violation_id = $1, kind = $3, updated_at = NOW()
+WHERE storage_key = ANY($4::text[]) AND user_id = $2 AND status = 'uploaded' AND violation_id IS NULL;
+`
+
+	sqlSetPhotoProcessed = `
+UPDATE photos
+SET processing_status = 'done', processing_error = NULL, mime = $2, size = $3, variants = $4, updated_at = NOW()
+WHERE storage_key = $1;
+`
+
+	sqlSetPhotoProcessingFailed = `
+UPDATE photos
+SET processing_status = 'failed', processing_error = $2, updated_at = NOW()
+WHERE storage_key = $1;
+`
+
+	sqlSelectPhotosPendingProcessing = `
+SELECT ` + photoColumns + `
+FROM photos
+WHERE status = 'uploaded' AND processing_status = 'pending'
+ORDER BY created_at
+LIMIT $1;
+`
+
+	sqlSelectViolationPhotos = `
+SELECT ` + photoColumns + `
+FROM photos
+WHERE violation_id = $1 AND status = 'uploaded'
+ORDER BY created_at, id;
+`
+)
+
+func (r *Repository) CreatePhoto(ctx context.Context, photo *model.Photo) (*model.Photo, error) {
+	row := r.conn.QueryRow(ctx, sqlInsertPhoto, photo.ID, int64(photo.UserID), photo.StorageKey, photo.Kind, photo.Mime, photo.Size)
+	return scanPhoto(row)
+}
+
+func (r *Repository) GetPhotoByKey(ctx context.Context, storageKey string) (*model.Photo, error) {
+
+	photo, err := scanPhoto(r.conn.QueryRow(ctx, sqlSelectPhotoByKey, storageKey))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: photo %s", model.ErrorNotFound, storageKey)
+		}
+		return nil, err
+	}
+
+	return photo, nil
+}
+
+func (r *Repository) GetPhotosByKeys(ctx context.Context, storageKeys []string) ([]*model.Photo, error) {
+	return r.queryPhotos(ctx, sqlSelectPhotosByKeys, storageKeys)
+}
+
+// UpdatePhotoStatus records the outcome of upload verification with the sniffed MIME type and real size.
+func (r *Repository) UpdatePhotoStatus(ctx context.Context, storageKey string, status string, mime string, size int64) (*model.Photo, error) {
+
+	photo, err := scanPhoto(r.conn.QueryRow(ctx, sqlUpdatePhotoStatus, storageKey, status, mime, size))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: photo %s", model.ErrorNotFound, storageKey)
+		}
+		return nil, err
+	}
+
+	return photo, nil
+}
+
+// AttachPhotos links the user's verified, unattached photos to the violation.
+// It fails with model.ErrConflict unless every key was attached.
+func (r *Repository) AttachPhotos(ctx context.Context, violationID model.ViolationID, userID model.UserID, kind string, storageKeys []string) error {
+
+	tag, err := r.conn.Exec(ctx, sqlAttachPhotos, string(violationID), int64(userID), kind, storageKeys)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() != int64(len(storageKeys)) {
+		return fmt.Errorf("%w: %d of %d photos could not be attached", model.ErrConflict, int64(len(storageKeys))-tag.RowsAffected(), len(storageKeys))
+	}
+
+	return nil
+}
+
+func (r *Repository) ListViolationPhotos(ctx context.Context, violationID model.ViolationID) ([]*model.Photo, error) {
+	return r.queryPhotos(ctx, sqlSelectViolationPhotos, string(violationID))
+}
+
+// SetPhotoProcessed stores the variants of a processed photo together with the
+// MIME type and size of the re-encoded original.
+func (r *Repository) SetPhotoProcessed(ctx context.Context, storageKey string, mime string, size int64, variants map[string]*model.PhotoVariant) error {
+
+	stored := make(map[string]storedVariant, len(variants))
+	for name, v := range variants {
+		stored[name] = storedVariant{StorageKey: v.StorageKey, Mime: v.Mime, Width: v.Width, Height: v.Height, Size: v.Size}
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.conn.Exec(ctx, sqlSetPhotoProcessed, storageKey, mime, size, data)
+	return err
+}
+
+func (r *Repository) SetPhotoProcessingFailed(ctx context.Context, storageKey string, reason string) error {
+	_, err := r.conn.Exec(ctx, sqlSetPhotoProcessingFailed, storageKey, reason)
+	return err
+}
+
+func (r *Repository) ListPhotosPendingProcessing(ctx context.Context, limit int) ([]*model.Photo, error) {
+	return r.queryPhotos(ctx, sqlSelectPhotosPendingProcessing, limit)
+}
+
+func (r *Repository) queryPhotos(ctx context.Context, query string, args ...any) ([]*model.Photo, error) {
+
+	rows, err := r.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []*model.Photo{}
+	for rows.Next() {
+		photo, err := scanPhoto(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, photo)
+	}
+
+	return res, rows.Err()
+}
+
+func scanPhoto(row pgx.Row) (*model.Photo, error) {
+
+	var (
+		res         model.Photo
+		violationID *string
+		variants    []byte
+	)
+	if err := row.Scan(
+		&res.ID,
+		&violationID,
+		&res.UserID,
+		&res.StorageKey,
+		&res.Kind,
+		&res.Status,
+		&res.ProcessingStatus,
+		&res.Mime,
+		&res.Size,
+		&variants,
+		&res.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	var stored map[string]storedVariant
+	if err := unmarshalNullable(variants, &stored); err != nil {
+		return nil, err
+	}
+	if stored != nil {
+		res.Variants = make(map[string]*model.PhotoVariant, len(stored))
+		for name, v := range stored {
+			res.Variants[name] = &model.PhotoVariant{StorageKey: v.StorageKey, Mime: v.Mime, Width: v.Width, Height: v.Height, Size: v.Size}
+		}
+	}
+
+	if violationID != nil {
+		res.ViolationID = model.ViolationID(*violationID)
+	}
+
+	return &res, nil
+}
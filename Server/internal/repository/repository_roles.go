@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+const (
+	sqlSelectUserRole = `SELECT role FROM users WHERE user_id = $1;`
+
+	sqlUpdateUserRole = `UPDATE users SET role = $2 WHERE user_id = $1;`
+)
+
+func (r *Repository) GetUserRole(ctx context.Context, userID model.UserID) (model.Role, error) {
+
+	var role model.Role
+	if err := r.conn.QueryRow(ctx, sqlSelectUserRole, int64(userID)).Scan(&role); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("%w: %v", model.ErrorNotFound, err)
+		}
+		return "", err
+	}
+
+	return role, nil
+}
+
+func (r *Repository) SetUserRole(ctx context.Context, userID model.UserID, role model.Role) error {
+
+	tag, err := r.conn.Exec(ctx, sqlUpdateUserRole, int64(userID), string(role))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: user %d", model.ErrorNotFound, userID)
+	}
+
+	return nil
+}
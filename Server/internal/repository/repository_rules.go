@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+const (
+	sqlSelectActiveRules = `
+SELECT id, name, trigger_event, condition, threshold, weight_map, action, action_params
+FROM violation_rules
+WHERE active
+ORDER BY created_at, name;
+`
+)
+
+func (r *Repository) ListActiveRules(ctx context.Context) ([]*model.ViolationRule, error) {
+
+	rows, err := r.conn.Query(ctx, sqlSelectActiveRules)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []*model.ViolationRule{}
+	for rows.Next() {
+		var (
+			rule                             model.ViolationRule
+			threshold                        *int32
+			condition, weightMap, actionArgs []byte
+		)
+
+		if err := rows.Scan(&rule.ID, &rule.Name, &rule.TriggerEvent, &condition, &threshold, &weightMap, &rule.Action, &actionArgs); err != nil {
+			return nil, err
+		}
+
+		if threshold != nil {
+			t := int(*threshold)
+			rule.Threshold = &t
+		}
+
+		if err := unmarshalNullable(condition, &rule.Condition); err != nil {
+			return nil, fmt.Errorf("rule %s: condition: %w", rule.Name, err)
+		}
+		if err := unmarshalNullable(weightMap, &rule.WeightMap); err != nil {
+			return nil, fmt.Errorf("rule %s: weight_map: %w", rule.Name, err)
+		}
+		if err := unmarshalNullable(actionArgs, &rule.ActionParams); err != nil {
+			return nil, fmt.Errorf("rule %s: action_params: %w", rule.Name, err)
+		}
+
+		res = append(res, &rule)
+	}
+
+	return res, rows.Err()
+}
+
+func unmarshalNullable(data []byte, v any) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
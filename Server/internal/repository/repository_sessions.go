@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/inzarubin80/Server/internal/model"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	sessionColumns = `id, family_id, user_id, token_hash, created_at, expires_at, used_at, revoked_at, user_agent, ip`
+
+	sqlInsertSession = `
+INSERT INTO sessions (id, family_id, user_id, token_hash, expires_at, user_agent, ip)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING ` + sessionColumns + `;
+`
+
+	// The old token is marked used and its successor inserted in one statement,
+	// so a token can be rotated only once even under concurrent requests.
+	sqlRotateSession = `
+WITH used AS (
+    UPDATE sessions
+    SET used_at = NOW()
+    WHERE token_hash = $1 AND used_at IS NULL AND revoked_at IS NULL AND expires_at > NOW()
+    RETURNING family_id, user_id
+)
+INSERT INTO sessions (id, family_id, user_id, token_hash, expires_at, user_agent, ip)
+SELECT $2::uuid, family_id, user_id, $3, $4::timestamptz, $5, $6 FROM used
+RETURNING ` + sessionColumns + `;
+`
+
+	sqlSelectSessionByTokenHash = `
+SELECT ` + sessionColumns + `
+FROM sessions
+WHERE token_hash = $1;
+`
+
+	sqlRevokeSessionFamily = `
+UPDATE sessions
+SET revoked_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL;
+`
+
+	sqlRevokeUserSessionFamily = `
+UPDATE sessions
+SET revoked_at = NOW()
+WHERE family_id = $1 AND user_id = $2 AND revoked_at IS NULL;
+`
+
+	sqlRevokeUserSessions = `
+UPDATE sessions
+SET revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
+`
+
+	// A family is active while its latest token is neither used nor revoked.
+	sqlSelectActiveSessionRole = `
+SELECT u.role
+FROM sessions s
+JOIN users u ON u.user_id = s.user_id
+WHERE s.family_id = $1 AND s.used_at IS NULL AND s.revoked_at IS NULL AND s.expires_at > NOW()
+LIMIT 1;
+`
+
+	sqlSelectUserSessions = `
+SELECT s.family_id, s.user_agent, s.ip, f.created_at, s.last_seen_at, s.expires_at
+FROM sessions s
+JOIN (
+    SELECT family_id, MIN(created_at) AS created_at
+    FROM sessions
+    WHERE user_id = $1
+    GROUP BY family_id
+) f ON f.family_id = s.family_id
+WHERE s.user_id = $1 AND s.used_at IS NULL AND s.revoked_at IS NULL AND s.expires_at > NOW()
+ORDER BY s.last_seen_at DESC;
+`
+)
+
+// CreateSession stores the first refresh token of a new session family.
+func (r *Repository) CreateSession(ctx context.Context, session *model.Session) (*model.Session, error) {
+	return scanSession(r.conn.QueryRow(ctx, sqlInsertSession,
+		session.ID, session.FamilyID, int64(session.UserID), session.TokenHash, session.ExpiresAt, session.Client.UserAgent, session.Client.IP))
+}
+
+// RotateSession marks the token with tokenHash used and stores next in the same family.
+// It returns model.ErrorNotFound if the token is unknown, expired, revoked or already used.
+func (r *Repository) RotateSession(ctx context.Context, tokenHash string, next *model.Session) (*model.Session, error) {
+
+	session, err := scanSession(r.conn.QueryRow(ctx, sqlRotateSession, tokenHash, next.ID, next.TokenHash, next.ExpiresAt, next.Client.UserAgent, next.Client.IP))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %v", model.ErrorNotFound, err)
+		}
+		return nil, err
+	}
+
+	return session, nil
+}
+
+func (r *Repository) GetSessionByTokenHash(ctx context.Context, tokenHash string) (*model.Session, error) {
+
+	session, err := scanSession(r.conn.QueryRow(ctx, sqlSelectSessionByTokenHash, tokenHash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %v", model.ErrorNotFound, err)
+		}
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// RevokeSessionFamily revokes every token of the family.
+func (r *Repository) RevokeSessionFamily(ctx context.Context, familyID string) error {
+	_, err := r.conn.Exec(ctx, sqlRevokeSessionFamily, familyID)
+	return err
+}
+
+// RevokeUserSessionFamily revokes one session family of the user. It returns
+// model.ErrorNotFound if the user has no such active session.
+func (r *Repository) RevokeUserSessionFamily(ctx context.Context, userID model.UserID, familyID string) error {
+
+	tag, err := r.conn.Exec(ctx, sqlRevokeUserSessionFamily, familyID, int64(userID))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: session %s", model.ErrorNotFound, familyID)
+	}
+
+	return nil
+}
+
+// RevokeUserSessions revokes every session of the user.
+func (r *Repository) RevokeUserSessions(ctx context.Context, userID model.UserID) error {
+	_, err := r.conn.Exec(ctx, sqlRevokeUserSessions, int64(userID))
+	return err
+}
+
+// GetActiveSessionRole returns the current role of the user of an active session
+// family. It returns model.ErrorNotFound if the family is revoked or expired.
+func (r *Repository) GetActiveSessionRole(ctx context.Context, familyID string) (model.Role, error) {
+
+	var role model.Role
+	if err := r.conn.QueryRow(ctx, sqlSelectActiveSessionRole, familyID).Scan(&role); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("%w: %v", model.ErrorNotFound, err)
+		}
+		return "", err
+	}
+
+	return role, nil
+}
+
+// ListUserSessions returns the active session families of the user, most recently used first.
+func (r *Repository) ListUserSessions(ctx context.Context, userID model.UserID) ([]*model.DeviceSession, error) {
+
+	rows, err := r.conn.Query(ctx, sqlSelectUserSessions, int64(userID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make([]*model.DeviceSession, 0)
+	for rows.Next() {
+		var s model.DeviceSession
+		if err := rows.Scan(&s.ID, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt); err != nil {
+			return nil, err
+		}
+		res = append(res, &s)
+	}
+
+	return res, rows.Err()
+}
+
+func scanSession(row pgx.Row) (*model.Session, error) {
+
+	var res model.Session
+	if err := row.Scan(
+		&res.ID,
+		&res.FamilyID,
+		&res.UserID,
+		&res.TokenHash,
+		&res.CreatedAt,
+		&res.ExpiresAt,
+		&res.UsedAt,
+		&res.RevokedAt,
+		&res.Client.UserAgent,
+		&res.Client.IP,
+	); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
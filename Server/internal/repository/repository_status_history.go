@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+const (
+	// The status update is guarded by the expected current status, so two
+	// concurrent transitions from the same state cannot both succeed.
+	sqlChangeViolationStatus = `
+WITH upd AS (
+    UPDATE violations
+    SET status = $3, resolution_status = 'none', updated_at = NOW()
+    WHERE id = $1 AND status = $2
+    RETURNING ` + violationColumns + `
+), hist AS (
+    INSERT INTO violation_status_history (violation_id, event, from_status, to_status, actor_id, reason, evidence)
+    SELECT id, 'status_change', $2, $3, NULLIF($4::bigint, 0), $5, $6 FROM upd
+)
+SELECT ` + violationColumns + ` FROM upd;
+`
+
+	sqlProposeResolve = `
+WITH upd AS (
+    UPDATE violations
+    SET resolution_status = 'proposed', updated_at = NOW()
+    WHERE id = $1 AND status = $2
+    RETURNING ` + violationColumns + `
+), hist AS (
+    INSERT INTO violation_status_history (violation_id, event, from_status, to_status, actor_id, reason, evidence)
+    SELECT id, 'propose_resolve', $2, $2, NULLIF($3::bigint, 0), $4, $5 FROM upd
+)
+SELECT ` + violationColumns + ` FROM upd;
+`
+
+	sqlSelectStatusHistory = `
+SELECT violation_id, event, from_status, to_status, COALESCE(actor_id, 0), reason, evidence, created_at
+FROM violation_status_history
+WHERE violation_id = $1
+ORDER BY created_at, id;
+`
+)
+
+// ChangeViolationStatus moves the violation from change.FromStatus to change.ToStatus
+// and records the transition. It returns model.ErrConflict if the violation is no
+// longer in change.FromStatus.
+func (r *Repository) ChangeViolationStatus(ctx context.Context, change *model.StatusChange) (*model.Violation, error) {
+
+	row := r.conn.QueryRow(ctx, sqlChangeViolationStatus,
+		string(change.ViolationID), string(change.FromStatus), string(change.ToStatus),
+		int64(change.ActorID), change.Reason, evidence(change.Evidence))
+
+	v, err := scanViolation(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: violation status has changed", model.ErrConflict)
+		}
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// ProposeResolve marks the violation as proposed for resolution and records the
+// proposal with its evidence. change.FromStatus is the expected current status.
+func (r *Repository) ProposeResolve(ctx context.Context, change *model.StatusChange) (*model.Violation, error) {
+
+	row := r.conn.QueryRow(ctx, sqlProposeResolve,
+		string(change.ViolationID), string(change.FromStatus),
+		int64(change.ActorID), change.Reason, evidence(change.Evidence))
+
+	v, err := scanViolation(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: violation status has changed", model.ErrConflict)
+		}
+		return nil, err
+	}
+
+	return v, nil
+}
+
+func (r *Repository) ListStatusHistory(ctx context.Context, violationID model.ViolationID) ([]*model.StatusChange, error) {
+
+	rows, err := r.conn.Query(ctx, sqlSelectStatusHistory, string(violationID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []*model.StatusChange{}
+	for rows.Next() {
+		var c model.StatusChange
+		if err := rows.Scan(&c.ViolationID, &c.Event, &c.FromStatus, &c.ToStatus, &c.ActorID, &c.Reason, &c.Evidence, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		res = append(res, &c)
+	}
+
+	return res, rows.Err()
+}
+
+func evidence(keys []string) []string {
+	if keys == nil {
+		return []string{}
+	}
+	return keys
+}
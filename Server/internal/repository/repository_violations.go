@@ -2,29 +2,364 @@ package repository
 
 import (
 	"context"
-	"time"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
 
-	"github.com/google/uuid"
 	"github.com/inzarubin80/Server/internal/model"
+	"github.com/jackc/pgx/v5"
 )
 
 const (
+	violationColumns = `id, user_id, type, description, lat, lng, status, resolution_status, confirmations_count, created_at, updated_at,
+    moderation_status, moderation_score, moderation_reason, moderated_by, moderated_at`
+
+	// The id may come from the client, so an existing row is left untouched.
 	sqlInsertViolation = `
-INSERT INTO violations (id, user_id, type, description, lat, lng, status, confirmations_count, created_at, updated_at)
-VALUES ($1,$2,$3,$4,$5,$6,$7,$8, NOW(), NOW())
-RETURNING id, user_id, type, description, lat, lng, status, confirmations_count, created_at, updated_at;
+INSERT INTO violations (id, user_id, type, description, lat, lng, status, confirmations_count, moderation_status, created_at, updated_at)
+VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9, NOW(), NOW())
+ON CONFLICT (id) DO NOTHING
+RETURNING ` + violationColumns + `;
+`
+
+	sqlInsertViolationMerge = `
+INSERT INTO violation_merges (id, user_id, target_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (id) DO NOTHING
+RETURNING id;
+`
+
+	sqlSelectViolationMerge = `SELECT user_id, target_id FROM violation_merges WHERE id = $1;`
+
+	sqlSelectViolations = `
+SELECT ` + violationColumns + `
+FROM violations
+`
+
+	sqlSelectViolationByID = sqlSelectViolations + `WHERE id = $1;`
+
+	// Most suspicious reports first; unscored ones in arrival order.
+	sqlSelectPendingModeration = sqlSelectViolations + `WHERE moderation_status = 'pending'
+ORDER BY moderation_score DESC NULLS LAST, created_at, id
+LIMIT $1;`
+
+	// Automated moderation only touches reports no moderator has decided on yet.
+	sqlApplyModerationScore = `
+UPDATE violations
+SET moderation_score = $2, moderation_status = $3::text, moderation_reason = $4,
+    moderated_at = CASE WHEN $3::text <> 'pending' THEN NOW() END, updated_at = NOW()
+WHERE id = $1 AND moderation_status = 'pending' AND moderated_by IS NULL
+RETURNING ` + violationColumns + `;
+`
+
+	// The bbox around the point lets idx_violations_lng_lat narrow the scan before
+	// the exact haversine distance is computed.
+	sqlSelectNearbyViolations = `
+SELECT ` + violationColumns + `, distance
+FROM (
+    SELECT *, 2 * 6371000 * ASIN(SQRT(
+        POWER(SIN(RADIANS(lat - $1) / 2), 2) +
+        COS(RADIANS($1)) * COS(RADIANS(lat)) * POWER(SIN(RADIANS(lng - $2) / 2), 2)
+    )) AS distance
+    FROM violations
+    WHERE lng BETWEEN $3 AND $4 AND lat BETWEEN $5 AND $6
+      AND type = $7 AND status <> 'resolved' AND created_at >= $8
+      AND (moderation_status = 'approved' OR (moderation_status = 'pending' AND user_id = $9))
+) v
+WHERE distance <= $10
+ORDER BY distance, created_at
+LIMIT $11;
+`
+
+	sqlModerateViolation = `
+UPDATE violations
+SET moderation_status = $2, moderation_reason = $3, moderated_by = $4, moderated_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND moderation_status <> $2
+RETURNING ` + violationColumns + `;
 `
 )
 
-func (r *Repository) CreateViolation(ctx context.Context, userID model.UserID, vType model.ViolationType, description string, lat, lng float64) (*model.Violation, error) {
-	id := uuid.New().String()
-	row := r.conn.QueryRow(ctx, sqlInsertViolation, id, int64(userID), string(vType), description, lat, lng, "new", 0)
+// CreateViolation stores a new violation with the given id. It returns
+// model.ErrConflict if a violation with that id already exists.
+func (r *Repository) CreateViolation(ctx context.Context, id model.ViolationID, userID model.UserID, vType model.ViolationType, description string, lat, lng float64, moderationStatus model.ModerationStatus) (*model.Violation, error) {
 
-	var res model.Violation
-	var createdAt time.Time
-	var updatedAt time.Time
+	v, err := scanViolation(r.conn.QueryRow(ctx, sqlInsertViolation, string(id), int64(userID), string(vType), description, lat, lng, string(model.ViolationStatusNew), 0, string(moderationStatus)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: violation %s already exists", model.ErrConflict, id)
+		}
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// AddViolationMerge records that the user's report with the given id was merged
+// into targetID. It returns model.ErrConflict if the id is already recorded.
+func (r *Repository) AddViolationMerge(ctx context.Context, id model.ViolationID, userID model.UserID, targetID model.ViolationID) error {
+
+	var stored string
+	err := r.conn.QueryRow(ctx, sqlInsertViolationMerge, string(id), int64(userID), string(targetID)).Scan(&stored)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: violation %s already merged", model.ErrConflict, id)
+	}
+	return err
+}
+
+// GetViolationMerge returns the user whose report with the given id was merged
+// and the violation it was merged into.
+func (r *Repository) GetViolationMerge(ctx context.Context, id model.ViolationID) (model.UserID, model.ViolationID, error) {
+
+	var (
+		userID   int64
+		targetID string
+	)
+	err := r.conn.QueryRow(ctx, sqlSelectViolationMerge, string(id)).Scan(&userID, &targetID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, "", fmt.Errorf("%w: merge of %s", model.ErrorNotFound, id)
+		}
+		return 0, "", err
+	}
+
+	return model.UserID(userID), model.ViolationID(targetID), nil
+}
+
+func (r *Repository) GetViolation(ctx context.Context, violationID model.ViolationID) (*model.Violation, error) {
+
+	v, err := scanViolation(r.conn.QueryRow(ctx, sqlSelectViolationByID, string(violationID)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %v", model.ErrorNotFound, err)
+		}
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// queryArgs collects the arguments of a query built at run time.
+type queryArgs []any
+
+// add appends the argument and returns its placeholder.
+func (a *queryArgs) add(v any) string {
+	*a = append(*a, v)
+	return fmt.Sprintf("$%d", len(*a))
+}
+
+// violationConditions translates the filter into WHERE conditions.
+// The bbox condition is expressed on (lng, lat) so that idx_violations_lng_lat can be used.
+func violationConditions(filter *model.ViolationFilter, args *queryArgs) []string {
+
+	var conds []string
+	arg := args.add
+
+	if filter.BBox != nil {
+		conds = append(conds, fmt.Sprintf("lng BETWEEN %s AND %s AND lat BETWEEN %s AND %s",
+			arg(filter.BBox.MinLng), arg(filter.BBox.MaxLng), arg(filter.BBox.MinLat), arg(filter.BBox.MaxLat)))
+	}
+
+	if len(filter.Types) > 0 {
+		types := make([]string, len(filter.Types))
+		for i, t := range filter.Types {
+			types[i] = string(t)
+		}
+		conds = append(conds, fmt.Sprintf("type = ANY(%s::text[])", arg(types)))
+	}
+
+	if len(filter.Statuses) > 0 {
+		statuses := make([]string, len(filter.Statuses))
+		for i, s := range filter.Statuses {
+			statuses[i] = string(s)
+		}
+		conds = append(conds, fmt.Sprintf("status = ANY(%s::text[])", arg(statuses)))
+	}
+
+	if filter.ModerationStatus != "" {
+		conds = append(conds, fmt.Sprintf("moderation_status = %s", arg(string(filter.ModerationStatus))))
+	}
+
+	if filter.CreatedFrom != nil {
+		conds = append(conds, fmt.Sprintf("created_at >= %s", arg(*filter.CreatedFrom)))
+	}
+
+	if filter.CreatedTo != nil {
+		conds = append(conds, fmt.Sprintf("created_at < %s", arg(*filter.CreatedTo)))
+	}
+
+	if filter.Cursor != nil {
+		conds = append(conds, fmt.Sprintf("(created_at, id) < (%s, %s::uuid)", arg(filter.Cursor.CreatedAt), arg(string(filter.Cursor.ID))))
+	}
+
+	return conds
+}
+
+func whereClause(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(conds, "\n  AND ") + "\n"
+}
+
+// ListViolations returns violations matching the filter ordered by (created_at, id) descending.
+func (r *Repository) ListViolations(ctx context.Context, filter *model.ViolationFilter) ([]*model.Violation, error) {
 
-	if err := row.Scan(
+	var args queryArgs
+	query := sqlSelectViolations + whereClause(violationConditions(filter, &args))
+	query += "ORDER BY created_at DESC, id DESC\nLIMIT " + args.add(filter.Limit)
+
+	rows, err := r.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make([]*model.Violation, 0, filter.Limit)
+	for rows.Next() {
+		v, err := scanViolation(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, v)
+	}
+
+	return res, rows.Err()
+}
+
+// ExportViolations calls fn for every violation matching the filter, newest first.
+// Rows are read from the connection as fn consumes them, so the result set is
+// never held in memory. The filter's cursor applies; its limit does not.
+func (r *Repository) ExportViolations(ctx context.Context, filter *model.ViolationFilter, fn func(*model.Violation) error) error {
+
+	var args queryArgs
+	query := sqlSelectViolations + whereClause(violationConditions(filter, &args)) + "ORDER BY created_at DESC, id DESC"
+
+	rows, err := r.conn.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		v, err := scanViolation(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// StatViolations returns the number of violations matching the filter and their
+// latest updated_at.
+func (r *Repository) StatViolations(ctx context.Context, filter *model.ViolationFilter) (*model.ViolationStats, error) {
+
+	var args queryArgs
+	query := "SELECT COUNT(*), MAX(updated_at)\nFROM violations\n" + whereClause(violationConditions(filter, &args))
+
+	var res model.ViolationStats
+	if err := r.conn.QueryRow(ctx, query, args...).Scan(&res.Count, &res.LastUpdatedAt); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// ListPendingModeration returns the moderation queue in priority order.
+func (r *Repository) ListPendingModeration(ctx context.Context, limit int) ([]*model.Violation, error) {
+
+	rows, err := r.conn.Query(ctx, sqlSelectPendingModeration, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make([]*model.Violation, 0, limit)
+	for rows.Next() {
+		v, err := scanViolation(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, v)
+	}
+
+	return res, rows.Err()
+}
+
+// ModerateViolation stores the decision. It returns model.ErrConflict if the
+// violation already has the decided moderation status.
+func (r *Repository) ModerateViolation(ctx context.Context, decision *model.ModerationDecision) (*model.Violation, error) {
+
+	var moderatedBy *int64
+	if decision.ModeratorID != 0 {
+		id := int64(decision.ModeratorID)
+		moderatedBy = &id
+	}
+
+	v, err := scanViolation(r.conn.QueryRow(ctx, sqlModerateViolation,
+		string(decision.ViolationID), string(decision.Status), decision.Reason, moderatedBy))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: violation is already %s", model.ErrConflict, decision.Status)
+		}
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// ApplyModerationScore stores the score of automated moderation and its decision,
+// which may be to keep the report pending. It returns model.ErrConflict if the
+// report is no longer pending.
+func (r *Repository) ApplyModerationScore(ctx context.Context, decision *model.ModerationDecision, score float64) (*model.Violation, error) {
+
+	v, err := scanViolation(r.conn.QueryRow(ctx, sqlApplyModerationScore,
+		string(decision.ViolationID), score, string(decision.Status), decision.Reason))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: violation is not pending moderation", model.ErrConflict)
+		}
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// FindNearbyViolations returns the open violations matching the filter, nearest first.
+func (r *Repository) FindNearbyViolations(ctx context.Context, filter *model.NearbyFilter) ([]*model.DuplicateCandidate, error) {
+
+	box := model.BBoxAround(filter.Lat, filter.Lng, filter.RadiusM)
+
+	rows, err := r.conn.Query(ctx, sqlSelectNearbyViolations,
+		filter.Lat, filter.Lng, box.MinLng, box.MaxLng, box.MinLat, box.MaxLat,
+		string(filter.Type), filter.Since, int64(filter.ViewerID), filter.RadiusM, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make([]*model.DuplicateCandidate, 0, filter.Limit)
+	for rows.Next() {
+		var c model.DuplicateCandidate
+		c.Violation, err = scanViolation(rows, &c.DistanceM)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, &c)
+	}
+
+	return res, rows.Err()
+}
+
+// scanViolation scans violationColumns followed by the extra columns of the query.
+func scanViolation(row pgx.Row, extra ...any) (*model.Violation, error) {
+
+	var res model.Violation
+	if err := row.Scan(append([]any{
 		&res.ID,
 		&res.UserID,
 		&res.Type,
@@ -32,16 +367,18 @@ func (r *Repository) CreateViolation(ctx context.Context, userID model.UserID, v
 		&res.Lat,
 		&res.Lng,
 		&res.Status,
+		&res.ResolutionStatus,
 		&res.ConfirmationsCount,
-		&createdAt,
-		&updatedAt,
-	); err != nil {
+		&res.CreatedAt,
+		&res.UpdatedAt,
+		&res.ModerationStatus,
+		&res.ModerationScore,
+		&res.ModerationReason,
+		&res.ModeratedBy,
+		&res.ModeratedAt,
+	}, extra...)...); err != nil {
 		return nil, err
 	}
 
-	res.CreatedAt = createdAt
-	res.UpdatedAt = updatedAt
 	return &res, nil
 }
-
-
@@ -0,0 +1,153 @@
+// Package rules evaluates the database-driven violation_rules against violation events.
+package rules
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+// defaultWeight is the weight of a vote whose role is missing from the rule's weight_map.
+const defaultWeight = 1
+
+type (
+	Repository interface {
+		ListActiveRules(ctx context.Context) ([]*model.ViolationRule, error)
+	}
+
+	// Engine caches active rules and reloads them from the repository once the
+	// cache is older than the reload interval, so rule edits apply without a restart.
+	Engine struct {
+		repository     Repository
+		reloadInterval time.Duration
+
+		mu       sync.RWMutex
+		rules    []*model.ViolationRule
+		loadedAt time.Time
+	}
+)
+
+func NewEngine(repository Repository, reloadInterval time.Duration) *Engine {
+	return &Engine{
+		repository:     repository,
+		reloadInterval: reloadInterval,
+	}
+}
+
+// Reload replaces the cached rules with the active rules from the repository.
+func (e *Engine) Reload(ctx context.Context) error {
+
+	rules, err := e.repository.ListActiveRules(ctx)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	e.rules = rules
+	e.loadedAt = time.Now()
+	e.mu.Unlock()
+
+	log.Printf("rules: loaded %d active rules", len(rules))
+	return nil
+}
+
+// Evaluate returns the rules triggered by the event, in rule order.
+// Rules whose action would not change the violation are not reported as fired.
+func (e *Engine) Evaluate(ctx context.Context, event *model.RuleEvent) ([]*model.RuleFiring, error) {
+
+	rules, err := e.activeRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var firings []*model.RuleFiring
+	for _, rule := range rules {
+		if rule.TriggerEvent != event.Type || !matches(rule.Condition, event) {
+			continue
+		}
+
+		score := score(rule.WeightMap, event.VoterRoles)
+		if rule.Threshold != nil && score < *rule.Threshold {
+			continue
+		}
+
+		if !changesViolation(rule, event.Violation) {
+			continue
+		}
+
+		log.Printf("rules: rule %q fired on %s for violation %s (score %d, action %s)",
+			rule.Name, event.Type, event.Violation.ID, score, rule.Action)
+		firings = append(firings, &model.RuleFiring{Rule: rule, Score: score})
+	}
+
+	return firings, nil
+}
+
+func (e *Engine) activeRules(ctx context.Context) ([]*model.ViolationRule, error) {
+
+	e.mu.RLock()
+	rules, fresh := e.rules, !e.loadedAt.IsZero() && time.Since(e.loadedAt) < e.reloadInterval
+	e.mu.RUnlock()
+
+	if fresh {
+		return rules, nil
+	}
+
+	if err := e.Reload(ctx); err != nil {
+		// Keep evaluating with the previous rules rather than failing user requests.
+		if rules != nil {
+			log.Printf("rules: reload failed, using cached rules: %v", err)
+			return rules, nil
+		}
+		return nil, err
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.rules, nil
+}
+
+func matches(c model.RuleCondition, event *model.RuleEvent) bool {
+
+	if c.ActorRole != nil && *c.ActorRole != event.ActorRole {
+		return false
+	}
+
+	if c.MinPhotos != nil && event.PhotoCount < *c.MinPhotos {
+		return false
+	}
+
+	if c.AuthorIsReporter != nil && (event.ActorID == event.Violation.UserID) != *c.AuthorIsReporter {
+		return false
+	}
+
+	return true
+}
+
+func score(weightMap map[string]int, voterRoles []string) int {
+
+	total := 0
+	for _, role := range voterRoles {
+		weight, ok := weightMap[role]
+		if !ok {
+			weight = defaultWeight
+		}
+		total += weight
+	}
+	return total
+}
+
+func changesViolation(rule *model.ViolationRule, v *model.Violation) bool {
+
+	switch rule.Action {
+	case model.RuleActionSetStatus:
+		status, _ := rule.ActionParams["status"].(string)
+		return model.ViolationStatus(status) != v.Status
+	case model.RuleActionAutoResolve:
+		return v.Status != model.ViolationStatusResolved
+	}
+	return true
+}
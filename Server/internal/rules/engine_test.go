@@ -0,0 +1,239 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+func TestEvaluate(t *testing.T) {
+
+	threshold := func(n int) *int { return &n }
+	moderator := string(model.RoleModerator)
+	minPhotos := 2
+	byReporter := true
+
+	confirmAt := func(n int, weights map[string]int) *model.ViolationRule {
+		return &model.ViolationRule{
+			Name:         "confirm",
+			TriggerEvent: model.RuleEventConfirmation,
+			Threshold:    threshold(n),
+			WeightMap:    weights,
+			Action:       model.RuleActionSetStatus,
+			ActionParams: map[string]any{"status": string(model.ViolationStatusConfirmed)},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		rule      *model.ViolationRule
+		event     *model.RuleEvent
+		wantScore int
+		fired     bool
+	}{
+		{
+			name:  "below threshold",
+			rule:  confirmAt(3, nil),
+			event: &model.RuleEvent{Type: model.RuleEventConfirmation, VoterRoles: []string{"user", "user"}},
+		},
+		{
+			name:      "threshold reached",
+			rule:      confirmAt(3, nil),
+			event:     &model.RuleEvent{Type: model.RuleEventConfirmation, VoterRoles: []string{"user", "user", "user"}},
+			wantScore: 3, fired: true,
+		},
+		{
+			name:      "weighted vote reaches threshold",
+			rule:      confirmAt(3, map[string]int{moderator: 3}),
+			event:     &model.RuleEvent{Type: model.RuleEventConfirmation, VoterRoles: []string{moderator}},
+			wantScore: 3, fired: true,
+		},
+		{
+			name:  "role missing from weight map counts once",
+			rule:  confirmAt(3, map[string]int{moderator: 3}),
+			event: &model.RuleEvent{Type: model.RuleEventConfirmation, VoterRoles: []string{"user", "partner"}},
+		},
+		{
+			name:  "zero weight",
+			rule:  confirmAt(1, map[string]int{"user": 0}),
+			event: &model.RuleEvent{Type: model.RuleEventConfirmation, VoterRoles: []string{"user", "user"}},
+		},
+		{
+			name:  "other trigger",
+			rule:  confirmAt(1, nil),
+			event: &model.RuleEvent{Type: model.RuleEventCreate, VoterRoles: []string{"user"}},
+		},
+		{
+			name: "status already set",
+			rule: confirmAt(1, nil),
+			event: &model.RuleEvent{Type: model.RuleEventConfirmation, VoterRoles: []string{"user"},
+				Violation: &model.Violation{ID: "v-1", Status: model.ViolationStatusConfirmed}},
+		},
+		{
+			name: "actor role matches",
+			rule: &model.ViolationRule{Name: "override", TriggerEvent: model.RuleEventConfirmation, Threshold: threshold(1),
+				Condition: model.RuleCondition{ActorRole: &moderator}, Action: model.RuleActionNotify},
+			event:     &model.RuleEvent{Type: model.RuleEventConfirmation, ActorRole: moderator, VoterRoles: []string{moderator}},
+			wantScore: 1, fired: true,
+		},
+		{
+			name: "actor role differs",
+			rule: &model.ViolationRule{Name: "override", TriggerEvent: model.RuleEventConfirmation,
+				Condition: model.RuleCondition{ActorRole: &moderator}, Action: model.RuleActionNotify},
+			event: &model.RuleEvent{Type: model.RuleEventConfirmation, ActorRole: "user"},
+		},
+		{
+			name: "too few photos",
+			rule: &model.ViolationRule{Name: "photos", TriggerEvent: model.RuleEventProposeResolve,
+				Condition: model.RuleCondition{MinPhotos: &minPhotos}, Action: model.RuleActionNotify},
+			event: &model.RuleEvent{Type: model.RuleEventProposeResolve, PhotoCount: 1},
+		},
+		{
+			name: "proposal by the reporter",
+			rule: &model.ViolationRule{Name: "reporter", TriggerEvent: model.RuleEventProposeResolve,
+				Condition: model.RuleCondition{AuthorIsReporter: &byReporter, MinPhotos: &minPhotos}, Action: model.RuleActionNotify},
+			event: &model.RuleEvent{Type: model.RuleEventProposeResolve, ActorID: 1, PhotoCount: 2,
+				Violation: &model.Violation{ID: "v-1", UserID: 1, Status: model.ViolationStatusConfirmed}},
+			fired: true,
+		},
+		{
+			name: "proposal by someone else",
+			rule: &model.ViolationRule{Name: "reporter", TriggerEvent: model.RuleEventProposeResolve,
+				Condition: model.RuleCondition{AuthorIsReporter: &byReporter}, Action: model.RuleActionNotify},
+			event: &model.RuleEvent{Type: model.RuleEventProposeResolve, ActorID: 2,
+				Violation: &model.Violation{ID: "v-1", UserID: 1, Status: model.ViolationStatusConfirmed}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			if tt.event.Violation == nil {
+				tt.event.Violation = &model.Violation{ID: "v-1", UserID: 1, Status: model.ViolationStatusNew}
+			}
+
+			e := NewEngine(&fakeRepository{rules: []*model.ViolationRule{tt.rule}}, time.Hour)
+			firings, err := e.Evaluate(context.Background(), tt.event)
+			if err != nil {
+				t.Fatalf("Evaluate: %v", err)
+			}
+
+			if got := len(firings) == 1; got != tt.fired {
+				t.Fatalf("fired = %v, want %v", got, tt.fired)
+			}
+			if tt.fired && firings[0].Score != tt.wantScore {
+				t.Errorf("score = %d, want %d", firings[0].Score, tt.wantScore)
+			}
+		})
+	}
+}
+
+func TestEngineReload(t *testing.T) {
+
+	ctx := context.Background()
+	event := &model.RuleEvent{
+		Type:       model.RuleEventConfirmation,
+		Violation:  &model.Violation{ID: "v-1", Status: model.ViolationStatusNew},
+		VoterRoles: []string{"user"},
+	}
+	notify := func(name string) *model.ViolationRule {
+		return &model.ViolationRule{Name: name, TriggerEvent: model.RuleEventConfirmation, Action: model.RuleActionNotify}
+	}
+	fired := func(e *Engine) string {
+		t.Helper()
+		firings, err := e.Evaluate(ctx, event)
+		if err != nil {
+			t.Fatalf("Evaluate: %v", err)
+		}
+		if len(firings) != 1 {
+			t.Fatalf("fired %d rules, want 1", len(firings))
+		}
+		return firings[0].Rule.Name
+	}
+
+	t.Run("cached until the interval passes", func(t *testing.T) {
+
+		repo := &fakeRepository{rules: []*model.ViolationRule{notify("old")}}
+		e := NewEngine(repo, time.Hour)
+
+		if got := fired(e); got != "old" {
+			t.Fatalf("fired %q, want old", got)
+		}
+		repo.set([]*model.ViolationRule{notify("new")}, nil)
+		if got := fired(e); got != "old" {
+			t.Errorf("fired %q from a fresh cache, want old", got)
+		}
+		if repo.calls() != 1 {
+			t.Errorf("loaded %d times, want 1", repo.calls())
+		}
+
+		if err := e.Reload(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if got := fired(e); got != "new" {
+			t.Errorf("fired %q after Reload, want new", got)
+		}
+	})
+
+	t.Run("stale cache is reloaded", func(t *testing.T) {
+
+		repo := &fakeRepository{rules: []*model.ViolationRule{notify("old")}}
+		e := NewEngine(repo, 0)
+
+		fired(e)
+		repo.set([]*model.ViolationRule{notify("new")}, nil)
+		if got := fired(e); got != "new" {
+			t.Errorf("fired %q, want new", got)
+		}
+	})
+
+	t.Run("failed reload keeps the cached rules", func(t *testing.T) {
+
+		repo := &fakeRepository{rules: []*model.ViolationRule{notify("old")}}
+		e := NewEngine(repo, 0)
+
+		fired(e)
+		repo.set(nil, errors.New("db is down"))
+		if got := fired(e); got != "old" {
+			t.Errorf("fired %q, want old", got)
+		}
+	})
+
+	t.Run("failed first load", func(t *testing.T) {
+
+		e := NewEngine(&fakeRepository{err: errors.New("db is down")}, time.Hour)
+		if _, err := e.Evaluate(ctx, event); err == nil {
+			t.Error("Evaluate succeeded without rules")
+		}
+	})
+}
+
+type fakeRepository struct {
+	mu    sync.Mutex
+	rules []*model.ViolationRule
+	err   error
+	loads int
+}
+
+func (r *fakeRepository) ListActiveRules(ctx context.Context) ([]*model.ViolationRule, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.loads++
+	return r.rules, r.err
+}
+
+func (r *fakeRepository) set(rules []*model.ViolationRule, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rules, r.err = rules, err
+}
+
+func (r *fakeRepository) calls() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.loads
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+type (
+	// EventHandler processes a domain event. Errors are logged by the bus; they
+	// never fail the operation that published the event.
+	EventHandler func(ctx context.Context, event model.Event) error
+
+	// EventBus delivers domain events to its subscribers. Synchronous subscribers
+	// run in the publisher's goroutine in subscription order; asynchronous ones get
+	// their own goroutine and queue, so a slow subscriber does not delay requests.
+	// Delivery is best effort; durable side effects also go through the outbox.
+	EventBus struct {
+		mu          sync.RWMutex
+		subscribers []*subscriber
+		wg          sync.WaitGroup
+	}
+
+	subscriber struct {
+		name   string
+		handle EventHandler
+		// queue is nil for synchronous subscribers.
+		queue chan published
+	}
+
+	published struct {
+		ctx   context.Context
+		event model.Event
+	}
+)
+
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe registers a handler that is called synchronously from Publish.
+// Handlers must not block.
+func (b *EventBus) Subscribe(name string, handle EventHandler) {
+	b.add(&subscriber{name: name, handle: handle})
+}
+
+// SubscribeAsync registers a handler that is called from a dedicated goroutine.
+// The handler gets the publisher's context without its cancellation, so request
+// values such as the request id are kept. Events are dropped (and logged) when
+// more than queueSize are pending.
+func (b *EventBus) SubscribeAsync(name string, handle EventHandler, queueSize int) {
+
+	sub := &subscriber{name: name, handle: handle, queue: make(chan published, queueSize)}
+	b.add(sub)
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		for p := range sub.queue {
+			sub.deliver(p.ctx, p.event)
+		}
+	}()
+}
+
+func (b *EventBus) add(sub *subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers = append(b.subscribers, sub)
+}
+
+// Publish delivers the event to every subscriber.
+func (b *EventBus) Publish(ctx context.Context, event model.Event) {
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, sub := range b.subscribers {
+		if sub.queue == nil {
+			sub.deliver(ctx, event)
+			continue
+		}
+
+		select {
+		case sub.queue <- published{ctx: context.WithoutCancel(ctx), event: event}:
+		default:
+			log.Printf("events: %s queue is full, dropping %s for violation %s", sub.name, event.EventType(), event.Subject().ID)
+		}
+	}
+}
+
+// Close stops accepting events and waits for asynchronous subscribers to drain their queues.
+func (b *EventBus) Close() {
+
+	b.mu.Lock()
+	for _, sub := range b.subscribers {
+		if sub.queue != nil {
+			close(sub.queue)
+		}
+	}
+	b.subscribers = nil
+	b.mu.Unlock()
+
+	b.wg.Wait()
+}
+
+func (s *subscriber) deliver(ctx context.Context, event model.Event) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("events: %s panicked on %s: %v", s.name, event.EventType(), r)
+		}
+	}()
+
+	if err := s.handle(ctx, event); err != nil {
+		log.Printf("events: %s failed on %s for violation %s: %v", s.name, event.EventType(), event.Subject().ID, err)
+	}
+}
@@ -2,52 +2,161 @@ package service
 
 import (
 	"context"
+	"io"
+	"time"
 
 	authinterface "github.com/inzarubin80/Server/internal/app/authinterface"
+	"github.com/inzarubin80/Server/internal/imageproc"
 	"github.com/inzarubin80/Server/internal/model"
+	"github.com/inzarubin80/Server/internal/storage"
 )
 
 type (
 	PokerService struct {
 		repository          Repository
-		hub                 Hub
+		events              EventPublisher
 		accessTokenService  TokenService
 		refreshTokenService TokenService
 		providersUserData   authinterface.ProvidersUserData
+		rules               RulesEngine
+		objectStorage       ObjectStorage
+		imageQueue          ImageQueue
+		moderator           Moderator
+		push                PushSender
+		notifier            Notifier
+		config              Config
 	}
 
-	Repository interface {
+	// Config holds the tunable limits of the service.
+	Config struct {
+		MaxPhotoSize    int64
+		MaxReportSize   int64
+		MaxReportPhotos int
+		UploadURLTTL    time.Duration
+		RefreshTokenTTL time.Duration
+		Image           imageproc.Options
+		Moderation      ModerationConfig
+		Dedup           DedupConfig
+		Clusters        ClusterConfig
+		Tiles           TileConfig
+		Export          ExportConfig
+		Comments        CommentConfig
+	}
+
+	CommentConfig struct {
+		// EditWindow is how long after posting authors may edit or delete a comment.
+		EditWindow time.Duration
+	}
+
+	ExportConfig struct {
+		// ReporterSecret keys the pseudonyms that replace authors in exports.
+		ReporterSecret string
+	}
+
+	// TileConfig bounds the vector tiles of the map. A tile holds at most
+	// MaxFeatures violations, the newest ones.
+	TileConfig struct {
+		MaxFeatures int
+		// MaxAge is how long clients and proxies may use a tile without revalidating it.
+		MaxAge time.Duration
+	}
+
+	// ClusterConfig decides when the map shows violations instead of clusters:
+	// from PointsZoom on, if the bbox holds at most MaxPoints of them.
+	ClusterConfig struct {
+		PointsZoom int
+		MaxPoints  int
+	}
+
+	// DedupConfig defines which reports count as duplicates of a new one: open
+	// reports of the same type within RadiusM metres created in the last Window.
+	DedupConfig struct {
+		Policy        model.DedupPolicy
+		RadiusM       float64
+		Window        time.Duration
+		MaxCandidates int
+	}
+
+	ModerationConfig struct {
+		// InitialStatus is the moderation status of new reports: pending keeps them
+		// hidden until a moderator approves them, approved publishes them at once.
+		InitialStatus model.ModerationStatus
+		// Automated moderation approves reports scored below AutoApproveBelow,
+		// rejects those scored RejectFrom or more and queues the rest.
+		AutoApproveBelow float64
+		RejectFrom       float64
+	}
 
-		//User
-		GetUserAuthProvidersByProviderUid(ctx context.Context, ProviderUid string, Provider string) (*model.UserAuthProviders, error)
-		AddUserAuthProviders(ctx context.Context, userProfileFromProvide *model.UserProfileFromProvider, userID model.UserID) (*model.UserAuthProviders, error)
-		CreateUser(ctx context.Context, userData *model.UserProfileFromProvider) (*model.User, error)
-		GetUsersByIDs(ctx context.Context, userIDs []model.UserID) ([]*model.User, error)
-			SetUserName(ctx context.Context, userID model.UserID, name string) error
-			CreateViolation(ctx context.Context, userID model.UserID, vType model.ViolationType, description string, lat, lng float64) (*model.Violation, error)
-		GetUser(ctx context.Context, userID model.UserID) (*model.User, error)
+	// Moderator scores reports automatically. It returns a nil result when the
+	// score is delivered later through ApplyModerationResult.
+	Moderator interface {
+		Moderate(ctx context.Context, req *model.ModerationRequest) (*model.ModerationResult, error)
+	}
+
+	// PushSender delivers push notifications. Send returns an error matching
+	// model.ErrInvalidPushToken when the device token is no longer valid.
+	PushSender interface {
+		Supports(platform string) bool
+		Send(ctx context.Context, msg *model.PushMessage) error
+	}
+
+	// Notifier delivers new inbox notifications to the user's open realtime
+	// connections; users who are not connected see them on the next listing.
+	Notifier interface {
+		NotifyUser(userID model.UserID, notification *model.Notification, unreadCount int)
+	}
+
+	// Repository is the storage of the service. Changes that must be atomic run
+	// through Transact.
+	Repository interface {
+		storage.Repository
+		storage.TransactionProvider
 	}
 
 	TokenService interface {
-		GenerateToken(userID model.UserID) (string, error)
+		GenerateToken(userID model.UserID, role model.Role, sessionID string) (string, error)
 		ValidateToken(tokenString string) (*model.Claims, error)
 	}
 
 		ProviderUserData interface{}
 
-	Hub interface {
-		AddMessage(pokerID model.PokerID, payload any) error
-		AddMessageForUser(pokerID model.PokerID, userID model.UserID, payload any) error
-		GetActiveUsersID(pokerID model.PokerID) ([]model.UserID, error)
+	ObjectStorage interface {
+		PresignPut(ctx context.Context, key string, contentType string, size int64, ttl time.Duration) (*model.PresignedUpload, error)
+		Stat(ctx context.Context, key string) (*model.ObjectInfo, error)
+		Get(ctx context.Context, key string) (io.ReadCloser, error)
+		Put(ctx context.Context, key string, contentType string, body io.Reader, size int64) error
+		Delete(ctx context.Context, key string) error
+		URL(key string) string
+	}
+
+	// ImageQueue schedules uploaded photos for background processing.
+	ImageQueue interface {
+		Enqueue(storageKey string)
+	}
+
+	RulesEngine interface {
+		Evaluate(ctx context.Context, event *model.RuleEvent) ([]*model.RuleFiring, error)
+	}
+
+	// EventPublisher delivers domain events to their subscribers (see EventBus).
+	EventPublisher interface {
+		Publish(ctx context.Context, event model.Event)
 	}
 )
 
-func NewPokerService(repository Repository, hub Hub, accessTokenService TokenService, refreshTokenService TokenService, providersUserData authinterface.ProvidersUserData) *PokerService {
+func NewPokerService(repository Repository, events EventPublisher, accessTokenService TokenService, refreshTokenService TokenService, providersUserData authinterface.ProvidersUserData, rules RulesEngine, objectStorage ObjectStorage, imageQueue ImageQueue, moderator Moderator, push PushSender, notifier Notifier, config Config) *PokerService {
 	return &PokerService{
 		repository:          repository,
-		hub:                 hub,
+		events:              events,
 		accessTokenService:  accessTokenService,
 		refreshTokenService: refreshTokenService,
 		providersUserData:   providersUserData,
+		rules:               rules,
+		objectStorage:       objectStorage,
+		imageQueue:          imageQueue,
+		moderator:           moderator,
+		push:                push,
+		notifier:            notifier,
+		config:              config,
 	}
 }
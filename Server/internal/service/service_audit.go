@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/inzarubin80/Server/internal/model"
+	"github.com/inzarubin80/Server/internal/storage"
+)
+
+const (
+	defaultAuditPageSize = 100
+	maxAuditPageSize     = 500
+)
+
+// auditIgnoredFields change with every write and would only add noise to diffs.
+var auditIgnoredFields = map[string]bool{"updated_at": true}
+
+// audit records a state change. before is nil for creations; before and after
+// are compared field by field through their JSON representation.
+// It is written through repo so that it shares the transaction of the change.
+func (s *PokerService) audit(ctx context.Context, repo storage.Repository, actorID model.UserID, action, entityType, entityID string, before, after any) error {
+
+	diff, err := auditDiff(before, after)
+	if err != nil {
+		return fmt.Errorf("audit %s: %w", action, err)
+	}
+
+	meta := model.RequestMetaFrom(ctx)
+
+	return repo.AddAuditLog(ctx, &model.AuditEntry{
+		ActorID:    actorID,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Diff:       diff,
+		RequestID:  meta.RequestID,
+		IP:         meta.IP,
+	})
+}
+
+// HandleAuditEvent is the event bus subscriber of the audit log. It records
+// every published domain event with its id, so that notifications, which carry
+// the id of their event, can be traced back to the change that caused them.
+// The state changes themselves are audited in their own transactions.
+func (s *PokerService) HandleAuditEvent(ctx context.Context, event model.Event) error {
+
+	published := map[string]any{"event_id": event.EventID(), "type": event.EventType()}
+	return s.audit(ctx, s.repository, event.Actor(), model.AuditEventPublish, model.AuditEntityViolation, string(event.Subject().ID), nil, published)
+}
+
+func auditDiff(before, after any) (map[string]model.AuditChange, error) {
+
+	from, err := auditFields(before)
+	if err != nil {
+		return nil, err
+	}
+	to, err := auditFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := make(map[string]model.AuditChange)
+	for name, value := range to {
+		if auditIgnoredFields[name] || reflect.DeepEqual(from[name], value) {
+			continue
+		}
+		diff[name] = model.AuditChange{From: from[name], To: value}
+	}
+	for name, value := range from {
+		if _, ok := to[name]; !ok && !auditIgnoredFields[name] {
+			diff[name] = model.AuditChange{From: value}
+		}
+	}
+
+	return diff, nil
+}
+
+func auditFields(v any) (map[string]any, error) {
+
+	fields := make(map[string]any)
+	if v == nil {
+		return fields, nil
+	}
+	if rv := reflect.ValueOf(v); (rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Map) && rv.IsNil() {
+		return fields, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// ListAuditLogs returns audit entries for admins, newest first. cursor is the
+// next_cursor of the previous page.
+func (s *PokerService) ListAuditLogs(ctx context.Context, filter *model.AuditFilter, cursor string) (*model.AuditPage, error) {
+
+	query := *filter
+
+	if cursor != "" {
+		id, err := strconv.ParseInt(cursor, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("%w: cursor", model.ErrInvalidParameter)
+		}
+		query.BeforeID = id
+	}
+
+	pageSize := filter.Limit
+	if pageSize <= 0 {
+		pageSize = defaultAuditPageSize
+	}
+	if pageSize > maxAuditPageSize {
+		pageSize = maxAuditPageSize
+	}
+	query.Limit = pageSize + 1
+
+	items, err := s.repository.ListAuditLogs(ctx, &query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &model.AuditPage{Items: items}
+	if len(items) > pageSize {
+		page.Items = items[:pageSize]
+		page.NextCursor = strconv.FormatInt(page.Items[pageSize-1].ID, 10)
+	}
+
+	return page, nil
+}
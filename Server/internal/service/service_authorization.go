@@ -2,12 +2,37 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/inzarubin80/Server/internal/model"
 )
 
+// Authorization validates the access token and checks that its session has not
+// been revoked by a logout. A token issued before the user's role changed is
+// rejected, so the client refreshes it and gets the new role.
 func (s *PokerService) Authorization(ctx context.Context, accessToken string) (*model.Claims, error) {
 
-	return s.accessTokenService.ValidateToken(accessToken)
+	claims, err := s.accessTokenService.ValidateToken(accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.SessionID == "" {
+		return nil, fmt.Errorf("%w: token has no session", model.ErrUnauthorized)
+	}
+
+	role, err := s.repository.GetActiveSessionRole(ctx, claims.SessionID)
+	if err != nil {
+		if errors.Is(err, model.ErrorNotFound) {
+			return nil, fmt.Errorf("%w: session is revoked", model.ErrUnauthorized)
+		}
+		return nil, err
+	}
+	if role != claims.Role {
+		return nil, fmt.Errorf("%w: role has changed, refresh the token", model.ErrUnauthorized)
+	}
+
+	return claims, nil
 
 }
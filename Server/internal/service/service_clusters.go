@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+const (
+	maxClusterZoom = 22
+	// Cells are a quarter of a 256 px map tile wide, so markers of neighbouring
+	// clusters do not overlap.
+	clusterCellPixels = 64
+	// maxClusterCells bounds the grid of one request: a bbox far larger than a
+	// screen at the requested zoom is rejected.
+	maxClusterCells = 10000
+)
+
+// ClusterViolations returns the approved violations in filter.BBox grouped into
+// grid cells sized for the zoom level. At zoom ClusterConfig.PointsZoom and above
+// the violations are returned as points if there are at most MaxPoints of them.
+//
+// The cells are square in degrees rather than in Web Mercator pixels, so they
+// get taller on screen towards the poles; that is fine for marker clustering.
+func (s *PokerService) ClusterViolations(ctx context.Context, filter *model.ViolationFilter, zoom int) (*model.ViolationClusters, error) {
+
+	if filter.BBox == nil {
+		return nil, fmt.Errorf("%w: bbox is required", model.ErrInvalidParameter)
+	}
+	if zoom < 0 || zoom > maxClusterZoom {
+		return nil, fmt.Errorf("%w: zoom must be between 0 and %d", model.ErrInvalidParameter, maxClusterZoom)
+	}
+	if err := validateViolationFilter(filter); err != nil {
+		return nil, err
+	}
+
+	query := *filter
+	query.Cursor = nil
+	query.ModerationStatus = model.ModerationApproved
+
+	res := &model.ViolationClusters{Zoom: zoom, Clusters: []*model.ViolationCluster{}, Points: []*model.Violation{}}
+
+	if zoom >= s.config.Clusters.PointsZoom {
+		query.Limit = s.config.Clusters.MaxPoints + 1
+		points, err := s.repository.ListViolations(ctx, &query)
+		if err != nil {
+			return nil, err
+		}
+		if len(points) <= s.config.Clusters.MaxPoints {
+			res.Points = points
+			return res, nil
+		}
+	}
+
+	cellSize := 360 / math.Exp2(float64(zoom)) * clusterCellPixels / 256
+	b := filter.BBox
+	if cells := math.Ceil((b.MaxLng-b.MinLng)/cellSize+1) * math.Ceil((b.MaxLat-b.MinLat)/cellSize+1); cells > maxClusterCells {
+		return nil, fmt.Errorf("%w: bbox is too large for zoom %d", model.ErrInvalidParameter, zoom)
+	}
+
+	cells, err := s.repository.ClusterViolations(ctx, &query, cellSize)
+	if err != nil {
+		return nil, err
+	}
+
+	res.Clusters = mergeClusterCells(cells)
+	return res, nil
+}
+
+// mergeClusterCells combines the per type and status counts of each grid cell
+// into one cluster, largest clusters first.
+func mergeClusterCells(cells []*model.ClusterCell) []*model.ViolationCluster {
+
+	type key struct{ x, y int64 }
+
+	byCell := make(map[key]*model.ViolationCluster)
+	clusters := make([]*model.ViolationCluster, 0)
+
+	for _, c := range cells {
+		cluster, ok := byCell[key{c.X, c.Y}]
+		if !ok {
+			cluster = &model.ViolationCluster{
+				ByType:      make(map[model.ViolationType]int),
+				ByStatus:    make(map[model.ViolationStatus]int),
+				ViolationID: c.ViolationID,
+			}
+			byCell[key{c.X, c.Y}] = cluster
+			clusters = append(clusters, cluster)
+		}
+
+		// Lat and Lng accumulate weighted sums until the loop below divides them.
+		cluster.Lat += c.Lat * float64(c.Count)
+		cluster.Lng += c.Lng * float64(c.Count)
+		cluster.Count += c.Count
+		cluster.ByType[c.Type] += c.Count
+		cluster.ByStatus[c.Status] += c.Count
+	}
+
+	for _, cluster := range clusters {
+		cluster.Lat /= float64(cluster.Count)
+		cluster.Lng /= float64(cluster.Count)
+		if cluster.Count > 1 {
+			cluster.ViolationID = ""
+		}
+	}
+
+	sort.Slice(clusters, func(i, j int) bool {
+		if clusters[i].Count != clusters[j].Count {
+			return clusters[i].Count > clusters[j].Count
+		}
+		if clusters[i].Lat != clusters[j].Lat {
+			return clusters[i].Lat < clusters[j].Lat
+		}
+		return clusters[i].Lng < clusters[j].Lng
+	})
+
+	return clusters
+}
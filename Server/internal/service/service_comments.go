@@ -0,0 +1,397 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/inzarubin80/Server/internal/model"
+	"github.com/inzarubin80/Server/internal/storage"
+)
+
+const (
+	maxCommentLength = 2000
+
+	defaultCommentsPageSize = 50
+	maxCommentsPageSize     = 200
+)
+
+// AddComment posts a comment on a published report. With automated moderation
+// the comment is pending, visible to its author only, until it has been scored.
+func (s *PokerService) AddComment(ctx context.Context, userID model.UserID, violationID model.ViolationID, text string) (*model.Comment, error) {
+
+	text, err := validateCommentText(text)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := s.getPublicViolation(ctx, violationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		comment *model.Comment
+		event   *model.CommentAdded
+	)
+	err = s.repository.Transact(ctx, func(tx storage.Adapters) error {
+
+		var err error
+		comment, err = tx.Repository.CreateComment(ctx, &model.Comment{
+			ViolationID:      v.ID,
+			Author:           model.UserBrief{ID: userID},
+			Text:             text,
+			ModerationStatus: s.newCommentStatus(),
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := s.audit(ctx, tx.Repository, userID, model.AuditCommentCreate, model.AuditEntityComment, commentEntityID(comment), nil, comment); err != nil {
+			return err
+		}
+
+		event, err = s.commentPublished(ctx, tx.Repository, v, nil, comment)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if event != nil {
+		s.events.Publish(ctx, event)
+	}
+	return comment, nil
+}
+
+// ListComments returns the thread of a violation, oldest first. Moderators also
+// see hidden and pending comments; authors see their own.
+func (s *PokerService) ListComments(ctx context.Context, violationID model.ViolationID, viewer model.Viewer, cursor string, limit int) (*model.CommentsPage, error) {
+
+	v, err := s.getViolation(ctx, violationID)
+	if err != nil {
+		return nil, err
+	}
+	if !viewer.CanSee(v) {
+		return nil, fmt.Errorf("%w: violation %s", model.ErrorNotFound, violationID)
+	}
+
+	filter := &model.CommentFilter{
+		ViolationID: violationID,
+		ViewerID:    viewer.UserID,
+		AllStatuses: viewer.Role.AtLeast(model.RoleModerator),
+	}
+
+	if cursor != "" {
+		id, err := strconv.ParseInt(cursor, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("%w: cursor", model.ErrInvalidParameter)
+		}
+		filter.AfterID = model.CommentID(id)
+	}
+
+	pageSize := limit
+	if pageSize <= 0 {
+		pageSize = defaultCommentsPageSize
+	}
+	if pageSize > maxCommentsPageSize {
+		pageSize = maxCommentsPageSize
+	}
+	filter.Limit = pageSize + 1
+
+	items, err := s.repository.ListComments(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &model.CommentsPage{Items: items}
+	if len(items) > pageSize {
+		page.Items = items[:pageSize]
+		page.NextCursor = strconv.FormatInt(int64(page.Items[pageSize-1].ID), 10)
+	}
+
+	return page, nil
+}
+
+// EditComment replaces the text of the user's comment within the edit window.
+// The new text is moderated again.
+func (s *PokerService) EditComment(ctx context.Context, userID model.UserID, violationID model.ViolationID, commentID model.CommentID, text string) (*model.Comment, error) {
+
+	text, err := validateCommentText(text)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := s.ownComment(ctx, userID, violationID, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := s.getViolation(ctx, violationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		comment *model.Comment
+		event   *model.CommentAdded
+	)
+	err = s.repository.Transact(ctx, func(tx storage.Adapters) error {
+
+		var err error
+		comment, err = tx.Repository.UpdateCommentText(ctx, commentID, text, s.newCommentStatus())
+		if err != nil {
+			return err
+		}
+
+		if err := s.audit(ctx, tx.Repository, userID, model.AuditCommentEdit, model.AuditEntityComment, commentEntityID(comment), before, comment); err != nil {
+			return err
+		}
+
+		event, err = s.commentPublished(ctx, tx.Repository, v, before, comment)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if event != nil {
+		s.events.Publish(ctx, event)
+	}
+	return comment, nil
+}
+
+// DeleteComment removes the user's comment within the edit window.
+func (s *PokerService) DeleteComment(ctx context.Context, userID model.UserID, violationID model.ViolationID, commentID model.CommentID) error {
+
+	before, err := s.ownComment(ctx, userID, violationID, commentID)
+	if err != nil {
+		return err
+	}
+
+	return s.repository.Transact(ctx, func(tx storage.Adapters) error {
+
+		if err := tx.Repository.DeleteComment(ctx, commentID); err != nil {
+			return err
+		}
+
+		return s.audit(ctx, tx.Repository, userID, model.AuditCommentDelete, model.AuditEntityComment, commentEntityID(before), before, nil)
+	})
+}
+
+// ModerateComment applies a moderator's action: hiding takes the comment out of
+// the thread and requires a reason, which is shown to the author; showing puts
+// it back and approves it.
+func (s *PokerService) ModerateComment(ctx context.Context, moderatorID model.UserID, violationID model.ViolationID, commentID model.CommentID, action string, reason string) (*model.Comment, error) {
+
+	reason = strings.TrimSpace(reason)
+
+	var hidden bool
+	switch action {
+	case model.CommentActionHide:
+		if reason == "" {
+			return nil, fmt.Errorf("%w: reason is required to hide", model.ErrInvalidParameter)
+		}
+		hidden = true
+	case model.CommentActionShow:
+		reason = ""
+	default:
+		return nil, fmt.Errorf("%w: unknown action %q", model.ErrInvalidParameter, action)
+	}
+
+	before, err := s.getComment(ctx, violationID, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := s.getViolation(ctx, violationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		comment *model.Comment
+		event   *model.CommentAdded
+	)
+	err = s.repository.Transact(ctx, func(tx storage.Adapters) error {
+
+		var err error
+		comment, err = tx.Repository.SetCommentVisibility(ctx, commentID, hidden, reason, moderatorID)
+		if err != nil {
+			return err
+		}
+
+		if err := s.audit(ctx, tx.Repository, moderatorID, model.AuditCommentModerate, model.AuditEntityComment, commentEntityID(comment), before, comment); err != nil {
+			return err
+		}
+
+		event, err = s.commentPublished(ctx, tx.Repository, v, before, comment)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if event != nil {
+		s.events.Publish(ctx, event)
+	}
+	return comment, nil
+}
+
+// ApplyCommentModerationResult turns the score of automated moderation into the
+// moderation status of a comment, like ApplyModerationResult does for reports.
+func (s *PokerService) ApplyCommentModerationResult(ctx context.Context, result *model.ModerationResult) (*model.Comment, error) {
+
+	if result.Score < 0 || result.Score > 1 {
+		return nil, fmt.Errorf("%w: score must be between 0 and 1", model.ErrInvalidParameter)
+	}
+
+	before, err := s.getComment(ctx, result.ViolationID, result.CommentID)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := s.getViolation(ctx, result.ViolationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		comment *model.Comment
+		event   *model.CommentAdded
+	)
+	err = s.repository.Transact(ctx, func(tx storage.Adapters) error {
+
+		var err error
+		comment, err = tx.Repository.ApplyCommentModerationScore(ctx, result.CommentID, s.moderationStatusForScore(result.Score), result.Score, strings.TrimSpace(result.Reason))
+		if err != nil {
+			return err
+		}
+
+		if err := s.audit(ctx, tx.Repository, 0, model.AuditCommentModerate, model.AuditEntityComment, commentEntityID(comment), before, comment); err != nil {
+			return err
+		}
+
+		event, err = s.commentPublished(ctx, tx.Repository, v, before, comment)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if event != nil {
+		s.events.Publish(ctx, event)
+	}
+	return comment, nil
+}
+
+// newCommentStatus is the moderation status of new and edited comments. There is
+// no manual queue for comments, so without automated moderation they are public
+// at once and moderators hide them afterwards if needed.
+func (s *PokerService) newCommentStatus() model.ModerationStatus {
+	if s.moderator == nil {
+		return model.ModerationApproved
+	}
+	return model.ModerationPending
+}
+
+// commentPublished records the consequences of a change of the comment: a
+// pending comment goes to automated moderation and a comment that becomes public
+// for the first time is announced; edits and re-approvals are not. It returns
+// the event to publish after the commit.
+func (s *PokerService) commentPublished(ctx context.Context, repo storage.Repository, v *model.Violation, before, after *model.Comment) (*model.CommentAdded, error) {
+
+	if after.ModerationStatus == model.ModerationPending && !after.Hidden {
+		return nil, s.enqueue(ctx, repo, model.OutboxTopicModerationSubmit,
+			&model.ModerationSubmission{ViolationID: after.ViolationID, CommentID: after.ID})
+	}
+
+	if !after.Public() || (before != nil && before.Public()) {
+		return nil, nil
+	}
+	first, err := repo.MarkCommentPublished(ctx, after.ID)
+	if err != nil || !first {
+		return nil, err
+	}
+
+	event := &model.CommentAdded{EventMeta: model.NewEventMeta(after.Author.ID), Violation: v, CommentID: after.ID}
+	return event, s.enqueueEvent(ctx, repo, event)
+}
+
+// submitCommentForModeration sends a pending comment to automated moderation.
+func (s *PokerService) submitCommentForModeration(ctx context.Context, submission *model.ModerationSubmission) error {
+
+	c, err := s.getComment(ctx, submission.ViolationID, submission.CommentID)
+	if errors.Is(err, model.ErrorNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if c.ModerationStatus != model.ModerationPending || c.Hidden {
+		return nil
+	}
+
+	result, err := s.moderator.Moderate(ctx, &model.ModerationRequest{ViolationID: c.ViolationID, CommentID: c.ID, Text: c.Text})
+	if err != nil || result == nil {
+		return err
+	}
+
+	// A moderator may have decided on the comment meanwhile.
+	_, err = s.ApplyCommentModerationResult(ctx, result)
+	if errors.Is(err, model.ErrConflict) {
+		return nil
+	}
+	return err
+}
+
+func (s *PokerService) getComment(ctx context.Context, violationID model.ViolationID, commentID model.CommentID) (*model.Comment, error) {
+
+	c, err := s.repository.GetComment(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if c.ViolationID != violationID {
+		return nil, fmt.Errorf("%w: comment %d", model.ErrorNotFound, commentID)
+	}
+
+	return c, nil
+}
+
+// ownComment returns the user's comment if it may still be changed.
+func (s *PokerService) ownComment(ctx context.Context, userID model.UserID, violationID model.ViolationID, commentID model.CommentID) (*model.Comment, error) {
+
+	c, err := s.getComment(ctx, violationID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if c.Author.ID != userID {
+		return nil, fmt.Errorf("%w: only the author can change a comment", model.ErrForbidden)
+	}
+	if time.Since(c.CreatedAt) > s.config.Comments.EditWindow {
+		return nil, fmt.Errorf("%w: comments can only be changed within %s of posting", model.ErrForbidden, s.config.Comments.EditWindow)
+	}
+
+	return c, nil
+}
+
+func validateCommentText(text string) (string, error) {
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", fmt.Errorf("%w: text is required", model.ErrInvalidParameter)
+	}
+	if utf8.RuneCountInString(text) > maxCommentLength {
+		return "", fmt.Errorf("%w: text is longer than %d characters", model.ErrInvalidParameter, maxCommentLength)
+	}
+
+	return text, nil
+}
+
+func commentEntityID(c *model.Comment) string {
+	return strconv.FormatInt(int64(c.ID), 10)
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/inzarubin80/Server/internal/model"
+	"github.com/inzarubin80/Server/internal/storage"
+)
+
+func (s *PokerService) ConfirmViolation(ctx context.Context, userID model.UserID, violationID model.ViolationID) (*model.Violation, error) {
+	return s.confirmViolation(ctx, userID, violationID, nil)
+}
+
+// confirmViolation confirms the violation; with base set, only if it has not
+// been updated since.
+func (s *PokerService) confirmViolation(ctx context.Context, userID model.UserID, violationID model.ViolationID, base *time.Time) (*model.Violation, error) {
+
+	v, err := s.getPublicViolation(ctx, violationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if v.UserID == userID {
+		return nil, fmt.Errorf("%w: author cannot confirm own report", model.ErrForbidden)
+	}
+
+	var (
+		event *model.ViolationConfirmed
+		ruled []model.Event
+	)
+	err = s.repository.Transact(ctx, func(tx storage.Adapters) error {
+
+		if err := unchangedSince(ctx, tx.Repository, violationID, base); err != nil {
+			return err
+		}
+
+		var err error
+		v, event, ruled, err = s.confirmTx(ctx, tx.Repository, userID, violationID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(ctx, event)
+	for _, e := range ruled {
+		s.events.Publish(ctx, e)
+	}
+
+	return v, nil
+}
+
+// confirmTx adds the user's confirmation through repo and applies the rules it
+// triggers in the same transaction. It returns the violation in its resulting
+// state, the confirmation event and the events of the rules, to publish once
+// the transaction has committed.
+func (s *PokerService) confirmTx(ctx context.Context, repo storage.Repository, userID model.UserID, violationID model.ViolationID) (*model.Violation, *model.ViolationConfirmed, []model.Event, error) {
+
+	before, err := repo.GetViolation(ctx, violationID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if before.Status == model.ViolationStatusResolved {
+		return nil, nil, nil, fmt.Errorf("%w: violation is already resolved", model.ErrConflict)
+	}
+
+	v, err := repo.AddConfirmation(ctx, violationID, userID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if err := s.audit(ctx, repo, userID, model.AuditViolationConfirm, model.AuditEntityViolation, string(v.ID), before, v); err != nil {
+		return nil, nil, nil, err
+	}
+
+	event := &model.ViolationConfirmed{EventMeta: model.NewEventMeta(userID), Violation: v}
+	if err := s.enqueueEvent(ctx, repo, event); err != nil {
+		return nil, nil, nil, err
+	}
+
+	ruleEvent, err := s.ruleEvent(ctx, repo, model.RuleEventConfirmation, v, userID, 0)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	v, ruled, err := s.applyRules(ctx, repo, ruleEvent)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return v, event, ruled, nil
+}
+
+func (s *PokerService) UnconfirmViolation(ctx context.Context, userID model.UserID, violationID model.ViolationID) (*model.Violation, error) {
+	return s.unconfirmViolation(ctx, userID, violationID, nil)
+}
+
+// unconfirmViolation withdraws the user's confirmation; with base set, only if
+// the violation has not been updated since.
+func (s *PokerService) unconfirmViolation(ctx context.Context, userID model.UserID, violationID model.ViolationID, base *time.Time) (*model.Violation, error) {
+
+	before, err := s.getPublicViolation(ctx, violationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var v *model.Violation
+	err = s.repository.Transact(ctx, func(tx storage.Adapters) error {
+
+		if err := unchangedSince(ctx, tx.Repository, violationID, base); err != nil {
+			return err
+		}
+
+		var err error
+		v, err = tx.Repository.RemoveConfirmation(ctx, violationID, userID)
+		if err != nil {
+			return err
+		}
+
+		return s.audit(ctx, tx.Repository, userID, model.AuditViolationUnconfirm, model.AuditEntityViolation, string(v.ID), before, v)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(ctx, &model.ViolationConfirmed{EventMeta: model.NewEventMeta(userID), Violation: v, Withdrawn: true})
+	return v, nil
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/inzarubin80/Server/internal/model"
+	"github.com/inzarubin80/Server/internal/storage"
+)
+
+// findDuplicates returns the open reports of the draft's type near its location,
+// nearest first. It finds nothing when deduplication is off.
+func (s *PokerService) findDuplicates(ctx context.Context, userID model.UserID, draft *model.ViolationDraft) ([]*model.DuplicateCandidate, error) {
+
+	config := s.config.Dedup
+	if config.Policy == model.DedupOff || draft.IgnoreDuplicates {
+		return nil, nil
+	}
+
+	return s.repository.FindNearbyViolations(ctx, &model.NearbyFilter{
+		Type:     draft.Type,
+		Lat:      draft.Lat,
+		Lng:      draft.Lng,
+		RadiusM:  config.RadiusM,
+		Since:    time.Now().Add(-config.Window),
+		ViewerID: userID,
+		Limit:    config.MaxCandidates,
+	})
+}
+
+// mergeIntoDuplicate records the draft as a confirmation of the existing report
+// and attaches its photos there, in one transaction. The author of the report,
+// or a user who has already confirmed it, cannot vote again, so for them only
+// the photos are added. A non-empty id is the id the client chose for the
+// draft; it is recorded so that a retry replays the merge.
+func (s *PokerService) mergeIntoDuplicate(ctx context.Context, id model.ViolationID, userID model.UserID, v *model.Violation, draft *model.ViolationDraft) (*model.Violation, error) {
+
+	merged := map[string]any{
+		"description": draft.Description,
+		"lat":         draft.Lat,
+		"lng":         draft.Lng,
+		"photos":      draft.PhotoKeys,
+	}
+
+	target := v
+	var published []model.Event
+	err := s.repository.Transact(ctx, func(tx storage.Adapters) error {
+
+		v, published = target, nil
+
+		if id != "" {
+			if err := tx.Repository.AddViolationMerge(ctx, id, userID, v.ID); err != nil {
+				return err
+			}
+		}
+
+		if v.UserID != userID {
+			confirmed, event, ruled, err := s.confirmTx(ctx, tx.Repository, userID, v.ID)
+			switch {
+			case err == nil:
+				v = confirmed
+				published = append(append(published, event), ruled...)
+			case !errors.Is(err, model.ErrConflict):
+				return err
+			}
+		}
+
+		if len(draft.PhotoKeys) > 0 {
+			if err := tx.Repository.AttachPhotos(ctx, v.ID, userID, model.PhotoKindReport, draft.PhotoKeys); err != nil {
+				return err
+			}
+		}
+
+		return s.audit(ctx, tx.Repository, userID, model.AuditViolationMerge, model.AuditEntityViolation, string(v.ID), nil, merged)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range published {
+		s.events.Publish(ctx, e)
+	}
+	if len(draft.PhotoKeys) > 0 {
+		s.events.Publish(ctx, &model.PhotoAdded{EventMeta: model.NewEventMeta(userID), Violation: v, Kind: model.PhotoKindReport, PhotoKeys: draft.PhotoKeys})
+	}
+
+	return v, nil
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+// maxDeviceTokenLength bounds tokens; FCM and APNs tokens are far shorter.
+const maxDeviceTokenLength = 4096
+
+// RegisterDevice subscribes the user's device to push notifications.
+func (s *PokerService) RegisterDevice(ctx context.Context, userID model.UserID, platform string, token string) (*model.Device, error) {
+
+	token = strings.TrimSpace(token)
+	if token == "" || len(token) > maxDeviceTokenLength {
+		return nil, fmt.Errorf("%w: token", model.ErrInvalidParameter)
+	}
+	if !model.ValidPlatform(platform) {
+		return nil, fmt.Errorf("%w: platform must be %s or %s", model.ErrInvalidParameter, model.PlatformAndroid, model.PlatformIOS)
+	}
+
+	return s.repository.UpsertDevice(ctx, userID, platform, token)
+}
+
+// UnregisterDevice stops push notifications to the user's device.
+func (s *PokerService) UnregisterDevice(ctx context.Context, userID model.UserID, token string) error {
+	return s.repository.DeleteUserDevice(ctx, userID, token)
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+// reporterPseudonymLength is the number of hex digits kept of the pseudonym MAC.
+const reporterPseudonymLength = 16
+
+// ExportViolations streams the approved violations matching the filter to fn,
+// newest first, with authors replaced by pseudonyms. Paging parameters are ignored.
+func (s *PokerService) ExportViolations(ctx context.Context, filter *model.ViolationFilter, fn func(*model.ExportedViolation) error) error {
+
+	if err := validateViolationFilter(filter); err != nil {
+		return err
+	}
+
+	query := *filter
+	query.Cursor = nil
+	query.Limit = 0
+	query.ModerationStatus = model.ModerationApproved
+
+	return s.repository.ExportViolations(ctx, &query, func(v *model.Violation) error {
+		return fn(&model.ExportedViolation{
+			ID:                 v.ID,
+			Type:               v.Type,
+			Description:        v.Description,
+			Lat:                v.Lat,
+			Lng:                v.Lng,
+			Status:             v.Status,
+			ConfirmationsCount: v.ConfirmationsCount,
+			CreatedAt:          v.CreatedAt,
+			UpdatedAt:          v.UpdatedAt,
+			Reporter:           s.reporterPseudonym(v.UserID),
+		})
+	})
+}
+
+// reporterPseudonym lets partners group reports by author without learning who
+// the author is. Changing Export.ReporterSecret changes every pseudonym.
+func (s *PokerService) reporterPseudonym(userID model.UserID) string {
+	mac := hmac.New(sha256.New, []byte(s.config.Export.ReporterSecret))
+	mac.Write([]byte(strconv.FormatInt(int64(userID), 10)))
+	return hex.EncodeToString(mac.Sum(nil))[:reporterPseudonymLength]
+}
@@ -5,10 +5,12 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/google/uuid"
 	"github.com/inzarubin80/Server/internal/model"
+	"github.com/inzarubin80/Server/internal/storage"
 )
 
-func (s *PokerService) Login(ctx context.Context, providerKey string, authorizationCode string, codeVerifier string) (*model.AuthData, error) {
+func (s *PokerService) Login(ctx context.Context, providerKey string, authorizationCode string, codeVerifier string, client model.ClientInfo) (*model.AuthData, error) {
 
 	provider, ok := s.providersUserData[providerKey]
 
@@ -29,12 +31,18 @@ func (s *PokerService) Login(ctx context.Context, providerKey string, authorizat
 
 	if userAuthProviders == nil {
 
-		user, err := s.repository.CreateUser(ctx, userProfileFromProvider)
-		if err != nil {
-			return nil, err
-		}
+		// The user and the provider link are created together, so a failure
+		// cannot leave a user nobody can log in as.
+		err = s.repository.Transact(ctx, func(tx storage.Adapters) error {
 
-		userAuthProviders, err = s.repository.AddUserAuthProviders(ctx, userProfileFromProvider, user.ID)
+			user, err := tx.Repository.CreateUser(ctx, userProfileFromProvider)
+			if err != nil {
+				return err
+			}
+
+			userAuthProviders, err = tx.Repository.AddUserAuthProviders(ctx, userProfileFromProvider, user.ID)
+			return err
+		})
 		if err != nil {
 			return nil, err
 		}
@@ -43,12 +51,23 @@ func (s *PokerService) Login(ctx context.Context, providerKey string, authorizat
 
 	userID := userAuthProviders.UserID
 
-	refreshToken, err := s.refreshTokenService.GenerateToken(userID)
+	role, err := s.repository.GetUserRole(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionID := uuid.NewString()
+
+	refreshToken, err := s.refreshTokenService.GenerateToken(userID, "", sessionID)
 	if err != nil {
 		return nil, err
 	}
 
-	accessToken, err := s.accessTokenService.GenerateToken(userID)
+	if _, err := s.repository.CreateSession(ctx, s.newSession(sessionID, userID, refreshToken, client)); err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.accessTokenService.GenerateToken(userID, role, sessionID)
 	if err != nil {
 		return nil, err
 	}
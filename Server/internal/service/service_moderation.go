@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/inzarubin80/Server/internal/model"
+	"github.com/inzarubin80/Server/internal/storage"
+)
+
+const (
+	defaultModerationPageSize = 50
+	maxModerationPageSize     = 200
+)
+
+// ListPendingModeration returns the moderation queue, most suspicious reports first.
+func (s *PokerService) ListPendingModeration(ctx context.Context, limit int) ([]*model.Violation, error) {
+
+	if limit <= 0 {
+		limit = defaultModerationPageSize
+	}
+	if limit > maxModerationPageSize {
+		limit = maxModerationPageSize
+	}
+
+	return s.repository.ListPendingModeration(ctx, limit)
+}
+
+// ModerateViolation applies a moderator's action. Approving publishes the report;
+// rejecting hides it and requires a reason, which is shown to the author.
+func (s *PokerService) ModerateViolation(ctx context.Context, moderatorID model.UserID, violationID model.ViolationID, action string, reason string) (*model.Violation, error) {
+
+	reason = strings.TrimSpace(reason)
+
+	decision := &model.ModerationDecision{
+		ViolationID: violationID,
+		Reason:      reason,
+		ModeratorID: moderatorID,
+	}
+
+	switch action {
+	case model.ModerationActionApprove:
+		decision.Status = model.ModerationApproved
+	case model.ModerationActionReject:
+		if reason == "" {
+			return nil, fmt.Errorf("%w: reason is required to reject", model.ErrInvalidParameter)
+		}
+		decision.Status = model.ModerationRejected
+	default:
+		return nil, fmt.Errorf("%w: unknown action %q", model.ErrInvalidParameter, action)
+	}
+
+	if _, err := s.getViolation(ctx, violationID); err != nil {
+		return nil, err
+	}
+
+	// The previous state is read in the transaction: whether the report was
+	// public decides what the realtime feed is told.
+	var before, v *model.Violation
+	err := s.repository.Transact(ctx, func(tx storage.Adapters) error {
+
+		var err error
+		before, err = tx.Repository.GetViolation(ctx, violationID)
+		if err != nil {
+			return err
+		}
+
+		v, err = tx.Repository.ModerateViolation(ctx, decision)
+		if err != nil {
+			return err
+		}
+
+		return s.audit(ctx, tx.Repository, moderatorID, model.AuditViolationModerate, model.AuditEntityViolation, string(v.ID), model.NewModeratedViolation(before), model.NewModeratedViolation(v))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(ctx, &model.ViolationModerated{EventMeta: model.NewEventMeta(decision.ModeratorID), Violation: v, Decision: decision, PreviousStatus: before.ModerationStatus})
+	return v, nil
+}
+
+// SubmitForModeration is the outbox handler of OutboxTopicModerationSubmit: it
+// sends a new report or comment to automated moderation. Until it succeeds the report waits
+// in the manual queue; reports decided meanwhile are skipped.
+func (s *PokerService) SubmitForModeration(ctx context.Context, payload json.RawMessage) error {
+
+	if s.moderator == nil {
+		return fmt.Errorf("automated moderation is not configured")
+	}
+
+	var submission model.ModerationSubmission
+	if err := json.Unmarshal(payload, &submission); err != nil {
+		return fmt.Errorf("moderation submission: %w", err)
+	}
+	if submission.CommentID != 0 {
+		return s.submitCommentForModeration(ctx, &submission)
+	}
+
+	v, err := s.getViolation(ctx, submission.ViolationID)
+	if errors.Is(err, model.ErrorNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if v.ModerationStatus != model.ModerationPending {
+		return nil
+	}
+
+	req := &model.ModerationRequest{ViolationID: v.ID, Text: v.Description}
+	if len(submission.PhotoKeys) > 0 {
+		photos, err := s.repository.GetPhotosByKeys(ctx, submission.PhotoKeys)
+		if err != nil {
+			return err
+		}
+		// Only stripped copies are served, so the report waits for its photos.
+		// Photos that could not be processed are never shown and are left out.
+		for _, photo := range photos {
+			switch photo.ProcessingStatus {
+			case model.PhotoProcessingPending:
+				return fmt.Errorf("photo %s is still being processed", photo.StorageKey)
+			case model.PhotoProcessingDone:
+				req.PhotoURLs = append(req.PhotoURLs, s.withURL(photo).URL)
+			}
+		}
+	}
+
+	result, err := s.moderator.Moderate(ctx, req)
+	if err != nil || result == nil {
+		return err
+	}
+
+	_, err = s.ApplyModerationResult(ctx, result)
+	return err
+}
+
+// ApplyModerationResult turns the score of automated moderation into a decision
+// using the configured thresholds. Reports already decided by a moderator are
+// left alone.
+func (s *PokerService) ApplyModerationResult(ctx context.Context, result *model.ModerationResult) (*model.Violation, error) {
+
+	if result.Score < 0 || result.Score > 1 {
+		return nil, fmt.Errorf("%w: score must be between 0 and 1", model.ErrInvalidParameter)
+	}
+
+	before, err := s.getViolation(ctx, result.ViolationID)
+	if err != nil {
+		return nil, err
+	}
+
+	decision := &model.ModerationDecision{
+		ViolationID: result.ViolationID,
+		Status:      s.moderationStatusForScore(result.Score),
+		Reason:      strings.TrimSpace(result.Reason),
+	}
+
+	var v *model.Violation
+	err = s.repository.Transact(ctx, func(tx storage.Adapters) error {
+
+		var err error
+		v, err = tx.Repository.ApplyModerationScore(ctx, decision, result.Score)
+		if err != nil {
+			return err
+		}
+
+		return s.audit(ctx, tx.Repository, 0, model.AuditViolationModerate, model.AuditEntityViolation, string(v.ID), model.NewModeratedViolation(before), model.NewModeratedViolation(v))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if decision.Status != model.ModerationPending {
+		s.events.Publish(ctx, &model.ViolationModerated{EventMeta: model.NewEventMeta(0), Violation: v, Decision: decision, PreviousStatus: before.ModerationStatus})
+	}
+
+	return v, nil
+}
+
+func (s *PokerService) moderationStatusForScore(score float64) model.ModerationStatus {
+
+	switch {
+	case score < s.config.Moderation.AutoApproveBelow:
+		return model.ModerationApproved
+	case score >= s.config.Moderation.RejectFrom:
+		return model.ModerationRejected
+	default:
+		return model.ModerationPending
+	}
+}
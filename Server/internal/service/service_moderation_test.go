@@ -0,0 +1,248 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	appHttp "github.com/inzarubin80/Server/internal/app/http"
+	"github.com/inzarubin80/Server/internal/model"
+	"github.com/inzarubin80/Server/internal/moderation"
+	"github.com/inzarubin80/Server/internal/storage"
+)
+
+const (
+	spamID  model.ViolationID = "0b6c2a52-6f5e-4a4c-9d0e-1f2a3b4c5d01"
+	cleanID model.ViolationID = "0b6c2a52-6f5e-4a4c-9d0e-1f2a3b4c5d02"
+)
+
+var testModerationConfig = ModerationConfig{
+	InitialStatus:    model.ModerationPending,
+	AutoApproveBelow: 0.3,
+	RejectFrom:       0.7,
+}
+
+func TestModerationStatusForScore(t *testing.T) {
+
+	tests := []struct {
+		score float64
+		want  model.ModerationStatus
+	}{
+		{score: 0, want: model.ModerationApproved},
+		{score: 0.29, want: model.ModerationApproved},
+		{score: 0.3, want: model.ModerationPending},
+		{score: 0.5, want: model.ModerationPending},
+		{score: 0.69, want: model.ModerationPending},
+		{score: 0.7, want: model.ModerationRejected},
+		{score: 1, want: model.ModerationRejected},
+	}
+
+	s := &PokerService{config: Config{Moderation: testModerationConfig}}
+
+	for _, tt := range tests {
+		if got := s.moderationStatusForScore(tt.score); got != tt.want {
+			t.Errorf("moderationStatusForScore(%g) = %s, want %s", tt.score, got, tt.want)
+		}
+	}
+}
+
+func TestModerationPipeline(t *testing.T) {
+
+	t.Run("synchronous result", func(t *testing.T) {
+
+		repo := newModerationRepo(&model.Violation{ID: spamID, Description: "cheap casino chips", ModerationStatus: model.ModerationPending})
+		events := &recordingPublisher{}
+		s := &PokerService{
+			repository: repo,
+			events:     events,
+			moderator:  moderation.NewFakeModerator(moderation.DefaultFakeRules),
+			config:     Config{Moderation: testModerationConfig},
+		}
+
+		if err := s.SubmitForModeration(context.Background(), submission(t, spamID)); err != nil {
+			t.Fatalf("SubmitForModeration: %v", err)
+		}
+
+		v := repo.violation(spamID)
+		if v.ModerationStatus != model.ModerationRejected || v.ModerationReason != "matched: spam" {
+			t.Errorf("violation is %s (%q), want rejected as spam", v.ModerationStatus, v.ModerationReason)
+		}
+		if len(repo.audits) != 1 || repo.audits[0].Action != model.AuditViolationModerate {
+			t.Errorf("audit entries = %v, want one %s", repo.audits, model.AuditViolationModerate)
+		}
+
+		moderated, ok := events.last().(*model.ViolationModerated)
+		if !ok || moderated.PreviousStatus != model.ModerationPending {
+			t.Errorf("published %#v, want ViolationModerated from pending", events.last())
+		}
+	})
+
+	t.Run("result through callback", func(t *testing.T) {
+
+		const secret = "moderation-secret"
+
+		repo := newModerationRepo(&model.Violation{ID: cleanID, Description: "tyres dumped in the forest", ModerationStatus: model.ModerationPending})
+		s := &PokerService{
+			repository: repo,
+			events:     &recordingPublisher{},
+			config:     Config{Moderation: testModerationConfig},
+		}
+
+		callback := httptest.NewServer(appHttp.NewModerationResultHandler(s, "moderation_result", secret))
+		defer callback.Close()
+
+		// The moderation service accepts the submission and posts its verdict to
+		// the callback afterwards.
+		scorer := moderation.NewFakeModerator(moderation.DefaultFakeRules)
+		called := make(chan int, 1)
+		service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			var req struct {
+				ViolationID model.ViolationID `json:"violation_id"`
+				Text        string            `json:"text"`
+				CallbackURL string            `json:"callback_url"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || r.Header.Get(moderation.SecretHeader) != secret {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+
+			go func() {
+				result, _ := scorer.Moderate(context.Background(), &model.ModerationRequest{ViolationID: req.ViolationID, Text: req.Text})
+				body, _ := json.Marshal(result)
+
+				cbReq, _ := http.NewRequest(http.MethodPost, req.CallbackURL, bytes.NewReader(body))
+				cbReq.Header.Set(moderation.SecretHeader, secret)
+				resp, err := http.DefaultClient.Do(cbReq)
+				if err != nil {
+					called <- 0
+					return
+				}
+				resp.Body.Close()
+				called <- resp.StatusCode
+			}()
+		}))
+		defer service.Close()
+
+		moderator, err := moderation.NewHTTPModerator(moderation.HTTPConfig{URL: service.URL, CallbackURL: callback.URL, Secret: secret})
+		if err != nil {
+			t.Fatalf("NewHTTPModerator: %v", err)
+		}
+		s.moderator = moderator
+
+		if err := s.SubmitForModeration(context.Background(), submission(t, cleanID)); err != nil {
+			t.Fatalf("SubmitForModeration: %v", err)
+		}
+
+		select {
+		case status := <-called:
+			if status != http.StatusOK {
+				t.Fatalf("callback answered %d, want 200", status)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("the moderation service never called back")
+		}
+
+		if v := repo.violation(cleanID); v.ModerationStatus != model.ModerationApproved {
+			t.Errorf("violation is %s after the callback, want approved", v.ModerationStatus)
+		}
+	})
+}
+
+func submission(t *testing.T, id model.ViolationID) json.RawMessage {
+	payload, err := json.Marshal(&model.ModerationSubmission{ViolationID: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload
+}
+
+// moderationRepo keeps violations in memory. Methods the moderation pipeline
+// does not use panic through the nil embedded Repository.
+type moderationRepo struct {
+	Repository
+
+	mu         sync.Mutex
+	violations map[model.ViolationID]*model.Violation
+	audits     []*model.AuditEntry
+}
+
+func newModerationRepo(violations ...*model.Violation) *moderationRepo {
+	r := &moderationRepo{violations: make(map[model.ViolationID]*model.Violation)}
+	for _, v := range violations {
+		r.violations[v.ID] = v
+	}
+	return r
+}
+
+func (r *moderationRepo) violation(id model.ViolationID) model.Violation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return *r.violations[id]
+}
+
+func (r *moderationRepo) Transact(ctx context.Context, txFunc func(adapters storage.Adapters) error) error {
+	return txFunc(storage.Adapters{Repository: r})
+}
+
+func (r *moderationRepo) GetViolation(ctx context.Context, id model.ViolationID) (*model.Violation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	v, ok := r.violations[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: violation %s", model.ErrorNotFound, id)
+	}
+	res := *v
+	return &res, nil
+}
+
+func (r *moderationRepo) ApplyModerationScore(ctx context.Context, decision *model.ModerationDecision, score float64) (*model.Violation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	v := r.violations[decision.ViolationID]
+	if v == nil || v.ModerationStatus != model.ModerationPending {
+		return nil, fmt.Errorf("%w: violation is not pending moderation", model.ErrConflict)
+	}
+	v.ModerationScore = &score
+	v.ModerationStatus = decision.Status
+	v.ModerationReason = decision.Reason
+
+	res := *v
+	return &res, nil
+}
+
+func (r *moderationRepo) AddAuditLog(ctx context.Context, entry *model.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.audits = append(r.audits, entry)
+	return nil
+}
+
+type recordingPublisher struct {
+	mu     sync.Mutex
+	events []model.Event
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event model.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+}
+
+func (p *recordingPublisher) last() model.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.events) == 0 {
+		return nil
+	}
+	return p.events[len(p.events)-1]
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+const (
+	defaultNotificationPageSize = 50
+	maxNotificationPageSize     = 200
+)
+
+// inboxNotificationFor returns the type and payload of the inbox notification
+// about the event, if it warrants one.
+func inboxNotificationFor(event model.Event) (string, map[string]any, bool) {
+
+	switch e := event.(type) {
+	case *model.ViolationStatusChanged:
+		if e.Change.Event != model.StatusEventChange || e.Change.FromStatus == e.Change.ToStatus {
+			return "", nil, false
+		}
+		return model.NotificationStatusChanged, map[string]any{
+			"from_status": e.Change.FromStatus,
+			"to_status":   e.Change.ToStatus,
+			"reason":      e.Change.Reason,
+		}, true
+
+	case *model.CommentAdded:
+		return model.NotificationCommentAdded, map[string]any{
+			"comment_id": e.CommentID,
+		}, true
+
+	case *model.RuleNotified:
+		return model.NotificationRuleNotice, map[string]any{
+			"rule":    e.Notice.Rule,
+			"message": e.Notice.Message,
+		}, true
+	}
+
+	return "", nil, false
+}
+
+// HandleNotificationEvent adds notifications about the event to the inboxes of
+// the author of the violation and the users who confirmed it, except the user
+// who caused the event, and delivers them to those who are connected. Notices
+// of rules go to the confirmers only if the rule asks for it. Redelivering the
+// event adds nothing to inboxes that already have it, so it serves both as the
+// event bus subscriber, which delivers right after the change, and as the
+// outbox handler, which guarantees delivery. Live delivery is best effort: the
+// inbox is the durable copy, so its failures are only logged.
+func (s *PokerService) HandleNotificationEvent(ctx context.Context, event model.Event) error {
+
+	notificationType, payload, ok := inboxNotificationFor(event)
+	if !ok {
+		return nil
+	}
+
+	withConfirmers := true
+	if e, ok := event.(*model.RuleNotified); ok {
+		withConfirmers = e.Notice.ToConfirmers
+	}
+
+	v := event.Subject()
+	recipients, err := s.interestedUsers(ctx, v, withConfirmers, event.Actor())
+	if err != nil || len(recipients) == 0 {
+		return err
+	}
+
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	notifications := make([]*model.Notification, len(recipients))
+	for i, userID := range recipients {
+		notifications[i] = &model.Notification{
+			UserID:      userID,
+			Type:        notificationType,
+			ViolationID: v.ID,
+			Payload:     encoded,
+			EventID:     event.EventID(),
+		}
+	}
+
+	stored, err := s.repository.AddNotifications(ctx, notifications)
+	if err != nil {
+		return err
+	}
+
+	if s.notifier == nil {
+		return nil
+	}
+	for _, n := range stored {
+		unread, err := s.repository.CountUnreadNotifications(ctx, n.UserID)
+		if err != nil {
+			log.Printf("notifications: count unread of user %d: %v", n.UserID, err)
+			continue
+		}
+		s.notifier.NotifyUser(n.UserID, n, unread)
+	}
+
+	return nil
+}
+
+// ListNotifications returns the user's inbox, newest first, with the number of
+// unread notifications for the badge. cursor is the next_cursor of the previous page.
+func (s *PokerService) ListNotifications(ctx context.Context, userID model.UserID, unreadOnly bool, cursor string, limit int) (*model.NotificationPage, error) {
+
+	filter := &model.NotificationFilter{UserID: userID, UnreadOnly: unreadOnly}
+
+	if cursor != "" {
+		id, err := strconv.ParseInt(cursor, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("%w: cursor", model.ErrInvalidParameter)
+		}
+		filter.BeforeID = model.NotificationID(id)
+	}
+
+	pageSize := limit
+	if pageSize <= 0 {
+		pageSize = defaultNotificationPageSize
+	}
+	if pageSize > maxNotificationPageSize {
+		pageSize = maxNotificationPageSize
+	}
+	filter.Limit = pageSize + 1
+
+	items, err := s.repository.ListNotifications(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	unread, err := s.repository.CountUnreadNotifications(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &model.NotificationPage{Items: items, UnreadCount: unread}
+	if len(items) > pageSize {
+		page.Items = items[:pageSize]
+		page.NextCursor = strconv.FormatInt(int64(page.Items[pageSize-1].ID), 10)
+	}
+
+	return page, nil
+}
+
+func (s *PokerService) UnreadNotificationsCount(ctx context.Context, userID model.UserID) (int, error) {
+	return s.repository.CountUnreadNotifications(ctx, userID)
+}
+
+// MarkNotificationRead marks one notification as read and returns the new unread count.
+func (s *PokerService) MarkNotificationRead(ctx context.Context, userID model.UserID, id model.NotificationID) (int, error) {
+
+	if err := s.repository.MarkNotificationRead(ctx, userID, id); err != nil {
+		return 0, err
+	}
+	return s.repository.CountUnreadNotifications(ctx, userID)
+}
+
+// MarkAllNotificationsRead empties the user's unread badge and returns how many
+// notifications were marked.
+func (s *PokerService) MarkAllNotificationsRead(ctx context.Context, userID model.UserID) (int, error) {
+	return s.repository.MarkAllNotificationsRead(ctx, userID)
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/inzarubin80/Server/internal/model"
+	"github.com/inzarubin80/Server/internal/storage"
+)
+
+const (
+	defaultOutboxPageSize = 100
+	maxOutboxPageSize     = 500
+)
+
+// enqueue records a side effect in the outbox. It is written through repo so
+// that it commits or rolls back together with the change that causes it.
+func (s *PokerService) enqueue(ctx context.Context, repo storage.Repository, topic string, payloads ...any) error {
+
+	messages := make([]*model.OutboxMessage, len(payloads))
+	for i, payload := range payloads {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("outbox %s: %w", topic, err)
+		}
+		messages[i] = &model.OutboxMessage{Topic: topic, Payload: encoded}
+	}
+
+	return repo.EnqueueOutbox(ctx, messages)
+}
+
+// enqueueEvent records the durable consequences of a domain event: inbox and
+// push notifications. The realtime feed is not durable and stays with Publish.
+func (s *PokerService) enqueueEvent(ctx context.Context, repo storage.Repository, event model.Event) error {
+
+	record := eventRecord(event)
+
+	if _, _, ok := inboxNotificationFor(event); ok {
+		if err := s.enqueue(ctx, repo, model.OutboxTopicInboxEvent, record); err != nil {
+			return err
+		}
+	}
+	if _, ok := pushNotificationFor(event); ok && s.push != nil {
+		if err := s.enqueue(ctx, repo, model.OutboxTopicPushEvent, record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func eventRecord(event model.Event) *model.EventRecord {
+
+	v := event.Subject()
+	record := &model.EventRecord{
+		ID:                 event.EventID(),
+		Type:               event.EventType(),
+		ViolationID:        v.ID,
+		ActorID:            event.Actor(),
+		Status:             v.Status,
+		ConfirmationsCount: v.ConfirmationsCount,
+	}
+
+	switch e := event.(type) {
+	case *model.ViolationConfirmed:
+		record.OccurredAt = e.OccurredAt
+		record.Withdrawn = e.Withdrawn
+	case *model.ViolationStatusChanged:
+		record.OccurredAt = e.OccurredAt
+		record.Change = e.Change
+	case *model.CommentAdded:
+		record.OccurredAt = e.OccurredAt
+		record.CommentID = e.CommentID
+	case *model.RuleNotified:
+		record.OccurredAt = e.OccurredAt
+		record.Notice = e.Notice
+	}
+
+	return record
+}
+
+// eventFromRecord rebuilds a recorded event around the current state of its
+// violation, with the status and the confirmation count it had right after the
+// event. It returns nil when the violation no longer exists.
+func (s *PokerService) eventFromRecord(ctx context.Context, payload json.RawMessage) (model.Event, error) {
+
+	var record model.EventRecord
+	if err := json.Unmarshal(payload, &record); err != nil {
+		return nil, fmt.Errorf("event record: %w", err)
+	}
+
+	v, err := s.getViolation(ctx, record.ViolationID)
+	if errors.Is(err, model.ErrorNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if record.Status != "" {
+		v.Status = record.Status
+		v.ConfirmationsCount = record.ConfirmationsCount
+	}
+
+	meta := model.EventMeta{ID: record.ID, ActorID: record.ActorID, OccurredAt: record.OccurredAt}
+
+	switch record.Type {
+	case model.EventViolationConfirmed:
+		return &model.ViolationConfirmed{EventMeta: meta, Violation: v, Withdrawn: record.Withdrawn}, nil
+	case model.EventViolationStatusChanged:
+		if record.Change == nil {
+			return nil, fmt.Errorf("event record of %s has no change", record.Type)
+		}
+		return &model.ViolationStatusChanged{EventMeta: meta, Violation: v, Change: record.Change}, nil
+	case model.EventCommentAdded:
+		return &model.CommentAdded{EventMeta: meta, Violation: v, CommentID: record.CommentID}, nil
+	case model.EventRuleNotified:
+		if record.Notice == nil {
+			return nil, fmt.Errorf("event record of %s has no notice", record.Type)
+		}
+		return &model.RuleNotified{EventMeta: meta, Violation: v, Notice: record.Notice}, nil
+	}
+
+	return nil, fmt.Errorf("event record of unsupported type %q", record.Type)
+}
+
+// DeliverInboxEvent is the outbox handler of OutboxTopicInboxEvent.
+func (s *PokerService) DeliverInboxEvent(ctx context.Context, payload json.RawMessage) error {
+
+	event, err := s.eventFromRecord(ctx, payload)
+	if err != nil || event == nil {
+		return err
+	}
+	return s.HandleNotificationEvent(ctx, event)
+}
+
+// DeliverPushEvent is the outbox handler of OutboxTopicPushEvent.
+func (s *PokerService) DeliverPushEvent(ctx context.Context, payload json.RawMessage) error {
+
+	event, err := s.eventFromRecord(ctx, payload)
+	if err != nil || event == nil {
+		return err
+	}
+	return s.HandlePushEvent(ctx, event)
+}
+
+// ListOutboxMessages returns outbox messages for admins, newest first. cursor is
+// the next_cursor of the previous page.
+func (s *PokerService) ListOutboxMessages(ctx context.Context, filter *model.OutboxFilter, cursor string) (*model.OutboxPage, error) {
+
+	if filter.Status != "" && !model.ValidOutboxStatus(filter.Status) {
+		return nil, fmt.Errorf("%w: unknown status %q", model.ErrInvalidParameter, filter.Status)
+	}
+
+	query := *filter
+
+	if cursor != "" {
+		id, err := strconv.ParseInt(cursor, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("%w: cursor", model.ErrInvalidParameter)
+		}
+		query.BeforeID = id
+	}
+
+	pageSize := filter.Limit
+	if pageSize <= 0 {
+		pageSize = defaultOutboxPageSize
+	}
+	if pageSize > maxOutboxPageSize {
+		pageSize = maxOutboxPageSize
+	}
+	query.Limit = pageSize + 1
+
+	items, err := s.repository.ListOutbox(ctx, &query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &model.OutboxPage{Items: items}
+	if len(items) > pageSize {
+		page.Items = items[:pageSize]
+		page.NextCursor = strconv.FormatInt(page.Items[pageSize-1].ID, 10)
+	}
+
+	return page, nil
+}
+
+// ReplayOutboxMessage returns a dead-lettered message to the queue.
+func (s *PokerService) ReplayOutboxMessage(ctx context.Context, actorID model.UserID, id int64) (*model.OutboxMessage, error) {
+
+	var msg *model.OutboxMessage
+	err := s.repository.Transact(ctx, func(tx storage.Adapters) error {
+
+		var err error
+		msg, err = tx.Repository.ReplayOutbox(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return s.audit(ctx, tx.Repository, actorID, model.AuditOutboxReplay, model.AuditEntityOutbox, strconv.FormatInt(id, 10),
+			map[string]any{"status": model.OutboxStatusDead}, map[string]any{"status": msg.Status})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("outbox: %s message %d replayed by user %d", msg.Topic, msg.ID, actorID)
+	return msg, nil
+}
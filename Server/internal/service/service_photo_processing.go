@@ -0,0 +1,129 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/inzarubin80/Server/internal/imageproc"
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+// pendingPhotosBatch bounds how many pending photos are re-queued on start.
+const pendingPhotosBatch = 500
+
+// ProcessPhoto stores a metadata-free copy of the uploaded original together with
+// the thumbnail and display variants, and deletes the upload. It is run by the
+// image worker pool.
+//
+// The copy goes under a key of its own: the presigned upload URL stays valid
+// until it expires, so whatever is at the uploaded key is never served.
+func (s *PokerService) ProcessPhoto(ctx context.Context, storageKey string) error {
+
+	photo, err := s.repository.GetPhotoByKey(ctx, storageKey)
+	if err != nil {
+		return err
+	}
+	if photo.Status != model.PhotoStatusUploaded || photo.ProcessingStatus == model.PhotoProcessingDone {
+		return nil
+	}
+
+	body, err := s.objectStorage.Get(ctx, storageKey)
+	if err != nil {
+		return err
+	}
+	result, err := imageproc.Process(body, s.config.Image)
+	body.Close()
+	if err != nil {
+		return err
+	}
+
+	variants := make(map[string]*model.PhotoVariant, len(result.Variants))
+	for name, img := range result.Variants {
+		key := variantKey(storageKey, name)
+		if err := s.objectStorage.Put(ctx, key, img.Mime, bytes.NewReader(img.Data), int64(len(img.Data))); err != nil {
+			return fmt.Errorf("store %s variant: %w", name, err)
+		}
+		variants[name] = &model.PhotoVariant{
+			StorageKey: key,
+			Mime:       img.Mime,
+			Width:      img.Width,
+			Height:     img.Height,
+			Size:       int64(len(img.Data)),
+		}
+	}
+
+	original := result.Original
+	key := strippedKey(storageKey, original.Mime)
+	if err := s.objectStorage.Put(ctx, key, original.Mime, bytes.NewReader(original.Data), int64(len(original.Data))); err != nil {
+		return fmt.Errorf("store stripped original: %w", err)
+	}
+	variants[imageproc.VariantOriginal] = &model.PhotoVariant{
+		StorageKey: key,
+		Mime:       original.Mime,
+		Width:      original.Width,
+		Height:     original.Height,
+		Size:       int64(len(original.Data)),
+	}
+
+	if err := s.repository.SetPhotoProcessed(ctx, storageKey, original.Mime, int64(len(original.Data)), variants); err != nil {
+		return err
+	}
+
+	if err := s.objectStorage.Delete(ctx, storageKey); err != nil {
+		log.Printf("imageproc: delete upload %s: %v", storageKey, err)
+	}
+	return nil
+}
+
+// FailPhotoProcessing is called by the worker pool once a photo has run out of
+// retries. The photo is never shown, so the upload, which still carries its
+// metadata, is deleted.
+func (s *PokerService) FailPhotoProcessing(ctx context.Context, storageKey string, err error) {
+
+	if err := s.repository.SetPhotoProcessingFailed(ctx, storageKey, err.Error()); err != nil {
+		log.Printf("imageproc: mark %s failed: %v", storageKey, err)
+		return
+	}
+
+	if err := s.objectStorage.Delete(ctx, storageKey); err != nil {
+		log.Printf("imageproc: delete upload %s: %v", storageKey, err)
+	}
+}
+
+// RequeuePendingPhotos schedules photos whose processing was interrupted by a
+// restart or that did not fit into the queue.
+func (s *PokerService) RequeuePendingPhotos(ctx context.Context) error {
+
+	photos, err := s.repository.ListPhotosPendingProcessing(ctx, pendingPhotosBatch)
+	if err != nil {
+		return err
+	}
+
+	for _, photo := range photos {
+		s.imageQueue.Enqueue(photo.StorageKey)
+	}
+	return nil
+}
+
+// strippedKey derives the storage key of the stripped original: uploads/1/id.jpg -> photos/1/id_original.jpg.
+func strippedKey(storageKey, mime string) string {
+	ext := ".jpg"
+	if mime == "image/png" {
+		ext = ".png"
+	}
+	return processedKey(storageKey, imageproc.VariantOriginal, ext)
+}
+
+// variantKey derives the storage key of a variant: uploads/1/id.png -> photos/1/id_thumb.jpg.
+func variantKey(storageKey, variant string) string {
+	return processedKey(storageKey, variant, ".jpg")
+}
+
+func processedKey(storageKey, variant, ext string) string {
+	base := strings.TrimPrefix(strings.TrimSuffix(storageKey, path.Ext(storageKey)), model.UploadKeyPrefix)
+	return model.PhotoKeyPrefix + base + "_" + variant + ext
+}
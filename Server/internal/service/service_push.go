@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+// pushNotification is what a push says about an event.
+type pushNotification struct {
+	title string
+	body  string
+	// toConfirmers extends the audience from the author to everyone who
+	// confirmed the report.
+	toConfirmers bool
+}
+
+// pushNotificationFor returns the notification of the event, if it warrants one.
+func pushNotificationFor(event model.Event) (*pushNotification, bool) {
+
+	switch e := event.(type) {
+	case *model.ViolationConfirmed:
+		if e.Withdrawn {
+			return nil, false
+		}
+		return &pushNotification{
+			title: "Your report was confirmed",
+			body:  fmt.Sprintf("Confirmations: %d", e.Violation.ConfirmationsCount),
+		}, true
+
+	case *model.ViolationStatusChanged:
+		if e.Change.Event == model.StatusEventProposeResolve {
+			return &pushNotification{
+				title: "Resolution proposed",
+				body:  "Someone reports that the problem has been fixed",
+			}, true
+		}
+		return &pushNotification{
+			title:        "Report status changed",
+			body:         fmt.Sprintf("The report is now %s", e.Change.ToStatus),
+			toConfirmers: true,
+		}, true
+
+	case *model.CommentAdded:
+		return &pushNotification{
+			title: "New comment on your report",
+			body:  "Open the report to read it",
+		}, true
+
+	case *model.RuleNotified:
+		return &pushNotification{
+			title:        "Update on a report",
+			body:         e.Notice.Message,
+			toConfirmers: e.Notice.ToConfirmers,
+		}, true
+	}
+
+	return nil, false
+}
+
+// HandlePushEvent queues push notifications about the event for the devices of
+// the interested users. The user who caused the event is not notified.
+func (s *PokerService) HandlePushEvent(ctx context.Context, event model.Event) error {
+
+	if s.push == nil {
+		return nil
+	}
+
+	n, ok := pushNotificationFor(event)
+	if !ok {
+		return nil
+	}
+
+	v := event.Subject()
+	recipients, err := s.interestedUsers(ctx, v, n.toConfirmers, event.Actor())
+	if err != nil || len(recipients) == 0 {
+		return err
+	}
+
+	devices, err := s.repository.ListUsersDevices(ctx, recipients)
+	if err != nil {
+		return err
+	}
+
+	var messages []any
+	for _, d := range devices {
+		if !s.push.Supports(d.Platform) {
+			continue
+		}
+		messages = append(messages, &model.PushMessage{
+			UserID:   d.UserID,
+			Platform: d.Platform,
+			Token:    d.Token,
+			Title:    n.title,
+			Body:     n.body,
+			Data: map[string]string{
+				"event":        string(event.EventType()),
+				"violation_id": string(v.ID),
+			},
+		})
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+
+	// Each device gets its own message so that a failing device is retried alone.
+	return s.enqueue(ctx, s.repository, model.OutboxTopicPushSend, messages...)
+}
+
+// interestedUsers returns the author of the violation and, if asked, the users
+// who confirmed it, without the actor.
+func (s *PokerService) interestedUsers(ctx context.Context, v *model.Violation, withConfirmers bool, actorID model.UserID) ([]model.UserID, error) {
+
+	var users []model.UserID
+	if v.UserID != actorID {
+		users = append(users, v.UserID)
+	}
+
+	if withConfirmers {
+		confirmations, err := s.repository.ListConfirmations(ctx, v.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range confirmations {
+			if c.User.ID != actorID && c.User.ID != v.UserID {
+				users = append(users, c.User.ID)
+			}
+		}
+	}
+
+	return users, nil
+}
+
+// SendPush is the outbox handler of OutboxTopicPushSend. A device whose token
+// the provider rejects is forgotten and the message is dropped.
+func (s *PokerService) SendPush(ctx context.Context, payload json.RawMessage) error {
+
+	if s.push == nil {
+		return fmt.Errorf("push is not configured")
+	}
+
+	var msg model.PushMessage
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		return fmt.Errorf("push message: %w", err)
+	}
+
+	err := s.push.Send(ctx, &msg)
+	if errors.Is(err, model.ErrInvalidPushToken) {
+		log.Printf("push: dropping device of user %d: %v", msg.UserID, err)
+		return s.repository.DeleteDeviceByToken(ctx, msg.Token)
+	}
+
+	return err
+}
@@ -2,23 +2,48 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/inzarubin80/Server/internal/model"
 )
 
-func (s *PokerService) RefreshToken(ctx context.Context, refreshToken string) (*model.AuthData, error) {
+// RefreshToken rotates the refresh token: the presented token is marked used and
+// a new one is issued in the same session family. A token that has already been
+// used means it was copied, so the whole family is revoked.
+func (s *PokerService) RefreshToken(ctx context.Context, refreshToken string, client model.ClientInfo) (*model.AuthData, error) {
 
 	claims, err := s.refreshTokenService.ValidateToken(refreshToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", model.ErrUnauthorized, err)
+	}
+
+	newRefreshToken, err := s.refreshTokenService.GenerateToken(claims.UserID, "", claims.SessionID)
 	if err != nil {
 		return nil, err
 	}
 
-	newAccessToken, err := s.accessTokenService.GenerateToken(claims.UserID)
+	tokenHash := hashToken(refreshToken)
+	// The family is taken from the rotated row; FamilyID here is ignored.
+	if _, err := s.repository.RotateSession(ctx, tokenHash, s.newSession("", claims.UserID, newRefreshToken, client)); err != nil {
+		if !errors.Is(err, model.ErrorNotFound) {
+			return nil, err
+		}
+		return nil, s.rejectRefreshToken(ctx, tokenHash)
+	}
+
+	// The role is read on every refresh, so role changes reach the access token here.
+	role, err := s.repository.GetUserRole(ctx, claims.UserID)
 	if err != nil {
 		return nil, err
 	}
 
-	newRefreshToken, err := s.refreshTokenService.GenerateToken(claims.UserID)
+	newAccessToken, err := s.accessTokenService.GenerateToken(claims.UserID, role, claims.SessionID)
 	if err != nil {
 		return nil, err
 	}
@@ -30,3 +55,44 @@ func (s *PokerService) RefreshToken(ctx context.Context, refreshToken string) (*
 	}, nil
 
 }
+
+// rejectRefreshToken explains why a token could not be rotated and revokes the
+// family when the token is being reused.
+func (s *PokerService) rejectRefreshToken(ctx context.Context, tokenHash string) error {
+
+	session, err := s.repository.GetSessionByTokenHash(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, model.ErrorNotFound) {
+			return fmt.Errorf("%w: unknown refresh token", model.ErrUnauthorized)
+		}
+		return err
+	}
+
+	if session.RevokedAt == nil && session.UsedAt != nil {
+		log.Printf("auth: refresh token reuse for user %d, revoking session family %s", session.UserID, session.FamilyID)
+		if err := s.repository.RevokeSessionFamily(ctx, session.FamilyID); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: refresh token reuse detected", model.ErrUnauthorized)
+	}
+
+	return fmt.Errorf("%w: refresh token is revoked or expired", model.ErrUnauthorized)
+}
+
+func (s *PokerService) newSession(familyID string, userID model.UserID, refreshToken string, client model.ClientInfo) *model.Session {
+	return &model.Session{
+		ID:        uuid.NewString(),
+		FamilyID:  familyID,
+		UserID:    userID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: time.Now().Add(s.config.RefreshTokenTTL),
+		Client:    client,
+	}
+}
+
+// hashToken returns the value stored instead of the token. Tokens are random
+// enough that a plain SHA-256 is sufficient.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/inzarubin80/Server/internal/model"
+	"github.com/inzarubin80/Server/internal/storage"
+)
+
+// SetUserRole changes the role of a user. The user's access tokens are rejected
+// by Authorization from now on, so clients refresh them and get the new role.
+func (s *PokerService) SetUserRole(ctx context.Context, actorID model.UserID, userID model.UserID, role model.Role) error {
+
+	if !role.Valid() {
+		return fmt.Errorf("%w: unknown role %q", model.ErrInvalidParameter, role)
+	}
+
+	if actorID == userID {
+		return fmt.Errorf("%w: admins cannot change their own role", model.ErrForbidden)
+	}
+
+	return s.repository.Transact(ctx, func(tx storage.Adapters) error {
+
+		before, err := tx.Repository.GetUserRole(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Repository.SetUserRole(ctx, userID, role); err != nil {
+			return err
+		}
+
+		return s.audit(ctx, tx.Repository, actorID, model.AuditUserRoleChange, model.AuditEntityUser, strconv.FormatInt(int64(userID), 10),
+			map[string]any{"role": before}, map[string]any{"role": role})
+	})
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/inzarubin80/Server/internal/model"
+	"github.com/inzarubin80/Server/internal/storage"
+)
+
+// ruleEvent builds the rule event for a change made by actorID. The roles are
+// read through repo, so they are those of the transaction of the change.
+func (s *PokerService) ruleEvent(ctx context.Context, repo storage.Repository, eventType string, v *model.Violation, actorID model.UserID, photoCount int) (*model.RuleEvent, error) {
+
+	actorRole, err := repo.GetUserRole(ctx, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	confirmations, err := repo.ListConfirmations(ctx, v.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	voterRoles := make([]string, len(confirmations))
+	for i, c := range confirmations {
+		voterRoles[i] = string(c.User.Role)
+	}
+
+	return &model.RuleEvent{
+		Type:       eventType,
+		Violation:  v,
+		ActorID:    actorID,
+		ActorRole:  string(actorRole),
+		PhotoCount: photoCount,
+		VoterRoles: voterRoles,
+	}, nil
+}
+
+// applyRules evaluates the rules for the event and executes the actions of the
+// fired rules through repo, in the transaction of the change that triggered
+// them, so the change and its consequences are committed together. It returns
+// the violation in its resulting state and the events to publish once the
+// transaction has committed.
+func (s *PokerService) applyRules(ctx context.Context, repo storage.Repository, event *model.RuleEvent) (*model.Violation, []model.Event, error) {
+
+	v := event.Violation
+
+	firings, err := s.rules.Evaluate(ctx, event)
+	if err != nil {
+		return nil, nil, fmt.Errorf("rules: evaluate %s for violation %s: %w", event.Type, v.ID, err)
+	}
+
+	var published []model.Event
+	for _, firing := range firings {
+		rule := firing.Rule
+
+		var changed *model.ViolationStatusChanged
+		switch rule.Action {
+		case model.RuleActionSetStatus:
+			status, _ := rule.ActionParams["status"].(string)
+			v, changed, err = s.applyRuleStatus(ctx, repo, firing, v, model.ViolationStatus(status))
+
+		case model.RuleActionAutoResolve:
+			v, changed, err = s.applyRuleStatus(ctx, repo, firing, v, model.ViolationStatusResolved)
+
+		case model.RuleActionNotify:
+			err = s.applyRuleNotify(ctx, repo, firing, v)
+
+		default:
+			log.Printf("rules: rule %q has unsupported action %q", rule.Name, rule.Action)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("rules: rule %q on violation %s: %w", rule.Name, v.ID, err)
+		}
+		if changed != nil {
+			published = append(published, changed)
+		}
+	}
+
+	return v, published, nil
+}
+
+// auditRuleFiring records that the rule fired on the violation.
+func (s *PokerService) auditRuleFiring(ctx context.Context, repo storage.Repository, firing *model.RuleFiring, v *model.Violation) error {
+
+	rule := firing.Rule
+	fired := map[string]any{"rule": rule.Name, "action": rule.Action, "score": firing.Score}
+	return s.audit(ctx, repo, 0, model.AuditRuleFire, model.AuditEntityViolation, string(v.ID), nil, fired)
+}
+
+// applyRuleStatus moves the violation to the status of the rule. A transition
+// the state machine does not allow from the current status is skipped, so a
+// misconfigured rule does not fail the change that triggered it.
+func (s *PokerService) applyRuleStatus(ctx context.Context, repo storage.Repository, firing *model.RuleFiring, v *model.Violation, status model.ViolationStatus) (*model.Violation, *model.ViolationStatusChanged, error) {
+
+	rule := firing.Rule
+	if v.Status == status {
+		return v, nil, nil
+	}
+	if !canTransition(v.Status, status) {
+		log.Printf("rules: rule %q skipped: transition %s -> %s is not allowed for violation %s", rule.Name, v.Status, status, v.ID)
+		return v, nil, nil
+	}
+
+	if err := s.auditRuleFiring(ctx, repo, firing, v); err != nil {
+		return nil, nil, err
+	}
+	return s.changeStatusTx(ctx, repo, v, status, 0, "rule: "+rule.Name, nil)
+}
+
+// applyRuleNotify queues the notice of the rule for the inboxes and devices of
+// the users interested in the violation. action_params may set "message" and
+// "confirmers" to also notify the users who confirmed the report.
+func (s *PokerService) applyRuleNotify(ctx context.Context, repo storage.Repository, firing *model.RuleFiring, v *model.Violation) error {
+
+	rule := firing.Rule
+	notice := &model.RuleNotice{Rule: rule.Name}
+	notice.Message, _ = rule.ActionParams["message"].(string)
+	notice.ToConfirmers, _ = rule.ActionParams["confirmers"].(bool)
+	if notice.Message == "" {
+		notice.Message = fmt.Sprintf("Rule %s applies to the report", rule.Name)
+	}
+
+	if err := s.auditRuleFiring(ctx, repo, firing, v); err != nil {
+		return err
+	}
+	return s.enqueueEvent(ctx, repo, &model.RuleNotified{EventMeta: model.NewEventMeta(0), Violation: v, Notice: notice})
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+// Logout revokes the session the refresh token belongs to. Unknown tokens are
+// ignored: the client is logged out either way.
+func (s *PokerService) Logout(ctx context.Context, refreshToken string) error {
+
+	session, err := s.repository.GetSessionByTokenHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, model.ErrorNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	return s.repository.RevokeSessionFamily(ctx, session.FamilyID)
+}
+
+// ListSessions returns the devices the user is logged in on. currentSessionID is
+// the session of the request and is flagged in the result.
+func (s *PokerService) ListSessions(ctx context.Context, userID model.UserID, currentSessionID string) ([]*model.DeviceSession, error) {
+
+	sessions, err := s.repository.ListUserSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, session := range sessions {
+		session.Current = session.ID == currentSessionID
+	}
+
+	return sessions, nil
+}
+
+func (s *PokerService) RevokeSession(ctx context.Context, userID model.UserID, sessionID string) error {
+
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return fmt.Errorf("%w: invalid session id", model.ErrInvalidParameter)
+	}
+
+	return s.repository.RevokeUserSessionFamily(ctx, userID, sessionID)
+}
+
+// RevokeAllSessions logs the user out on every device, including the current one.
+func (s *PokerService) RevokeAllSessions(ctx context.Context, userID model.UserID) error {
+	return s.repository.RevokeUserSessions(ctx, userID)
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/inzarubin80/Server/internal/model"
+	"github.com/inzarubin80/Server/internal/storage"
+)
+
+// violationTransitions lists the statuses a violation may move to from each status.
+// A report must be confirmed before it can be resolved. Resolved is terminal.
+var violationTransitions = map[model.ViolationStatus][]model.ViolationStatus{
+	model.ViolationStatusNew:       {model.ViolationStatusConfirmed},
+	model.ViolationStatusConfirmed: {model.ViolationStatusResolved},
+}
+
+func canTransition(from, to model.ViolationStatus) bool {
+	for _, allowed := range violationTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// changeStatus makes a status transition in its own transaction. actorID is
+// zero for transitions made by the system. check, if set, runs first in the
+// same transaction and may reject the change.
+func (s *PokerService) changeStatus(ctx context.Context, v *model.Violation, to model.ViolationStatus, actorID model.UserID, reason string, evidence []string, check func(repo storage.Repository) error) (*model.Violation, error) {
+
+	var (
+		updated *model.Violation
+		event   *model.ViolationStatusChanged
+	)
+	err := s.repository.Transact(ctx, func(tx storage.Adapters) error {
+
+		if check != nil {
+			if err := check(tx.Repository); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		updated, event, err = s.changeStatusTx(ctx, tx.Repository, v, to, actorID, reason, evidence)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(ctx, event)
+	return updated, nil
+}
+
+// changeStatusTx is the single entry point for status transitions. It writes
+// through repo, so the caller decides which transaction the change belongs to,
+// and returns the event to publish once that transaction has committed.
+func (s *PokerService) changeStatusTx(ctx context.Context, repo storage.Repository, v *model.Violation, to model.ViolationStatus, actorID model.UserID, reason string, evidence []string) (*model.Violation, *model.ViolationStatusChanged, error) {
+
+	if !canTransition(v.Status, to) {
+		return nil, nil, fmt.Errorf("%w: transition %s -> %s is not allowed", model.ErrConflict, v.Status, to)
+	}
+
+	change := &model.StatusChange{
+		ViolationID: v.ID,
+		Event:       model.StatusEventChange,
+		FromStatus:  v.Status,
+		ToStatus:    to,
+		ActorID:     actorID,
+		Reason:      reason,
+		Evidence:    evidence,
+	}
+
+	updated, err := repo.ChangeViolationStatus(ctx, change)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.audit(ctx, repo, actorID, model.AuditViolationStatusChange, model.AuditEntityViolation, string(v.ID), v, updated); err != nil {
+		return nil, nil, err
+	}
+
+	event := &model.ViolationStatusChanged{EventMeta: model.NewEventMeta(actorID), Violation: updated, Change: change}
+	if err := s.enqueueEvent(ctx, repo, event); err != nil {
+		return nil, nil, err
+	}
+	return updated, event, nil
+}
+
+// ProposeResolve lets any user suggest that a violation has been fixed. The proposal
+// must carry a comment or at least one photo as evidence; the photos are attached to
+// the violation as evidence.
+func (s *PokerService) ProposeResolve(ctx context.Context, userID model.UserID, violationID model.ViolationID, comment string, photoKeys []string) (*model.Violation, error) {
+	return s.proposeResolve(ctx, userID, violationID, comment, photoKeys, nil)
+}
+
+// proposeResolve records the proposal; with base set, only if the violation has
+// not been updated since.
+func (s *PokerService) proposeResolve(ctx context.Context, userID model.UserID, violationID model.ViolationID, comment string, photoKeys []string, base *time.Time) (*model.Violation, error) {
+
+	comment = strings.TrimSpace(comment)
+	if comment == "" && len(photoKeys) == 0 {
+		return nil, fmt.Errorf("%w: comment or photos are required", model.ErrInvalidParameter)
+	}
+
+	v, err := s.getPublicViolation(ctx, violationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkPhotos(ctx, userID, photoKeys); err != nil {
+		return nil, err
+	}
+
+	if !canTransition(v.Status, model.ViolationStatusResolved) {
+		return nil, fmt.Errorf("%w: violation is already %s", model.ErrConflict, v.Status)
+	}
+
+	change := &model.StatusChange{
+		ViolationID: v.ID,
+		Event:       model.StatusEventProposeResolve,
+		FromStatus:  v.Status,
+		ToStatus:    v.Status,
+		ActorID:     userID,
+		Reason:      comment,
+		Evidence:    photoKeys,
+	}
+
+	before := v
+	var (
+		event *model.ViolationStatusChanged
+		ruled []model.Event
+	)
+	err = s.repository.Transact(ctx, func(tx storage.Adapters) error {
+
+		if err := unchangedSince(ctx, tx.Repository, violationID, base); err != nil {
+			return err
+		}
+
+		var err error
+		v, err = tx.Repository.ProposeResolve(ctx, change)
+		if err != nil {
+			return err
+		}
+
+		if len(photoKeys) > 0 {
+			if err := tx.Repository.AttachPhotos(ctx, v.ID, userID, model.PhotoKindEvidence, photoKeys); err != nil {
+				return err
+			}
+		}
+
+		if err := s.audit(ctx, tx.Repository, userID, model.AuditViolationProposeResolve, model.AuditEntityViolation, string(v.ID), before, v); err != nil {
+			return err
+		}
+
+		event = &model.ViolationStatusChanged{EventMeta: model.NewEventMeta(userID), Violation: v, Change: change}
+		if err := s.enqueueEvent(ctx, tx.Repository, event); err != nil {
+			return err
+		}
+
+		ruleEvent, err := s.ruleEvent(ctx, tx.Repository, model.RuleEventProposeResolve, v, userID, len(photoKeys))
+		if err != nil {
+			return err
+		}
+		v, ruled, err = s.applyRules(ctx, tx.Repository, ruleEvent)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(ctx, event)
+	if len(photoKeys) > 0 {
+		s.events.Publish(ctx, &model.PhotoAdded{EventMeta: model.NewEventMeta(userID), Violation: event.Violation, Kind: model.PhotoKindEvidence, PhotoKeys: photoKeys})
+	}
+	for _, e := range ruled {
+		s.events.Publish(ctx, e)
+	}
+
+	return v, nil
+}
+
+// ResolveViolation closes the violation. Moderators may close it directly; the
+// author of the report may only accept a pending resolve proposal.
+// Only published reports can be resolved.
+func (s *PokerService) ResolveViolation(ctx context.Context, userID model.UserID, violationID model.ViolationID, reason string) (*model.Violation, error) {
+
+	v, err := s.getPublicViolation(ctx, violationID)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := s.repository.GetUserRole(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var proposed func(repo storage.Repository) error
+	if !role.AtLeast(model.RoleModerator) {
+		if v.UserID != userID {
+			return nil, fmt.Errorf("%w: only the author or a moderator can resolve the report", model.ErrForbidden)
+		}
+		proposed = func(repo storage.Repository) error {
+			current, err := repo.GetViolation(ctx, violationID)
+			if err != nil {
+				return err
+			}
+			if current.ResolutionStatus != model.ResolutionStatusProposed {
+				return fmt.Errorf("%w: the report has no pending resolve proposal", model.ErrConflict)
+			}
+			return nil
+		}
+	}
+
+	return s.changeStatus(ctx, v, model.ViolationStatusResolved, userID, strings.TrimSpace(reason), nil, proposed)
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/inzarubin80/Server/internal/model"
+	"github.com/inzarubin80/Server/internal/storage"
+)
+
+const maxSyncOperations = 100
+
+// Sync applies the operations a client queued offline in their order and
+// returns the outcome of each. The server copy is authoritative: an operation
+// made against an older version of a violation than the stored one is not
+// applied and is reported as a conflict together with the server copy. A failed
+// operation does not stop the ones after it.
+func (s *PokerService) Sync(ctx context.Context, viewer model.Viewer, ops []*model.SyncOperation) ([]*model.SyncResult, error) {
+
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("%w: no operations", model.ErrInvalidParameter)
+	}
+	if len(ops) > maxSyncOperations {
+		return nil, fmt.Errorf("%w: at most %d operations per request", model.ErrInvalidParameter, maxSyncOperations)
+	}
+
+	results := make([]*model.SyncResult, 0, len(ops))
+	for _, op := range ops {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		results = append(results, s.syncOperation(ctx, viewer, op))
+	}
+
+	return results, nil
+}
+
+func (s *PokerService) syncOperation(ctx context.Context, viewer model.Viewer, op *model.SyncOperation) *model.SyncResult {
+
+	var apply func() (*model.Violation, error)
+	switch op.Type {
+	case model.SyncCreate:
+		return s.syncCreate(ctx, viewer, op)
+	case model.SyncConfirm:
+		apply = func() (*model.Violation, error) {
+			return s.confirmViolation(ctx, viewer.UserID, op.ViolationID, op.BaseUpdatedAt)
+		}
+	case model.SyncUnconfirm:
+		apply = func() (*model.Violation, error) {
+			return s.unconfirmViolation(ctx, viewer.UserID, op.ViolationID, op.BaseUpdatedAt)
+		}
+	case model.SyncProposeResolve:
+		apply = func() (*model.Violation, error) {
+			return s.proposeResolve(ctx, viewer.UserID, op.ViolationID, op.Comment, op.PhotoKeys, op.BaseUpdatedAt)
+		}
+	default:
+		return s.syncError(ctx, viewer, op, fmt.Errorf("%w: operation type %q", model.ErrInvalidParameter, op.Type))
+	}
+
+	// A retried batch finds its own changes in place; they are reported as
+	// applied rather than as conflicts with themselves.
+	v, done, err := s.syncDone(ctx, viewer.UserID, op)
+	if err != nil {
+		return s.syncError(ctx, viewer, op, err)
+	}
+	if !done {
+		v, err = apply()
+		if err != nil {
+			return s.syncError(ctx, viewer, op, err)
+		}
+	}
+
+	return &model.SyncResult{OperationID: op.ID, Status: model.SyncApplied, Violation: v}
+}
+
+// syncDone reports whether the effect of the operation is already in place: the
+// user has confirmed the violation, has no confirmation to withdraw or has
+// proposed its resolution with the same comment and photos.
+func (s *PokerService) syncDone(ctx context.Context, userID model.UserID, op *model.SyncOperation) (*model.Violation, bool, error) {
+
+	v, err := s.getPublicViolation(ctx, op.ViolationID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch op.Type {
+	case model.SyncConfirm, model.SyncUnconfirm:
+		confirmations, err := s.repository.ListConfirmations(ctx, v.ID)
+		if err != nil {
+			return nil, false, err
+		}
+		confirmed := slices.ContainsFunc(confirmations, func(c *model.Confirmation) bool { return c.User.ID == userID })
+		return v, confirmed == (op.Type == model.SyncConfirm), nil
+
+	case model.SyncProposeResolve:
+		history, err := s.repository.ListStatusHistory(ctx, v.ID)
+		if err != nil {
+			return nil, false, err
+		}
+		comment := strings.TrimSpace(op.Comment)
+		proposed := slices.ContainsFunc(history, func(c *model.StatusChange) bool {
+			return c.Event == model.StatusEventProposeResolve && c.ActorID == userID &&
+				c.Reason == comment && slices.Equal(c.Evidence, op.PhotoKeys)
+		})
+		return v, proposed, nil
+	}
+
+	return v, false, nil
+}
+
+// unchangedSince returns model.ErrConflict if the violation has been updated
+// since base; a nil base always passes. It runs in the transaction of the
+// change: a concurrent update is either seen here or makes the transaction retry.
+func unchangedSince(ctx context.Context, repo storage.Repository, violationID model.ViolationID, base *time.Time) error {
+
+	if base == nil {
+		return nil
+	}
+
+	v, err := repo.GetViolation(ctx, violationID)
+	if err != nil {
+		return err
+	}
+	if v.UpdatedAt.After(*base) {
+		return fmt.Errorf("%w: violation was updated at %s", model.ErrConflict, v.UpdatedAt.Format(time.RFC3339Nano))
+	}
+
+	return nil
+}
+
+// syncCreate submits a report queued offline. The client must have given it an
+// id so that the operation can be retried safely.
+func (s *PokerService) syncCreate(ctx context.Context, viewer model.Viewer, op *model.SyncOperation) *model.SyncResult {
+
+	if op.Draft == nil || op.ViolationID == "" {
+		return s.syncError(ctx, viewer, op, fmt.Errorf("%w: create needs a violation id and a report", model.ErrInvalidParameter))
+	}
+
+	draft := *op.Draft
+	draft.ID = op.ViolationID
+
+	submission, err := s.CreateViolation(ctx, viewer.UserID, &draft)
+	if err != nil {
+		return s.syncError(ctx, viewer, op, err)
+	}
+
+	return &model.SyncResult{OperationID: op.ID, Status: model.SyncApplied, Violation: submission.Violation, Merged: submission.Merged}
+}
+
+// syncError reports an operation that was not applied. Conflicts on existing
+// violations carry the server copy, if the viewer may see it, so that the client
+// can replace its own.
+func (s *PokerService) syncError(ctx context.Context, viewer model.Viewer, op *model.SyncOperation, err error) *model.SyncResult {
+
+	res := &model.SyncResult{OperationID: op.ID, Status: model.SyncFailed, Error: err.Error()}
+
+	var duplicates *model.DuplicatesError
+	if errors.As(err, &duplicates) {
+		res.Duplicates = duplicates.Candidates
+	}
+
+	if !errors.Is(err, model.ErrConflict) {
+		return res
+	}
+	res.Status = model.SyncConflict
+
+	if op.Type == model.SyncCreate {
+		return res
+	}
+
+	v, err := s.getViolation(ctx, op.ViolationID)
+	if err == nil && viewer.CanSee(v) {
+		res.Violation = v
+	}
+
+	return res
+}
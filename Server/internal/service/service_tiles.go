@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/inzarubin80/Server/internal/model"
+	"github.com/inzarubin80/Server/internal/mvt"
+)
+
+const (
+	violationsLayer = "violations"
+	// tileBuffer keeps markers near a tile edge from being clipped by the
+	// renderer: points this many tile units outside the tile are included.
+	tileBuffer = 64
+	// tileEncodingVersion is part of the ETag so that cached tiles are
+	// invalidated when the tile content changes shape.
+	tileEncodingVersion = 1
+)
+
+// ViolationTile returns the approved violations in tile z/x/y as a vector tile
+// with one point layer. The ETag is derived from the number of violations in the
+// tile and their latest updated_at; when it equals ifNoneMatch the tile is not
+// encoded at all.
+func (s *PokerService) ViolationTile(ctx context.Context, z, x, y int, ifNoneMatch string) (*model.VectorTile, error) {
+
+	if !mvt.ValidTile(z, x, y) {
+		return nil, fmt.Errorf("%w: tile %d/%d/%d", model.ErrInvalidParameter, z, x, y)
+	}
+
+	bounds := mvt.TileBounds(z, x, y, mvt.DefaultExtent, tileBuffer)
+	filter := &model.ViolationFilter{
+		BBox:             &model.BBox{MinLng: bounds.MinLng, MinLat: bounds.MinLat, MaxLng: bounds.MaxLng, MaxLat: bounds.MaxLat},
+		ModerationStatus: model.ModerationApproved,
+	}
+
+	stats, err := s.repository.StatViolations(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	tile := &model.VectorTile{ETag: tileETag(stats)}
+	if tile.ETag == ifNoneMatch {
+		tile.NotModified = true
+		return tile, nil
+	}
+	if stats.Count == 0 {
+		return tile, nil
+	}
+
+	filter.Limit = stats.Count
+	if max := s.config.Tiles.MaxFeatures; filter.Limit > max {
+		filter.Limit = max
+	}
+
+	violations, err := s.repository.ListViolations(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	layer := &mvt.Layer{Name: violationsLayer, Extent: mvt.DefaultExtent, Features: make([]mvt.Feature, 0, len(violations))}
+	for _, v := range violations {
+		px, py := mvt.Project(v.Lat, v.Lng, z, x, y, mvt.DefaultExtent)
+		layer.Features = append(layer.Features, mvt.Feature{
+			X: px,
+			Y: py,
+			Properties: map[string]any{
+				"id":            string(v.ID),
+				"type":          string(v.Type),
+				"status":        string(v.Status),
+				"confirmations": v.ConfirmationsCount,
+			},
+		})
+	}
+
+	tile.Data, err = mvt.Encode(layer)
+	if err != nil {
+		return nil, err
+	}
+
+	return tile, nil
+}
+
+func tileETag(stats *model.ViolationStats) string {
+	var updated int64
+	if stats.LastUpdatedAt != nil {
+		updated = stats.LastUpdatedAt.UnixMicro()
+	}
+	return fmt.Sprintf(`"v%d-%d-%x"`, tileEncodingVersion, stats.Count, updated)
+}
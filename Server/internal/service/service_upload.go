@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/inzarubin80/Server/internal/imageproc"
+	"github.com/inzarubin80/Server/internal/model"
+)
+
+// sniffLen is the number of bytes http.DetectContentType looks at.
+const sniffLen = 512
+
+// allowedPhotoTypes maps the accepted MIME types to the extension used in storage keys.
+// Only formats the standard library can decode are accepted, because every photo is
+// re-encoded to strip its metadata.
+var allowedPhotoTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+// InitUpload registers a pending photo and returns a presigned URL the client uploads the bytes to.
+func (s *PokerService) InitUpload(ctx context.Context, userID model.UserID, mime string, size int64) (*model.PresignedUpload, error) {
+
+	ext, ok := allowedPhotoTypes[mime]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported mime type %q", model.ErrInvalidParameter, mime)
+	}
+	if size <= 0 || size > s.config.MaxPhotoSize {
+		return nil, fmt.Errorf("%w: photo size must be between 1 and %d bytes", model.ErrInvalidParameter, s.config.MaxPhotoSize)
+	}
+
+	id := uuid.New().String()
+	photo, err := s.repository.CreatePhoto(ctx, &model.Photo{
+		ID:         id,
+		UserID:     userID,
+		StorageKey: fmt.Sprintf("%s%d/%s%s", model.UploadKeyPrefix, userID, id, ext),
+		Kind:       model.PhotoKindReport,
+		Mime:       mime,
+		Size:       size,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	upload, err := s.objectStorage.PresignPut(ctx, photo.StorageKey, mime, size, s.config.UploadURLTTL)
+	if err != nil {
+		return nil, err
+	}
+	upload.PhotoID = photo.ID
+
+	return upload, nil
+}
+
+// CompleteUpload verifies an uploaded object: the real size must be within limits and
+// the sniffed content must be an allowed image type. Rejected objects are deleted.
+func (s *PokerService) CompleteUpload(ctx context.Context, userID model.UserID, storageKey string) (*model.Photo, error) {
+
+	photo, err := s.repository.GetPhotoByKey(ctx, storageKey)
+	if err != nil {
+		return nil, err
+	}
+	if photo.UserID != userID {
+		return nil, fmt.Errorf("%w: photo %s", model.ErrorNotFound, storageKey)
+	}
+
+	switch photo.Status {
+	case model.PhotoStatusUploaded:
+		return s.withURL(photo), nil
+	case model.PhotoStatusRejected:
+		return nil, fmt.Errorf("%w: photo was rejected", model.ErrConflict)
+	}
+
+	info, err := s.objectStorage.Stat(ctx, storageKey)
+	if err != nil {
+		if errors.Is(err, model.ErrorNotFound) {
+			return nil, fmt.Errorf("%w: photo has not been uploaded", model.ErrConflict)
+		}
+		return nil, err
+	}
+
+	if info.Size > s.config.MaxPhotoSize {
+		return nil, s.rejectUpload(ctx, photo, fmt.Sprintf("photo is larger than %d bytes", s.config.MaxPhotoSize))
+	}
+
+	mime, err := s.sniffContentType(ctx, storageKey)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := allowedPhotoTypes[mime]; !ok {
+		return nil, s.rejectUpload(ctx, photo, fmt.Sprintf("content type %q is not allowed", mime))
+	}
+
+	photo, err = s.repository.UpdatePhotoStatus(ctx, storageKey, model.PhotoStatusUploaded, mime, info.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	s.imageQueue.Enqueue(storageKey)
+
+	return s.withURL(photo), nil
+}
+
+func (s *PokerService) sniffContentType(ctx context.Context, storageKey string) (string, error) {
+
+	body, err := s.objectStorage.Get(ctx, storageKey)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(body, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	return http.DetectContentType(head[:n]), nil
+}
+
+func (s *PokerService) rejectUpload(ctx context.Context, photo *model.Photo, reason string) error {
+
+	if err := s.objectStorage.Delete(ctx, photo.StorageKey); err != nil {
+		log.Printf("upload: delete rejected object %s: %v", photo.StorageKey, err)
+	}
+
+	if _, err := s.repository.UpdatePhotoStatus(ctx, photo.StorageKey, model.PhotoStatusRejected, photo.Mime, photo.Size); err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%w: %s", model.ErrInvalidParameter, reason)
+}
+
+// checkPhotos validates that the keys refer to the user's verified, unattached photos
+// and that together they fit the per-report limits.
+func (s *PokerService) checkPhotos(ctx context.Context, userID model.UserID, storageKeys []string) error {
+
+	if len(storageKeys) == 0 {
+		return nil
+	}
+	if len(storageKeys) > s.config.MaxReportPhotos {
+		return fmt.Errorf("%w: at most %d photos per report", model.ErrInvalidParameter, s.config.MaxReportPhotos)
+	}
+
+	seen := make(map[string]bool, len(storageKeys))
+	for _, key := range storageKeys {
+		if seen[key] {
+			return fmt.Errorf("%w: duplicate photo %s", model.ErrInvalidParameter, key)
+		}
+		seen[key] = true
+	}
+
+	photos, err := s.repository.GetPhotosByKeys(ctx, storageKeys)
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	found := make(map[string]bool, len(photos))
+	for _, photo := range photos {
+		if photo.UserID != userID {
+			continue
+		}
+		if photo.Status != model.PhotoStatusUploaded {
+			return fmt.Errorf("%w: photo %s has not been uploaded", model.ErrInvalidParameter, photo.StorageKey)
+		}
+		if photo.ViolationID != "" {
+			return fmt.Errorf("%w: photo %s is already attached", model.ErrInvalidParameter, photo.StorageKey)
+		}
+		found[photo.StorageKey] = true
+		total += photo.Size
+	}
+
+	for _, key := range storageKeys {
+		if !found[key] {
+			return fmt.Errorf("%w: photo %s", model.ErrorNotFound, key)
+		}
+	}
+
+	if total > s.config.MaxReportSize {
+		return fmt.Errorf("%w: photos exceed %d bytes per report", model.ErrInvalidParameter, s.config.MaxReportSize)
+	}
+
+	return nil
+}
+
+func (s *PokerService) listPhotos(ctx context.Context, violationID model.ViolationID) ([]*model.Photo, error) {
+
+	photos, err := s.repository.ListViolationPhotos(ctx, violationID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, photo := range photos {
+		s.withURL(photo)
+	}
+	return photos, nil
+}
+
+// withURL exposes the photo URLs. The photo URL points at the stripped original
+// and is only set once processing has stored it, so reporters' EXIF data is never
+// served.
+func (s *PokerService) withURL(photo *model.Photo) *model.Photo {
+
+	if photo.ProcessingStatus != model.PhotoProcessingDone {
+		return photo
+	}
+
+	if original, ok := photo.Variants[imageproc.VariantOriginal]; ok {
+		photo.URL = s.objectStorage.URL(original.StorageKey)
+	}
+	for _, variant := range photo.Variants {
+		variant.URL = s.objectStorage.URL(variant.StorageKey)
+	}
+	return photo
+}
@@ -20,6 +20,11 @@ func (s *PokerService) GetUser(ctx context.Context, userID model.UserID) (*model
 		return nil, err
 	}
 
+	user.Role, err = s.repository.GetUserRole(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
 	if user.EvaluationStrategy == "" {
 		user.EvaluationStrategy = "average"
 	}
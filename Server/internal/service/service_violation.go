@@ -2,25 +2,344 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/google/uuid"
 	"github.com/inzarubin80/Server/internal/model"
+	"github.com/inzarubin80/Server/internal/storage"
 )
 
-func (s *PokerService) CreateViolation(ctx context.Context, userID model.UserID, vType model.ViolationType, description string, lat, lng float64) (*model.Violation, error) {
-	if lat < -90 || lat > 90 {
+const (
+	defaultViolationsPageSize = 50
+	maxViolationsPageSize     = 200
+
+	maxIdempotencyKeyLength = 255
+)
+
+// idempotencyNamespace scopes the report ids derived from idempotency keys.
+var idempotencyNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("warden/violations"))
+
+// CreateViolation submits a new report. If open reports of the same type are
+// nearby, the configured dedup policy either returns them as a
+// *model.DuplicatesError or merges the draft into the nearest one.
+//
+// A draft with a client-side id or an idempotency key is created once: a retry
+// returns the stored report, or the report the draft was merged into, flagged
+// as Replayed.
+func (s *PokerService) CreateViolation(ctx context.Context, userID model.UserID, draft *model.ViolationDraft) (*model.ViolationSubmission, error) {
+	if draft.Lat < -90 || draft.Lat > 90 {
 		return nil, fmt.Errorf("invalid lat")
 	}
-	if lng < -180 || lng > 180 {
+	if draft.Lng < -180 || draft.Lng > 180 {
 		return nil, fmt.Errorf("invalid lng")
 	}
-	switch vType {
-	case "garbage", "pollution", "air", "deforestation", "other":
-	default:
+	if !draft.Type.Valid() {
 		return nil, fmt.Errorf("invalid type")
 	}
 
-	return s.repository.CreateViolation(ctx, userID, vType, description, lat, lng)
+	id, clientID, err := violationIDFor(userID, draft)
+	if err != nil {
+		return nil, err
+	}
+	if clientID {
+		submission, err := s.submittedViolation(ctx, userID, id)
+		if !errors.Is(err, model.ErrorNotFound) {
+			return submission, err
+		}
+	}
+
+	photoKeys := draft.PhotoKeys
+	if err := s.checkPhotos(ctx, userID, photoKeys); err != nil {
+		return nil, err
+	}
+
+	duplicates, err := s.findDuplicates(ctx, userID, draft)
+	if err != nil {
+		return nil, err
+	}
+	if len(duplicates) > 0 {
+		if s.config.Dedup.Policy != model.DedupMerge {
+			return nil, &model.DuplicatesError{Candidates: duplicates}
+		}
+
+		mergeID := model.ViolationID("")
+		if clientID {
+			mergeID = id
+		}
+		v, err := s.mergeIntoDuplicate(ctx, mergeID, userID, duplicates[0].Violation, draft)
+		if err != nil {
+			// A concurrent retry of the same draft may have merged it first.
+			if clientID && errors.Is(err, model.ErrConflict) {
+				if submission, replayErr := s.submittedViolation(ctx, userID, id); replayErr == nil {
+					return submission, nil
+				}
+			}
+			return nil, err
+		}
+		return &model.ViolationSubmission{Violation: v, Merged: true}, nil
+	}
+
+	var (
+		v       *model.Violation
+		created *model.ViolationCreated
+		ruled   []model.Event
+	)
+	err = s.repository.Transact(ctx, func(tx storage.Adapters) error {
+
+		var err error
+		v, err = tx.Repository.CreateViolation(ctx, id, userID, draft.Type, draft.Description, draft.Lat, draft.Lng, s.config.Moderation.InitialStatus)
+		if err != nil {
+			return err
+		}
+
+		if len(photoKeys) > 0 {
+			if err := tx.Repository.AttachPhotos(ctx, v.ID, userID, model.PhotoKindReport, photoKeys); err != nil {
+				return err
+			}
+		}
+
+		if err := s.audit(ctx, tx.Repository, userID, model.AuditViolationCreate, model.AuditEntityViolation, string(v.ID), nil, v); err != nil {
+			return err
+		}
+
+		if s.moderator != nil && v.ModerationStatus == model.ModerationPending {
+			if err := s.enqueue(ctx, tx.Repository, model.OutboxTopicModerationSubmit, &model.ModerationSubmission{ViolationID: v.ID, PhotoKeys: photoKeys}); err != nil {
+				return err
+			}
+		}
+
+		created = &model.ViolationCreated{EventMeta: model.NewEventMeta(userID), Violation: v, PhotoKeys: photoKeys}
+
+		ruleEvent, err := s.ruleEvent(ctx, tx.Repository, model.RuleEventCreate, v, userID, len(photoKeys))
+		if err != nil {
+			return err
+		}
+		v, ruled, err = s.applyRules(ctx, tx.Repository, ruleEvent)
+		return err
+	})
+	if err != nil {
+		// A concurrent retry of the same draft may have created the report first.
+		if clientID && errors.Is(err, model.ErrConflict) {
+			if submission, replayErr := s.submittedViolation(ctx, userID, id); replayErr == nil {
+				return submission, nil
+			}
+		}
+		return nil, err
+	}
+
+	s.events.Publish(ctx, created)
+	for _, e := range ruled {
+		s.events.Publish(ctx, e)
+	}
+
+	return &model.ViolationSubmission{Violation: v}, nil
+}
+
+// violationIDFor returns the id of the report to create and whether the client
+// chose it. A client UUID wins over an idempotency key, which is turned into a
+// UUID scoped to the user so that keys of different users never collide.
+func violationIDFor(userID model.UserID, draft *model.ViolationDraft) (model.ViolationID, bool, error) {
+
+	switch {
+	case draft.ID != "":
+		id, err := uuid.Parse(string(draft.ID))
+		if err != nil || id == uuid.Nil {
+			return "", false, fmt.Errorf("%w: violation id", model.ErrInvalidParameter)
+		}
+		return model.ViolationID(id.String()), true, nil
+
+	case draft.IdempotencyKey != "":
+		if len(draft.IdempotencyKey) > maxIdempotencyKeyLength {
+			return "", false, fmt.Errorf("%w: idempotency key is longer than %d bytes", model.ErrInvalidParameter, maxIdempotencyKeyLength)
+		}
+		id := uuid.NewSHA1(idempotencyNamespace, []byte(fmt.Sprintf("%d:%s", userID, draft.IdempotencyKey)))
+		return model.ViolationID(id.String()), true, nil
+	}
+
+	return model.ViolationID(uuid.New().String()), false, nil
+}
+
+// submittedViolation returns the report an earlier submission of the draft has
+// created or been merged into, or model.ErrorNotFound if there is none.
+func (s *PokerService) submittedViolation(ctx context.Context, userID model.UserID, id model.ViolationID) (*model.ViolationSubmission, error) {
+
+	v, err := s.repository.GetViolation(ctx, id)
+	if errors.Is(err, model.ErrorNotFound) {
+		return s.mergedViolation(ctx, userID, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if v.UserID != userID {
+		return nil, fmt.Errorf("%w: violation id %s is taken", model.ErrConflict, id)
+	}
+
+	return &model.ViolationSubmission{Violation: v, Replayed: true}, nil
+}
+
+// mergedViolation returns the report the user's draft with the given id was
+// merged into. It returns model.ErrorNotFound if the draft was not merged.
+func (s *PokerService) mergedViolation(ctx context.Context, userID model.UserID, id model.ViolationID) (*model.ViolationSubmission, error) {
+
+	mergedBy, targetID, err := s.repository.GetViolationMerge(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if mergedBy != userID {
+		return nil, fmt.Errorf("%w: violation id %s is taken", model.ErrConflict, id)
+	}
+
+	v, err := s.repository.GetViolation(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ViolationSubmission{Violation: v, Merged: true, Replayed: true}, nil
+}
+
+func (s *PokerService) ListViolations(ctx context.Context, filter *model.ViolationFilter) (*model.ViolationsPage, error) {
+
+	if err := validateViolationFilter(filter); err != nil {
+		return nil, err
+	}
+
+	pageSize := filter.Limit
+	if pageSize <= 0 {
+		pageSize = defaultViolationsPageSize
+	}
+	if pageSize > maxViolationsPageSize {
+		pageSize = maxViolationsPageSize
+	}
+
+	// Fetch one extra row to know whether there is a next page.
+	query := *filter
+	query.Limit = pageSize + 1
+	query.ModerationStatus = model.ModerationApproved
+
+	items, err := s.repository.ListViolations(ctx, &query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &model.ViolationsPage{Items: items}
+	if len(items) > pageSize {
+		page.Items = items[:pageSize]
+		last := page.Items[pageSize-1]
+		page.NextCursor = model.ViolationCursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+
+	return page, nil
 }
 
+// validateViolationFilter checks the filters shared by the list and map endpoints.
+func validateViolationFilter(filter *model.ViolationFilter) error {
+
+	if filter.BBox != nil {
+		b := filter.BBox
+		if b.MinLat < -90 || b.MaxLat > 90 || b.MinLng < -180 || b.MaxLng > 180 || b.MinLat > b.MaxLat || b.MinLng > b.MaxLng {
+			return fmt.Errorf("%w: bbox", model.ErrInvalidParameter)
+		}
+	}
+
+	for _, t := range filter.Types {
+		if !t.Valid() {
+			return fmt.Errorf("%w: type %q", model.ErrInvalidParameter, t)
+		}
+	}
+
+	for _, st := range filter.Statuses {
+		if !st.Valid() {
+			return fmt.Errorf("%w: status %q", model.ErrInvalidParameter, st)
+		}
+	}
+
+	if filter.CreatedFrom != nil && filter.CreatedTo != nil && !filter.CreatedFrom.Before(*filter.CreatedTo) {
+		return fmt.Errorf("%w: from must be before to", model.ErrInvalidParameter)
+	}
+
+	return nil
+}
+
+// GetViolation returns the report with its photos, votes and history. Reports that
+// are not approved are reported as not found to anyone but the author and moderators.
+func (s *PokerService) GetViolation(ctx context.Context, violationID model.ViolationID, viewer model.Viewer) (*model.ViolationDetails, error) {
+
+	v, err := s.getViolation(ctx, violationID)
+	if err != nil {
+		return nil, err
+	}
+	if !viewer.CanSee(v) {
+		return nil, fmt.Errorf("%w: violation %s", model.ErrorNotFound, violationID)
+	}
+
+	details := &model.ViolationDetails{
+		Violation: v,
+	}
+	if (viewer.UserID != 0 && viewer.UserID == v.UserID) || viewer.Role.AtLeast(model.RoleModerator) {
+		details.ModerationReason = v.ModerationReason
+	}
 
+	details.Photos, err = s.listPhotos(ctx, violationID)
+	if err != nil {
+		return nil, err
+	}
+
+	details.Confirmations, err = s.repository.ListConfirmations(ctx, violationID)
+	if err != nil {
+		return nil, err
+	}
+
+	details.StatusHistory, err = s.repository.ListStatusHistory(ctx, violationID)
+	if err != nil {
+		return nil, err
+	}
+
+	photoIDs := make(map[string]string, len(details.Photos))
+	for _, photo := range details.Photos {
+		photoIDs[photo.StorageKey] = photo.ID
+	}
+	for _, change := range details.StatusHistory {
+		for _, key := range change.Evidence {
+			if id, ok := photoIDs[key]; ok {
+				change.EvidencePhotoIDs = append(change.EvidencePhotoIDs, id)
+			}
+		}
+	}
+
+	users, err := s.repository.GetUsersByIDs(ctx, []model.UserID{v.UserID})
+	if err != nil {
+		return nil, err
+	}
+	if len(users) > 0 {
+		details.Author = &model.UserBrief{ID: users[0].ID, Name: users[0].Name}
+	}
+
+	return details, nil
+}
+
+// getViolation validates the identifier before hitting the database so that
+// malformed ids are reported as bad parameters rather than driver errors.
+func (s *PokerService) getViolation(ctx context.Context, violationID model.ViolationID) (*model.Violation, error) {
+
+	if _, err := uuid.Parse(string(violationID)); err != nil {
+		return nil, fmt.Errorf("%w: violation id", model.ErrInvalidParameter)
+	}
+
+	return s.repository.GetViolation(ctx, violationID)
+}
+
+// getPublicViolation is getViolation for actions that are only possible on
+// published reports, such as voting.
+func (s *PokerService) getPublicViolation(ctx context.Context, violationID model.ViolationID) (*model.Violation, error) {
+
+	v, err := s.getViolation(ctx, violationID)
+	if err != nil {
+		return nil, err
+	}
+	if v.ModerationStatus != model.ModerationApproved {
+		return nil, fmt.Errorf("%w: violation %s", model.ErrorNotFound, violationID)
+	}
+
+	return v, nil
+}
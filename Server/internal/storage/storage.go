@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"time"
 
 	"github.com/inzarubin80/Server/internal/model"
 	"github.com/jackc/pgx/v5"
@@ -17,24 +18,86 @@ type DB interface {
 
 type Repository interface {
 
-
 	//User
 	GetUserAuthProvidersByProviderUid(ctx context.Context, ProviderUid string, Provider string) (*model.UserAuthProviders, error)
 	AddUserAuthProviders(ctx context.Context, userProfileFromProvide *model.UserProfileFromProvider, userID model.UserID) (*model.UserAuthProviders, error)
 	CreateUser(ctx context.Context, userData *model.UserProfileFromProvider) (*model.User, error)
 	GetUsersByIDs(ctx context.Context, userIDs []model.UserID) ([]*model.User, error)
-	GetUserIDsByPokerID(ctx context.Context, pokerID model.PokerID) ([]model.UserID, error)
-	AddPokerUser(ctx context.Context, pokerID model.PokerID, userID model.UserID) error
 	SetUserName(ctx context.Context, userID model.UserID, name string) error
+	CreateViolation(ctx context.Context, id model.ViolationID, userID model.UserID, vType model.ViolationType, description string, lat, lng float64, moderationStatus model.ModerationStatus) (*model.Violation, error)
+	ListViolations(ctx context.Context, filter *model.ViolationFilter) ([]*model.Violation, error)
+	FindNearbyViolations(ctx context.Context, filter *model.NearbyFilter) ([]*model.DuplicateCandidate, error)
+	ExportViolations(ctx context.Context, filter *model.ViolationFilter, fn func(*model.Violation) error) error
+	StatViolations(ctx context.Context, filter *model.ViolationFilter) (*model.ViolationStats, error)
+	ClusterViolations(ctx context.Context, filter *model.ViolationFilter, cellSize float64) ([]*model.ClusterCell, error)
+	ListPendingModeration(ctx context.Context, limit int) ([]*model.Violation, error)
+	ModerateViolation(ctx context.Context, decision *model.ModerationDecision) (*model.Violation, error)
+	ApplyModerationScore(ctx context.Context, decision *model.ModerationDecision, score float64) (*model.Violation, error)
+	AddAuditLog(ctx context.Context, entry *model.AuditEntry) error
+	ListAuditLogs(ctx context.Context, filter *model.AuditFilter) ([]*model.AuditEntry, error)
+	GetViolation(ctx context.Context, violationID model.ViolationID) (*model.Violation, error)
+	AddViolationMerge(ctx context.Context, id model.ViolationID, userID model.UserID, targetID model.ViolationID) error
+	GetViolationMerge(ctx context.Context, id model.ViolationID) (model.UserID, model.ViolationID, error)
+	AddConfirmation(ctx context.Context, violationID model.ViolationID, userID model.UserID) (*model.Violation, error)
+	RemoveConfirmation(ctx context.Context, violationID model.ViolationID, userID model.UserID) (*model.Violation, error)
+	ListConfirmations(ctx context.Context, violationID model.ViolationID) ([]*model.Confirmation, error)
+	ChangeViolationStatus(ctx context.Context, change *model.StatusChange) (*model.Violation, error)
+	ProposeResolve(ctx context.Context, change *model.StatusChange) (*model.Violation, error)
+	ListStatusHistory(ctx context.Context, violationID model.ViolationID) ([]*model.StatusChange, error)
+	ListActiveRules(ctx context.Context) ([]*model.ViolationRule, error)
+	CreatePhoto(ctx context.Context, photo *model.Photo) (*model.Photo, error)
+	GetPhotoByKey(ctx context.Context, storageKey string) (*model.Photo, error)
+	GetPhotosByKeys(ctx context.Context, storageKeys []string) ([]*model.Photo, error)
+	UpdatePhotoStatus(ctx context.Context, storageKey string, status string, mime string, size int64) (*model.Photo, error)
+	AttachPhotos(ctx context.Context, violationID model.ViolationID, userID model.UserID, kind string, storageKeys []string) error
+	ListViolationPhotos(ctx context.Context, violationID model.ViolationID) ([]*model.Photo, error)
+	SetPhotoProcessed(ctx context.Context, storageKey string, mime string, size int64, variants map[string]*model.PhotoVariant) error
+	SetPhotoProcessingFailed(ctx context.Context, storageKey string, reason string) error
+	ListPhotosPendingProcessing(ctx context.Context, limit int) ([]*model.Photo, error)
 	GetUser(ctx context.Context, userID model.UserID) (*model.User, error)
-	SetUserSettings(ctx context.Context, userID model.UserID, userSettings *model.UserSettings) error
-
+	CreateSession(ctx context.Context, session *model.Session) (*model.Session, error)
+	RotateSession(ctx context.Context, tokenHash string, next *model.Session) (*model.Session, error)
+	GetSessionByTokenHash(ctx context.Context, tokenHash string) (*model.Session, error)
+	RevokeSessionFamily(ctx context.Context, familyID string) error
+	RevokeUserSessionFamily(ctx context.Context, userID model.UserID, familyID string) error
+	RevokeUserSessions(ctx context.Context, userID model.UserID) error
+	GetActiveSessionRole(ctx context.Context, familyID string) (model.Role, error)
+	GetUserRole(ctx context.Context, userID model.UserID) (model.Role, error)
+	SetUserRole(ctx context.Context, userID model.UserID, role model.Role) error
+	UpsertDevice(ctx context.Context, userID model.UserID, platform string, token string) (*model.Device, error)
+	DeleteUserDevice(ctx context.Context, userID model.UserID, token string) error
+	DeleteDeviceByToken(ctx context.Context, token string) error
+	ListUsersDevices(ctx context.Context, userIDs []model.UserID) ([]*model.Device, error)
+	EnqueueOutbox(ctx context.Context, messages []*model.OutboxMessage) error
+	ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]*model.OutboxMessage, error)
+	CompleteOutbox(ctx context.Context, id int64) error
+	RetryOutbox(ctx context.Context, id int64, at time.Time, reason string) error
+	DeadLetterOutbox(ctx context.Context, id int64, reason string) error
+	ListOutbox(ctx context.Context, filter *model.OutboxFilter) ([]*model.OutboxMessage, error)
+	ReplayOutbox(ctx context.Context, id int64) (*model.OutboxMessage, error)
+	CreateComment(ctx context.Context, comment *model.Comment) (*model.Comment, error)
+	GetComment(ctx context.Context, id model.CommentID) (*model.Comment, error)
+	ListComments(ctx context.Context, filter *model.CommentFilter) ([]*model.Comment, error)
+	UpdateCommentText(ctx context.Context, id model.CommentID, text string, status model.ModerationStatus) (*model.Comment, error)
+	ApplyCommentModerationScore(ctx context.Context, id model.CommentID, status model.ModerationStatus, score float64, reason string) (*model.Comment, error)
+	SetCommentVisibility(ctx context.Context, id model.CommentID, hidden bool, reason string, moderatorID model.UserID) (*model.Comment, error)
+	MarkCommentPublished(ctx context.Context, id model.CommentID) (bool, error)
+	DeleteComment(ctx context.Context, id model.CommentID) error
+	AddNotifications(ctx context.Context, notifications []*model.Notification) ([]*model.Notification, error)
+	ListNotifications(ctx context.Context, filter *model.NotificationFilter) ([]*model.Notification, error)
+	CountUnreadNotifications(ctx context.Context, userID model.UserID) (int, error)
+	MarkNotificationRead(ctx context.Context, userID model.UserID, id model.NotificationID) error
+	MarkAllNotificationsRead(ctx context.Context, userID model.UserID) (int, error)
+	ListUserSessions(ctx context.Context, userID model.UserID) ([]*model.DeviceSession, error)
 }
 
+// Adapters are the repositories bound to one transaction.
 type Adapters struct {
 	Repository Repository
 }
 
+// TransactionProvider runs txFunc in a transaction that is committed when txFunc
+// returns nil and rolled back otherwise. txFunc may be called more than once.
 type TransactionProvider interface {
 	Transact(ctx context.Context, txFunc func(adapters Adapters) error) error
 }
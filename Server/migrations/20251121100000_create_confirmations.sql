@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS confirmations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    violation_id UUID NOT NULL REFERENCES violations (id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    -- один голос на пользователя
+    CONSTRAINT confirmations_violation_user_key UNIQUE (violation_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_confirmations_user_id ON confirmations (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS confirmations;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE violations
+    ADD COLUMN IF NOT EXISTS resolution_status TEXT NOT NULL DEFAULT 'none' CHECK (resolution_status IN ('none','proposed'));
+
+CREATE TABLE IF NOT EXISTS violation_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    violation_id UUID NOT NULL REFERENCES violations (id) ON DELETE CASCADE,
+    event TEXT NOT NULL CHECK (event IN ('status_change','propose_resolve')),
+    from_status TEXT NOT NULL,
+    to_status TEXT NOT NULL,
+    -- NULL, если переход выполнен системой (правилом)
+    actor_id BIGINT,
+    reason TEXT NOT NULL DEFAULT '',
+    evidence TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_violation_status_history_violation_id ON violation_status_history (violation_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS violation_status_history;
+ALTER TABLE violations DROP COLUMN IF EXISTS resolution_status;
+-- +goose StatementEnd
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS violation_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL UNIQUE,
+    trigger_event TEXT NOT NULL CHECK (trigger_event IN ('create','confirmation','propose_resolve')),
+    condition JSONB NULL,
+    threshold INT NULL,
+    weight_map JSONB NULL,
+    action TEXT NOT NULL,
+    action_params JSONB NULL,
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_violation_rules_trigger_event ON violation_rules (trigger_event) WHERE active;
+
+-- Правила MVP (см. Documents/ЭкоМонитор_ТЗ.md)
+INSERT INTO violation_rules (name, trigger_event, threshold, action, action_params)
+VALUES ('confirmation_threshold_default', 'confirmation', 3, 'set_status', '{"status":"confirmed"}');
+
+INSERT INTO violation_rules (name, trigger_event, condition, threshold, action, action_params)
+VALUES ('moderator_override', 'confirmation', '{"actor_role":"moderator"}', 1, 'set_status', '{"status":"confirmed"}');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS violation_rules;
+-- +goose StatementEnd
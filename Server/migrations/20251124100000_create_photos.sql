@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS photos (
+    id UUID PRIMARY KEY,
+    -- NULL, пока фото загружено, но ещё не привязано к нарушению
+    violation_id UUID NULL REFERENCES violations (id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    storage_key TEXT NOT NULL UNIQUE,
+    kind TEXT NOT NULL DEFAULT 'report' CHECK (kind IN ('report','evidence')),
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending','uploaded','rejected')),
+    mime TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_photos_violation_id ON photos (violation_id);
+CREATE INDEX IF NOT EXISTS idx_photos_user_id_status ON photos (user_id, status);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS photos;
+-- +goose StatementEnd
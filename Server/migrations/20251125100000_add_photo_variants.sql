@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE photos
+    ADD COLUMN IF NOT EXISTS processing_status TEXT NOT NULL DEFAULT 'pending' CHECK (processing_status IN ('pending','done','failed')),
+    ADD COLUMN IF NOT EXISTS processing_error TEXT,
+    ADD COLUMN IF NOT EXISTS variants JSONB NOT NULL DEFAULT '{}';
+CREATE INDEX IF NOT EXISTS idx_photos_processing_pending ON photos (created_at) WHERE status = 'uploaded' AND processing_status = 'pending';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_photos_processing_pending;
+ALTER TABLE photos
+    DROP COLUMN IF EXISTS processing_status,
+    DROP COLUMN IF EXISTS processing_error,
+    DROP COLUMN IF EXISTS variants;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Первого администратора назначают вручную:
+-- UPDATE users SET role = 'admin' WHERE user_id = ...;
+-- Партнёры (муниципальные и природоохранные службы) выгружают отчёты через экспорт.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'user'
+        CONSTRAINT users_role_check CHECK (role IN ('user', 'partner', 'moderator', 'admin'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN IF EXISTS role;
+-- +goose StatementEnd
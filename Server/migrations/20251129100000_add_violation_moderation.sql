@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Уже опубликованные нарушения считаются одобренными.
+ALTER TABLE violations
+    ADD COLUMN IF NOT EXISTS moderation_status TEXT NOT NULL DEFAULT 'approved'
+        CONSTRAINT violations_moderation_status_check CHECK (moderation_status IN ('pending', 'approved', 'rejected')),
+    ADD COLUMN IF NOT EXISTS moderation_score DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS moderation_reason TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS moderated_by BIGINT,
+    ADD COLUMN IF NOT EXISTS moderated_at TIMESTAMPTZ;
+-- очередь модерации: сначала самые подозрительные, затем самые старые
+CREATE INDEX IF NOT EXISTS idx_violations_pending_moderation
+    ON violations (moderation_score DESC NULLS LAST, created_at)
+    WHERE moderation_status = 'pending';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_violations_pending_moderation;
+ALTER TABLE violations
+    DROP COLUMN IF EXISTS moderated_at,
+    DROP COLUMN IF EXISTS moderated_by,
+    DROP COLUMN IF EXISTS moderation_reason,
+    DROP COLUMN IF EXISTS moderation_score,
+    DROP COLUMN IF EXISTS moderation_status;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Устройства для push-уведомлений. Токен уникален: при повторной регистрации
+-- на другом аккаунте устройство переходит к новому пользователю.
+CREATE TABLE IF NOT EXISTS devices (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    platform TEXT NOT NULL CHECK (platform IN ('android', 'ios')),
+    token TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT devices_token_key UNIQUE (token)
+);
+CREATE INDEX IF NOT EXISTS idx_devices_user_id ON devices (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS devices;
+-- +goose StatementEnd
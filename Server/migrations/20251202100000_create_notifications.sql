@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Входящие уведомления пользователя. payload содержит данные события
+-- (id нарушения, статусы, id комментария); read_at заполняется при прочтении.
+-- event_id — id события, о котором уведомление: повторная доставка события
+-- не добавляет пользователю второе уведомление.
+CREATE TABLE IF NOT EXISTS notifications (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    type TEXT NOT NULL,
+    violation_id UUID REFERENCES violations (id) ON DELETE CASCADE,
+    payload JSONB NOT NULL DEFAULT '{}'::jsonb,
+    event_id UUID,
+    read_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications (user_id, id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notifications_event ON notifications (user_id, event_id) WHERE event_id IS NOT NULL;
+-- счётчик непрочитанных
+CREATE INDEX IF NOT EXISTS idx_notifications_unread ON notifications (user_id) WHERE read_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS notifications;
+-- +goose StatementEnd
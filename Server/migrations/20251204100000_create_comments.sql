@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Обсуждение нарушений. Комментарий публичен, когда он одобрен модерацией
+-- (moderation_status = 'approved') и не скрыт модератором (hidden).
+-- published_at — когда комментарий впервые стал публичным: о нём сообщают
+-- подписчикам один раз, повторное одобрение после правки уже не уведомление.
+CREATE TABLE IF NOT EXISTS comments (
+    id BIGSERIAL PRIMARY KEY,
+    violation_id UUID NOT NULL REFERENCES violations (id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    text TEXT NOT NULL,
+    moderation_status TEXT NOT NULL DEFAULT 'approved' CHECK (moderation_status IN ('pending', 'approved', 'rejected')),
+    moderation_score DOUBLE PRECISION,
+    moderation_reason TEXT NOT NULL DEFAULT '',
+    hidden BOOLEAN NOT NULL DEFAULT FALSE,
+    hidden_reason TEXT NOT NULL DEFAULT '',
+    hidden_by BIGINT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    edited_at TIMESTAMPTZ,
+    published_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_comments_violation_id ON comments (violation_id, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS comments;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- id отчётов, объединённых с уже существующим нарушением (target_id). Повтор
+-- отправки с тем же id возвращает результат объединения, а не объединяет снова.
+CREATE TABLE IF NOT EXISTS violation_merges (
+    id UUID PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    target_id UUID NOT NULL REFERENCES violations (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS violation_merges;
+-- +goose StatementEnd